@@ -6,11 +6,11 @@ import (
 	"github.com/coffee-realist/balancer/internal/balancer/least_conn"
 	"github.com/coffee-realist/balancer/internal/balancer/p2c"
 	"github.com/coffee-realist/balancer/internal/balancer/round_robin"
-	"github.com/stretchr/testify/require"
 	"io"
 	"net"
 	"net/http"
 	"net/http/httptest"
+	"sync"
 	"testing"
 	"time"
 
@@ -45,6 +45,8 @@ func startServer(handler http.Handler) (addr string, stop func(), err error) {
 }
 
 // TestIntegration_AdaptiveModes проверяет поведение адаптивного балансировщика при различных режимах нагрузки.
+// Режим определяется числом незавершённых запросов, поэтому бэкенды держат ответы,
+// пока не будут отправлены все запросы.
 func TestIntegration_AdaptiveModes(t *testing.T) {
 	const total = 15 // общее количество запросов
 	const (
@@ -52,23 +54,27 @@ func TestIntegration_AdaptiveModes(t *testing.T) {
 		highThresh = 10 // порог переключения с P2C на LeastConn
 	)
 
+	arrived := make(chan string)   // имя бэкенда, принявшего очередной запрос
+	release := make(chan struct{}) // закрывается, когда бэкенды могут ответить
+	newBackend := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			arrived <- name
+			<-release
+			_, err := io.WriteString(w, name)
+			assert.NoError(t, err)
+		}))
+	}
+
 	// создаём два тестовых бэкенда, возвращающих "B1" и "B2" соответственно
-	backend1 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		_, err := io.WriteString(w, "B1")
-		require.NoError(t, err)
-	}))
+	backend1 := newBackend("B1")
 	defer backend1.Close()
-
-	backend2 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		_, err := io.WriteString(w, "B2")
-		require.NoError(t, err)
-	}))
+	backend2 := newBackend("B2")
 	defer backend2.Close()
 
 	// создаём адаптивный балансировщик с тремя режимами: RR, P2C, LeastConn
 	rr := round_robin.NewRoundRobinBalancer([]string{backend1.URL, backend2.URL})
 	lc := least_conn.NewLeastConnBalancer([]string{backend1.URL, backend2.URL})
-	p2cb := p2c.NewP2CBalancer([]string{backend1.URL, backend2.URL}, 10*time.Second)
+	p2cb := p2c.NewP2CBalancer([]string{backend1.URL, backend2.URL})
 	ab := adapter.NewAdaptiveBalancer(rr, lc, p2cb, lowThresh, highThresh)
 	defer ab.Stop() // корректное завершение фоновых процессов
 
@@ -78,18 +84,35 @@ func TestIntegration_AdaptiveModes(t *testing.T) {
 	assert.NoError(t, err)
 	defer stop()
 
-	client := &http.Client{Timeout: time.Second}
+	client := &http.Client{Timeout: 5 * time.Second}
 
-	// отправляем 15 последовательных запросов
+	// отправляем 15 запросов, дожидаясь, пока каждый дойдёт до бэкенда
+	var wg sync.WaitGroup
 	results := make([]string, total)
 	for i := 0; i < total; i++ {
-		resp, err := client.Get("http://" + addr + "/foo")
-		assert.NoError(t, err)
-		body, _ := io.ReadAll(resp.Body)
-		err = resp.Body.Close()
-		require.NoError(t, err)
-		results[i] = string(body)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := client.Get("http://" + addr + "/foo")
+			if !assert.NoError(t, err) {
+				return
+			}
+			_, _ = io.Copy(io.Discard, resp.Body)
+			assert.NoError(t, resp.Body.Close())
+		}()
+		select {
+		case results[i] = <-arrived:
+		case <-time.After(2 * time.Second):
+			close(release)
+			t.Fatalf("request %d did not reach a backend", i)
+		}
 	}
+	assert.Equal(t, int64(total), ab.Active(), "all requests are in flight")
+
+	// отпускаем бэкенды: после завершения запросов нагрузка возвращается к нулю
+	close(release)
+	wg.Wait()
+	assert.Eventually(t, func() bool { return ab.Active() == 0 }, time.Second, 10*time.Millisecond)
 
 	// --- 1) Проверка режима RoundRobin: первые lowThresh запросов
 	for i := 0; i < lowThresh; i++ {
@@ -114,9 +137,17 @@ func TestIntegration_AdaptiveModes(t *testing.T) {
 	assert.True(t, seen2, "P2C: B2 должен получить хотя бы один запрос")
 
 	// --- 3) Проверка режима LeastConn: последние запросы
-	// так как соединения краткоживущие, LC всегда будет выбирать первый
-	for i := highThresh; i < total; i++ {
-		assert.Equal(t, "B1", results[i], "LC: всегда выбираем первый сервер")
+	// каждый запрос уходит на бэкенд с наименьшим числом незавершённых запросов
+	inFlight := map[string]int{}
+	for i := 0; i < total; i++ {
+		if i >= highThresh {
+			other := "B1"
+			if results[i] == "B1" {
+				other = "B2"
+			}
+			assert.LessOrEqual(t, inFlight[results[i]], inFlight[other], "LC: запрос %d ушёл на более загруженный бэкенд", i)
+		}
+		inFlight[results[i]]++
 	}
 }
 
@@ -0,0 +1,151 @@
+package accesslog
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/coffee-realist/balancer/internal/forwarded"
+	"github.com/coffee-realist/balancer/internal/logger"
+)
+
+// Entry — запись журнала доступа об одном запросе клиента.
+type Entry struct {
+	Time      time.Time     `json:"time"`                 // Момент получения запроса
+	RemoteIP  string        `json:"remote_ip"`            // IP-адрес клиента
+	Method    string        `json:"method"`               // HTTP-метод
+	URI       string        `json:"uri"`                  // Путь с query-строкой
+	Proto     string        `json:"proto"`                // Версия протокола
+	Host      string        `json:"host"`                 // Заголовок Host
+	Status    int           `json:"status"`               // Код ответа клиенту
+	Bytes     int64         `json:"bytes"`                // Размер тела ответа
+	Duration  time.Duration `json:"duration"`             // Время обработки запроса
+	Referer   string        `json:"referer,omitempty"`    // Заголовок Referer
+	UserAgent string        `json:"user_agent"`           // Заголовок User-Agent
+	ClientKey string        `json:"client_key,omitempty"` // Отпечаток заголовка X-API-Key (logger.Fingerprint)
+	Backend   string        `json:"backend,omitempty"`    // Бэкенд, ответивший на запрос
+	RequestID string        `json:"request_id,omitempty"` // Заголовок X-Request-ID
+}
+
+// Logger пишет записи о запросах в заданном формате через асинхронный Writer.
+type Logger struct {
+	format Formatter
+	out    *Writer
+	pool   sync.Pool // Буферы для форматирования записей
+}
+
+// New создаёт Logger. Записи форматируются в горутине запроса, запись в out асинхронная.
+func New(format Formatter, out *Writer) *Logger {
+	return &Logger{
+		format: format,
+		out:    out,
+		pool:   sync.Pool{New: func() any { return new([]byte) }},
+	}
+}
+
+// Log форматирует запись и ставит её в очередь на запись.
+func (l *Logger) Log(e *Entry) {
+	bp := l.pool.Get().(*[]byte)
+	line := l.format.Format((*bp)[:0], e)
+	l.out.Write(line) // Writer копирует строку
+	*bp = line
+	l.pool.Put(bp)
+}
+
+// Close дописывает очередь и закрывает вывод.
+func (l *Logger) Close() error {
+	return l.out.Close()
+}
+
+type ctxKey struct{}
+
+// Middleware записывает в журнал каждый запрос, прошедший через next.
+func (l *Logger) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		e := &Entry{
+			Time:      time.Now(),
+			RemoteIP:  remoteIP(r),
+			Method:    r.Method,
+			URI:       r.RequestURI,
+			Proto:     r.Proto,
+			Host:      r.Host,
+			Referer:   r.Referer(),
+			UserAgent: r.UserAgent(),
+			RequestID: r.Header.Get("X-Request-ID"),
+		}
+		if key := r.Header.Get("X-API-Key"); key != "" {
+			// Сам ключ — секрет, в журнал попадает только его отпечаток
+			e.ClientKey = logger.Fingerprint(key)
+		}
+		rec := &ResponseRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r.WithContext(context.WithValue(r.Context(), ctxKey{}, e)))
+
+		e.Status = rec.Status()
+		e.Bytes = rec.Bytes()
+		e.Duration = time.Since(e.Time)
+		l.Log(e)
+	})
+}
+
+// Observe запоминает бэкенд, к которому обратился прокси; реализует proxy.Observer.
+// При переходе на запасной бэкенд в записи остаётся последний.
+func (l *Logger) Observe(r *http.Request, server string, _ time.Duration, _ int, _ error) {
+	if e, ok := r.Context().Value(ctxKey{}).(*Entry); ok {
+		e.Backend = server
+	}
+}
+
+// remoteIP возвращает IP-адрес клиента: определённый forwarded.Middleware с учётом
+// доверенных прокси, иначе адрес соединения.
+func remoteIP(r *http.Request) string {
+	if c, ok := forwarded.FromContext(r.Context()); ok {
+		return c.IP
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// ResponseRecorder запоминает код ответа и размер тела. Unwrap даёт
+// http.ResponseController доступ к Flush и другим возможностям исходного ResponseWriter.
+type ResponseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (w *ResponseRecorder) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *ResponseRecorder) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+func (w *ResponseRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Status возвращает код ответа; 200, если обработчик ничего не записал.
+func (w *ResponseRecorder) Status() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+// Bytes возвращает число записанных байт тела ответа.
+func (w *ResponseRecorder) Bytes() int64 {
+	return w.bytes
+}
@@ -0,0 +1,177 @@
+package accesslog
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coffee-realist/balancer/internal/forwarded"
+	"github.com/coffee-realist/balancer/internal/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testEntry — запись с заполненными полями для проверки форматов.
+func testEntry() *Entry {
+	return &Entry{
+		Time:      time.Date(2024, 3, 5, 14, 7, 9, 0, time.FixedZone("", 3*3600)),
+		RemoteIP:  "10.0.0.7",
+		Method:    http.MethodGet,
+		URI:       "/api/items?id=1",
+		Proto:     "HTTP/1.1",
+		Host:      "lb.local",
+		Status:    http.StatusOK,
+		Bytes:     512,
+		Duration:  1500 * time.Millisecond,
+		Referer:   "https://example.com/",
+		UserAgent: `curl/8.0 "test"`,
+		ClientKey: "team-a",
+		Backend:   "http://10.0.1.2:8080",
+	}
+}
+
+// TestCombined проверяет Apache Combined Log Format.
+func TestCombined(t *testing.T) {
+	line := string(Combined{}.Format(nil, testEntry()))
+	assert.Equal(t, `10.0.0.7 - - [05/Mar/2024:14:07:09 +0300] "GET /api/items?id=1 HTTP/1.1" 200 512 "https://example.com/" "curl/8.0 \"test\""`+"\n", line)
+
+	e := testEntry()
+	e.Bytes, e.Referer = 0, ""
+	assert.Contains(t, string(Combined{}.Format(nil, e)), `200 - "-"`)
+}
+
+// TestJSON проверяет JSON-формат с длительностью в секундах.
+func TestJSON(t *testing.T) {
+	line := JSON{}.Format(nil, testEntry())
+	assert.True(t, bytes.HasSuffix(line, []byte("\n")))
+	var got map[string]interface{}
+	require.NoError(t, json.Unmarshal(line, &got))
+	assert.Equal(t, 1.5, got["duration"])
+	assert.Equal(t, "http://10.0.1.2:8080", got["backend"])
+	assert.Equal(t, 200.0, got["status"])
+	assert.Equal(t, "team-a", got["client_key"])
+}
+
+// TestTemplate проверяет пользовательский шаблон и ошибки разбора.
+func TestTemplate(t *testing.T) {
+	f, err := NewFormatter(FormatTemplate, `{{.RemoteIP}} {{.Status}} {{.Backend}} {{ms .Duration}}ms`)
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.7 200 http://10.0.1.2:8080 1500ms\n", string(f.Format(nil, testEntry())))
+
+	_, err = NewFormatter(FormatTemplate, `{{.Missing`)
+	assert.Error(t, err)
+	_, err = NewFormatter(FormatTemplate, "")
+	assert.Error(t, err)
+	_, err = NewFormatter("xml", "")
+	assert.Error(t, err)
+}
+
+// syncBuffer — потокобезопасный вывод для тестов с признаком закрытия.
+type syncBuffer struct {
+	mu     sync.Mutex
+	buf    bytes.Buffer
+	writes int
+	closed bool
+	block  chan struct{} // Если не nil, Write ждёт закрытия канала
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	if b.block != nil {
+		<-b.block
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.writes++
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	return nil
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// TestWriter проверяет асинхронную запись, отбрасывание при переполнении и Close.
+func TestWriter(t *testing.T) {
+	out := &syncBuffer{block: make(chan struct{})}
+	w := NewWriter(out, 2)
+
+	line := []byte("a\n")
+	w.Write(line)
+	line[0] = 'x' // Writer хранит копию строки
+	// Первая строка может быть уже забрана горутиной записи, поэтому пишем с запасом
+	for range 4 {
+		w.Write([]byte("b\n"))
+	}
+	assert.Positive(t, w.Dropped(), "queue overflow drops lines instead of blocking")
+
+	close(out.block)
+	require.NoError(t, w.Close())
+	assert.True(t, out.closed)
+	assert.True(t, strings.HasPrefix(out.String(), "a\nb\n"))
+	assert.Equal(t, out.writes, strings.Count(out.String(), "\n"), "one Write per line")
+
+	w.Write([]byte("late\n"))
+	assert.NotContains(t, out.String(), "late")
+}
+
+// TestMiddleware проверяет запись о запросе со статусом, размером и бэкендом.
+func TestMiddleware(t *testing.T) {
+	out := &syncBuffer{}
+	l := New(JSON{}, NewWriter(out, 0))
+
+	handler := l.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Так прокси сообщает о бэкенде через proxy.Observer
+		l.Observe(r, "http://a:80", time.Millisecond, http.StatusCreated, nil)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("hello"))
+	}))
+	req := httptest.NewRequest(http.MethodPost, "/items?x=1", nil)
+	req.RemoteAddr = "192.0.2.1:4567"
+	req.Header.Set("User-Agent", "test-agent")
+	req.Header.Set("X-API-Key", "k1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	require.NoError(t, l.Close())
+
+	var got map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(out.String()), &got))
+	assert.Equal(t, "192.0.2.1", got["remote_ip"])
+	assert.Equal(t, "/items?x=1", got["uri"])
+	assert.Equal(t, 201.0, got["status"])
+	assert.Equal(t, 5.0, got["bytes"])
+	assert.Equal(t, "test-agent", got["user_agent"])
+	assert.Equal(t, logger.Fingerprint("k1"), got["client_key"], "API key itself is not logged")
+	assert.Equal(t, "http://a:80", got["backend"])
+	assert.Less(t, got["duration"], 1.0)
+}
+
+// TestMiddlewareForwardedClient проверяет адрес клиента за доверенным прокси.
+func TestMiddlewareForwardedClient(t *testing.T) {
+	out := &syncBuffer{}
+	l := New(JSON{}, NewWriter(out, 0))
+	trusted, err := forwarded.ParseTrustedProxies([]string{"10.0.0.0/8"})
+	require.NoError(t, err)
+	handler := forwarded.Middleware(l.Middleware(http.NotFoundHandler()), trusted)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.0.0.1:4567"
+	req.Header.Set("X-Forwarded-For", "198.51.100.4")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	require.NoError(t, l.Close())
+
+	var got map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(out.String()), &got))
+	assert.Equal(t, "198.51.100.4", got["remote_ip"])
+}
@@ -0,0 +1,117 @@
+package accesslog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"text/template"
+	"time"
+)
+
+// Форматы записей.
+const (
+	FormatCombined = "combined" // Apache Combined Log Format
+	FormatJSON     = "json"     // Запись — JSON-объект Entry
+	FormatTemplate = "template" // Шаблон text/template над Entry
+)
+
+// Formatter добавляет к buf строку записи, включая перевод строки.
+type Formatter interface {
+	Format(buf []byte, e *Entry) []byte
+}
+
+// NewFormatter создаёт Formatter по названию формата; tmpl нужен только для FormatTemplate.
+func NewFormatter(format, tmpl string) (Formatter, error) {
+	switch format {
+	case "", FormatCombined:
+		return Combined{}, nil
+	case FormatJSON:
+		return JSON{}, nil
+	case FormatTemplate:
+		return NewTemplate(tmpl)
+	}
+	return nil, fmt.Errorf("unknown access log format %q", format)
+}
+
+// Combined — Apache Combined Log Format:
+// %h %l %u [%t] "%r" %>s %b "%{Referer}i" "%{User-agent}i".
+type Combined struct{}
+
+// clfTime — формат времени Common Log Format.
+const clfTime = "02/Jan/2006:15:04:05 -0700"
+
+func (Combined) Format(buf []byte, e *Entry) []byte {
+	buf = append(buf, e.RemoteIP...)
+	buf = append(buf, " - - ["...)
+	buf = e.Time.AppendFormat(buf, clfTime)
+	buf = append(buf, "] "...)
+	buf = strconv.AppendQuote(buf, e.Method+" "+e.URI+" "+e.Proto)
+	buf = append(buf, ' ')
+	buf = strconv.AppendInt(buf, int64(e.Status), 10)
+	buf = append(buf, ' ')
+	if e.Bytes == 0 {
+		buf = append(buf, '-')
+	} else {
+		buf = strconv.AppendInt(buf, e.Bytes, 10)
+	}
+	buf = append(buf, ' ')
+	buf = strconv.AppendQuote(buf, dash(e.Referer))
+	buf = append(buf, ' ')
+	buf = strconv.AppendQuote(buf, dash(e.UserAgent))
+	return append(buf, '\n')
+}
+
+// dash заменяет пустое значение на "-", как принято в Combined.
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// JSON выводит запись JSON-объектом в одну строку; длительность — в секундах.
+type JSON struct{}
+
+// jsonEntry — Entry с длительностью в секундах вместо наносекунд.
+type jsonEntry struct {
+	*Entry
+	Duration float64 `json:"duration"`
+}
+
+func (JSON) Format(buf []byte, e *Entry) []byte {
+	data, err := json.Marshal(jsonEntry{Entry: e, Duration: e.Duration.Seconds()})
+	if err != nil {
+		return buf
+	}
+	buf = append(buf, data...)
+	return append(buf, '\n')
+}
+
+// Template выводит запись по шаблону text/template над полями Entry,
+// например `{{.RemoteIP}} {{.Status}} {{.Backend}} {{.Duration}}`.
+type Template struct {
+	t *template.Template
+}
+
+// NewTemplate разбирает шаблон записи; перевод строки добавляется автоматически.
+func NewTemplate(text string) (*Template, error) {
+	if text == "" {
+		return nil, fmt.Errorf("empty access log template")
+	}
+	t, err := template.New("access").Funcs(template.FuncMap{
+		"ms": func(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) },
+	}).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid access log template: %w", err)
+	}
+	return &Template{t: t}, nil
+}
+
+func (t *Template) Format(buf []byte, e *Entry) []byte {
+	w := bytes.NewBuffer(buf)
+	if err := t.t.Execute(w, e); err != nil {
+		return buf
+	}
+	return append(w.Bytes(), '\n')
+}
@@ -0,0 +1,138 @@
+package accesslog
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// backupTimeFormat — суффикс имени файла после ротации.
+const backupTimeFormat = "20060102T150405.000"
+
+// RotatingFile — файл журнала с ротацией по размеру и по времени. При ротации текущий
+// файл переименовывается в <path>.<время>, копии сверх MaxBackups удаляются.
+// Не потокобезопасен: пишет только горутина Writer.
+type RotatingFile struct {
+	path       string
+	maxSize    int64         // Размер, после которого файл ротируется; 0 — без ограничения
+	interval   time.Duration // Период ротации; 0 — без ротации по времени
+	maxBackups int           // Сколько ротированных файлов хранить; 0 — все
+	now        func() time.Time
+
+	f        *os.File
+	bw       *bufio.Writer
+	size     int64
+	openedAt time.Time
+}
+
+// NewRotatingFile открывает файл журнала на дозапись.
+func NewRotatingFile(path string, maxSize int64, interval time.Duration, maxBackups int) (*RotatingFile, error) {
+	rf := &RotatingFile{
+		path:       path,
+		maxSize:    maxSize,
+		interval:   interval,
+		maxBackups: maxBackups,
+		now:        time.Now,
+	}
+	if err := rf.open(); err != nil {
+		return nil, err
+	}
+	return rf, nil
+}
+
+// open открывает файл и учитывает его текущий размер.
+func (rf *RotatingFile) open() error {
+	f, err := os.OpenFile(rf.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	rf.f = f
+	rf.bw = bufio.NewWriterSize(f, 64<<10)
+	rf.size = info.Size()
+	rf.openedAt = rf.now()
+	return nil
+}
+
+// Write дописывает строку, предварительно ротируя файл, если он переполнен или устарел.
+func (rf *RotatingFile) Write(p []byte) (int, error) {
+	if rf.needRotate(int64(len(p))) {
+		if err := rf.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := rf.bw.Write(p)
+	rf.size += int64(n)
+	return n, err
+}
+
+// needRotate сообщает, пора ли начинать новый файл перед записью n байт.
+func (rf *RotatingFile) needRotate(n int64) bool {
+	if rf.size == 0 {
+		return false
+	}
+	if rf.maxSize > 0 && rf.size+n > rf.maxSize {
+		return true
+	}
+	return rf.interval > 0 && rf.now().Sub(rf.openedAt) >= rf.interval
+}
+
+// rotate переименовывает текущий файл, открывает новый и удаляет лишние копии.
+func (rf *RotatingFile) rotate() error {
+	if err := rf.closeFile(); err != nil {
+		return err
+	}
+	backup := rf.path + "." + rf.now().Format(backupTimeFormat)
+	if err := os.Rename(rf.path, backup); err != nil {
+		return err
+	}
+	if err := rf.open(); err != nil {
+		return err
+	}
+	return rf.prune()
+}
+
+// prune удаляет самые старые ротированные файлы сверх maxBackups.
+func (rf *RotatingFile) prune() error {
+	if rf.maxBackups <= 0 {
+		return nil
+	}
+	backups, err := filepath.Glob(rf.path + ".*")
+	if err != nil {
+		return err
+	}
+	// Суффикс времени сортируется лексикографически
+	slices.Sort(backups)
+	for len(backups) > rf.maxBackups {
+		if err := os.Remove(backups[0]); err != nil {
+			return err
+		}
+		backups = backups[1:]
+	}
+	return nil
+}
+
+// Flush сбрасывает буфер в файл.
+func (rf *RotatingFile) Flush() error {
+	return rf.bw.Flush()
+}
+
+// Close сбрасывает буфер и закрывает файл.
+func (rf *RotatingFile) Close() error {
+	return rf.closeFile()
+}
+
+// closeFile сбрасывает буфер и закрывает текущий файл.
+func (rf *RotatingFile) closeFile() error {
+	if err := rf.bw.Flush(); err != nil {
+		_ = rf.f.Close()
+		return err
+	}
+	return rf.f.Close()
+}
@@ -0,0 +1,63 @@
+package accesslog
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRotatingFile_Size проверяет ротацию по размеру и удаление лишних копий.
+func TestRotatingFile_Size(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	rf, err := NewRotatingFile(path, 10, 0, 2)
+	require.NoError(t, err)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	rf.now = func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+
+	for _, line := range []string{"11111\n", "22222\n", "33333\n", "44444\n"} {
+		_, err := rf.Write([]byte(line))
+		require.NoError(t, err)
+	}
+	require.NoError(t, rf.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "44444\n", string(data))
+	backups, _ := filepath.Glob(path + ".*")
+	assert.Len(t, backups, 2, "only max_backups copies are kept")
+	oldest, err := os.ReadFile(backups[0])
+	require.NoError(t, err)
+	assert.Equal(t, "22222\n", string(oldest))
+}
+
+// TestRotatingFile_Interval проверяет ротацию по времени и дозапись в существующий файл.
+func TestRotatingFile_Interval(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	require.NoError(t, os.WriteFile(path, []byte("old\n"), 0o644))
+
+	rf, err := NewRotatingFile(path, 0, time.Hour, 0)
+	require.NoError(t, err)
+	now := rf.openedAt
+	rf.now = func() time.Time { return now }
+
+	_, err = rf.Write([]byte("a\n"))
+	require.NoError(t, err)
+	now = now.Add(time.Hour)
+	_, err = rf.Write([]byte("b\n"))
+	require.NoError(t, err)
+	require.NoError(t, rf.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "b\n", string(data))
+	backup, err := os.ReadFile(path + "." + now.Format(backupTimeFormat))
+	require.NoError(t, err)
+	assert.Equal(t, "old\na\n", string(backup))
+}
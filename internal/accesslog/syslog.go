@@ -0,0 +1,15 @@
+//go:build !windows && !plan9
+
+package accesslog
+
+import (
+	"io"
+	"log/syslog"
+)
+
+// NewSyslog подключается к syslog: network и addr пустые — локальный сокет,
+// иначе, например, "udp" и "logs.internal:514". Каждая строка — отдельное сообщение
+// уровня info с меткой tag.
+func NewSyslog(network, addr, tag string) (io.WriteCloser, error) {
+	return syslog.Dial(network, addr, syslog.LOG_INFO|syslog.LOG_LOCAL0, tag)
+}
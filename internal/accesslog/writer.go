@@ -0,0 +1,114 @@
+package accesslog
+
+import (
+	"bufio"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultQueueSize — размер очереди записей по умолчанию.
+const DefaultQueueSize = 8192
+
+// flusher — вывод с собственным буфером, который сбрасывается, когда очередь опустела.
+type flusher interface {
+	Flush() error
+}
+
+// Writer асинхронно пишет строки в out из отдельной горутины. Запрос не ждёт записи:
+// при переполненной очереди строка отбрасывается и учитывается в Dropped.
+// Каждая строка передаётся в out отдельным вызовом Write, поэтому для syslog одна строка —
+// одно сообщение; выводы с буфером (Flush) сбрасываются, когда очередь опустела.
+type Writer struct {
+	out     io.WriteCloser
+	lines   chan []byte
+	dropped atomic.Uint64
+	errs    atomic.Uint64
+	mu      sync.RWMutex // Защищает закрытие очереди от параллельной записи
+	closed  bool
+	done    chan struct{}
+	err     error // Ошибка закрытия out, доступна после done
+}
+
+// NewWriter создаёт Writer с очередью на queue строк и запускает горутину записи.
+func NewWriter(out io.WriteCloser, queue int) *Writer {
+	if queue <= 0 {
+		queue = DefaultQueueSize
+	}
+	w := &Writer{
+		out:   out,
+		lines: make(chan []byte, queue),
+		done:  make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// Write ставит копию строки в очередь; после Close строки отбрасываются.
+func (w *Writer) Write(line []byte) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.closed {
+		w.dropped.Add(1)
+		return
+	}
+	select {
+	case w.lines <- append([]byte(nil), line...):
+	default:
+		w.dropped.Add(1)
+	}
+}
+
+// Dropped возвращает число строк, отброшенных из-за переполнения очереди.
+func (w *Writer) Dropped() uint64 {
+	return w.dropped.Load()
+}
+
+// Errors возвращает число строк, которые не удалось записать в вывод.
+func (w *Writer) Errors() uint64 {
+	return w.errs.Load()
+}
+
+// Close дописывает очередь, сбрасывает буфер и закрывает вывод.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.lines)
+	}
+	w.mu.Unlock()
+	<-w.done
+	return w.err
+}
+
+// run пишет строки из очереди до её закрытия.
+func (w *Writer) run() {
+	defer close(w.done)
+	f, buffered := w.out.(flusher)
+	for line := range w.lines {
+		if _, err := w.out.Write(line); err != nil {
+			w.errs.Add(1)
+		}
+		if buffered && len(w.lines) == 0 {
+			_ = f.Flush()
+		}
+	}
+	if buffered {
+		_ = f.Flush()
+	}
+	w.err = w.out.Close()
+}
+
+// bufferedStream — поток (stdout) с буфером; Close не закрывает сам поток.
+type bufferedStream struct {
+	*bufio.Writer
+}
+
+// NewStream возвращает вывод в поток с буферизацией, например в os.Stdout.
+func NewStream(out io.Writer) io.WriteCloser {
+	return bufferedStream{Writer: bufio.NewWriterSize(out, 64<<10)}
+}
+
+func (s bufferedStream) Close() error {
+	return s.Flush()
+}
@@ -0,0 +1,234 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/coffee-realist/balancer/internal/balancer"
+	"github.com/coffee-realist/balancer/internal/balancer/healthcheck"
+	"github.com/coffee-realist/balancer/internal/balancer/stats"
+	"github.com/coffee-realist/balancer/internal/logger"
+	"github.com/coffee-realist/balancer/internal/requestid"
+)
+
+// adminBackendsPath — префикс путей управления бэкендами.
+const adminBackendsPath = "/admin/backends"
+
+// HealthChecks — источник результатов активных проверок здоровья (healthcheck.Checker).
+type HealthChecks interface {
+	Last(server string) (healthcheck.Result, bool)
+}
+
+// Backends — источники сведений о бэкендах для admin API. Любое поле может быть пустым:
+// соответствующие данные тогда не попадают в ответ.
+type Backends struct {
+	Servers []string            // Серверы из конфигурации, если стратегия не поддерживает Membership
+	Members balancer.Membership // Состав, веса, состояние и число запросов в обработке
+	Health  balancer.Health     // Итоговое здоровье с учётом активных и пассивных проверок
+	Checks  HealthChecks        // Результаты активных проверок
+	Stats   *stats.Collector    // Счётчики проксированных запросов
+}
+
+// BackendStatus — состояние бэкенда в ответах admin API.
+type BackendStatus struct {
+	ID        string              `json:"id"` // Хост бэкенда, используется в путях /admin/backends/{id}
+	Server    string              `json:"server"`
+	State     balancer.AdminState `json:"state"`
+	Healthy   bool                `json:"healthy"`
+	Weight    int                 `json:"weight,omitempty"`
+	Active    int64               `json:"active"`
+	Requests  int64               `json:"requests"`
+	Errors    int64               `json:"errors"`
+	ErrorRate float64             `json:"error_rate"`
+	LastCheck *healthcheck.Result `json:"last_check,omitempty"`
+}
+
+// adminActions — действия над бэкендом и состояния, в которые они его переводят.
+// drain выполняется через Membership.Drain, как DELETE /servers?drain=true.
+var adminActions = map[string]balancer.AdminState{
+	"drain":   balancer.StateDraining,
+	"enable":  balancer.StateEnabled,
+	"disable": balancer.StateDisabled,
+}
+
+// RegisterAdmin регистрирует административные обработчики бэкендов:
+// - GET /admin/backends — список бэкендов со здоровьем, нагрузкой и статистикой,
+// - GET /admin/backends/{id} — состояние одного бэкенда,
+// - POST /admin/backends/{id}/drain — вывод из ротации и удаление после завершения
+// начатых запросов, как DELETE /servers?drain=true; до удаления отменяется через enable,
+// - POST /admin/backends/{id}/disable — вывод из ротации без удаления,
+// - POST /admin/backends/{id}/enable — возврат в ротацию.
+// В качестве id принимается хост бэкенда или полный URL, экранированный в пути.
+func RegisterAdmin(mux *http.ServeMux, backends Backends, log logger.Logger) {
+	mux.HandleFunc(adminBackendsPath, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			requestid.Error(w, r, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		writeJSON(w, backends.List(), log)
+	})
+
+	mux.HandleFunc(adminBackendsPath+"/", func(w http.ResponseWriter, r *http.Request) {
+		// Путь экранированный: полный URL бэкенда содержит "/", закодированные как %2F
+		rest := strings.TrimPrefix(r.URL.EscapedPath(), adminBackendsPath+"/")
+		rawID, action, _ := strings.Cut(rest, "/")
+		id, err := url.PathUnescape(rawID)
+		if err != nil || id == "" {
+			requestid.Error(w, r, "not found", http.StatusNotFound)
+			return
+		}
+		st, ok := backends.find(id)
+		if !ok {
+			requestid.Error(w, r, "backend not found", http.StatusNotFound)
+			return
+		}
+
+		if action == "" {
+			if r.Method != http.MethodGet {
+				requestid.Error(w, r, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			writeJSON(w, st, log)
+			return
+		}
+
+		state, ok := adminActions[action]
+		if !ok {
+			requestid.Error(w, r, "unknown action", http.StatusNotFound)
+			return
+		}
+		if r.Method != http.MethodPost {
+			requestid.Error(w, r, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if backends.Members == nil {
+			requestid.Error(w, r, "membership not supported by algorithm", http.StatusNotImplemented)
+			return
+		}
+		if state == balancer.StateDraining {
+			err = backends.Members.Drain(st.Server)
+		} else {
+			err = backends.Members.SetState(st.Server, state)
+		}
+		if err != nil {
+			if errors.Is(err, balancer.ErrUnknownServer) {
+				// Сервер удалён между поиском и изменением состояния
+				requestid.Error(w, r, "backend not found", http.StatusNotFound)
+				return
+			}
+			log.Errorf("set backend %s state error: %v", st.Server, err)
+			requestid.Error(w, r, "internal error", http.StatusInternalServerError)
+			return
+		}
+		log.Infof("backend %s state set to %s", st.Server, state)
+
+		st, ok = backends.find(st.Server)
+		if !ok {
+			// Выводимый сервер без начатых запросов удалён сразу
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		writeJSON(w, st, log)
+	})
+}
+
+// List собирает состояние всех бэкендов.
+func (b Backends) List() []BackendStatus {
+	var members []balancer.MemberInfo
+	if b.Members != nil {
+		members = b.Members.Members()
+	} else {
+		members = make([]balancer.MemberInfo, len(b.Servers))
+		for i, s := range b.Servers {
+			members[i] = balancer.MemberInfo{Server: s, State: balancer.StateEnabled}
+		}
+	}
+	list := make([]BackendStatus, len(members))
+	for i, m := range members {
+		list[i] = b.status(m)
+	}
+	return list
+}
+
+// find ищет бэкенд по хосту или полному URL.
+func (b Backends) find(id string) (BackendStatus, bool) {
+	for _, st := range b.List() {
+		if st.ID == id || st.Server == id {
+			return st, true
+		}
+	}
+	return BackendStatus{}, false
+}
+
+// status дополняет сведения о составе здоровьем и статистикой.
+func (b Backends) status(m balancer.MemberInfo) BackendStatus {
+	st := BackendStatus{
+		ID:      backendID(m.Server),
+		Server:  m.Server,
+		State:   m.State,
+		Healthy: b.Health == nil || b.Health.Healthy(m.Server),
+		Weight:  m.Weight,
+		Active:  m.Active,
+	}
+	if b.Stats != nil {
+		s := b.Stats.Get(m.Server)
+		st.Requests, st.Errors, st.ErrorRate = s.Requests, s.Errors, s.ErrorRate
+	}
+	if b.Checks != nil {
+		if res, ok := b.Checks.Last(m.Server); ok {
+			st.LastCheck = &res
+		}
+	}
+	return st
+}
+
+// backendID возвращает хост бэкенда, а для адреса без хоста — адрес целиком.
+func backendID(server string) string {
+	if u, err := url.Parse(server); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return server
+}
+
+// writeJSON отправляет значение в формате JSON.
+func writeJSON(w http.ResponseWriter, v any, log logger.Logger) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Errorf("encode response error: %v", err)
+	}
+}
+
+// logLevelRequest — тело запросов /admin/log-level.
+type logLevelRequest struct {
+	Level string `json:"level"`
+}
+
+// RegisterLogLevel регистрирует управление уровнем журнала во время работы:
+// - GET /admin/log-level — текущий уровень ({"level": "info"}),
+// - PUT /admin/log-level — новый уровень: debug, info, warn или error.
+func RegisterLogLevel(mux *http.ServeMux, level *slog.LevelVar, log logger.Logger) {
+	mux.HandleFunc("/admin/log-level", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			var req logLevelRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				requestid.Error(w, r, "invalid body", http.StatusBadRequest)
+				return
+			}
+			if err := level.UnmarshalText([]byte(req.Level)); err != nil {
+				requestid.Error(w, r, "unknown level", http.StatusBadRequest)
+				return
+			}
+			log.Infof("log level set to %s", level.Level())
+		default:
+			requestid.Error(w, r, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		writeJSON(w, logLevelRequest{Level: strings.ToLower(level.Level().String())}, log)
+	})
+}
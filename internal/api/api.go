@@ -2,46 +2,66 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
+	"github.com/coffee-realist/balancer/internal/balancer"
 	"github.com/coffee-realist/balancer/internal/ratelimiter"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/coffee-realist/balancer/internal/logger"
+	"github.com/coffee-realist/balancer/internal/requestid"
 )
 
-// Register регистрирует HTTP-обработчики для управления клиентами и проксирования запросов.
-// - /clients используется для добавления клиента (POST),
-// - /clients/<id> — для получения (GET) и удаления (DELETE),
-// - все остальные запросы обрабатываются через proxyHandler.
-func Register(
+// ManagementPaths — шаблоны путей управляющего API: RegisterManagement, RegisterAdmin и /metrics.
+var ManagementPaths = []string{"/clients", "/clients/", "/servers", "/admin/", "/metrics"}
+
+// Mount направляет управляющие пути (ManagementPaths) mux на handler, например
+// на mux управляющего API, обёрнутый аутентификацией.
+func Mount(mux *http.ServeMux, handler http.Handler) {
+	for _, p := range ManagementPaths {
+		mux.Handle(p, handler)
+	}
+}
+
+// RegisterManagement регистрирует HTTP-обработчики для управления клиентами и бэкендами:
+// - /clients используется для добавления клиента (POST) и списка клиентов (GET, см. listClients),
+// - /clients/<id> — для получения (GET), замены (PUT), частичного изменения (PATCH) и удаления (DELETE);
+// GET возвращает ETag конфигурации, PUT и PATCH учитывают If-Match,
+// - /servers — для управления составом бэкендов (см. registerServers), members может быть nil.
+func RegisterManagement(
 	mux *http.ServeMux,
 	clientMgr *ratelimiter.DBManager,
-	proxyHandler http.Handler,
+	members balancer.Membership,
 	log logger.Logger,
 ) {
-	// Обработчик для POST-запросов на /clients: добавление нового клиента по ID
+	// Обработчик для запросов на /clients: список клиентов и добавление нового клиента по ID
 	mux.HandleFunc("/clients", func(w http.ResponseWriter, r *http.Request) {
 		if clientMgr == nil {
-			http.Error(w, `{"error":"service unavailable"}`, http.StatusServiceUnavailable)
+			requestid.Error(w, r, "service unavailable", http.StatusServiceUnavailable)
 			return
 		}
-		// Разрешаем только POST-метод
-		if r.Method != http.MethodPost {
-			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		switch r.Method {
+		case http.MethodGet:
+			listClients(w, r, clientMgr, log)
+			return
+		case http.MethodPost:
+		default:
+			requestid.Error(w, r, "method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
 
 		// Получаем ID клиента из query-параметра
 		id := r.URL.Query().Get("id")
 		if id == "" {
-			http.Error(w, `{"error":"missing id"}`, http.StatusBadRequest)
+			requestid.Error(w, r, "missing id", http.StatusBadRequest)
 			return
 		}
 
 		// Декодируем конфигурацию клиента из тела запроса
 		var cfg ratelimiter.ClientConfig
 		if err := json.NewDecoder(r.Body).Decode(&cfg); err != nil {
-			http.Error(w, `{"error":"invalid body"}`, http.StatusBadRequest)
+			requestid.Error(w, r, "invalid body", http.StatusBadRequest)
 			return
 		}
 
@@ -56,12 +76,16 @@ func Register(
 		w.WriteHeader(http.StatusCreated)
 	})
 
-	// Обработчик для GET и DELETE-запросов на /clients/<id>
+	// Обработчик для GET, PUT, PATCH и DELETE-запросов на /clients/<id>
 	mux.HandleFunc("/clients/", func(w http.ResponseWriter, r *http.Request) {
+		if clientMgr == nil {
+			requestid.Error(w, r, "service unavailable", http.StatusServiceUnavailable)
+			return
+		}
 		// Извлекаем ID из URL-пути, ожидается "/clients/<id>"
 		parts := strings.SplitN(r.URL.Path, "/", 3)
 		if len(parts) != 3 || parts[2] == "" {
-			http.Error(w, "not found", http.StatusNotFound)
+			requestid.Error(w, r, "not found", http.StatusNotFound)
 			return
 		}
 		id := parts[2]
@@ -71,32 +95,95 @@ func Register(
 			// Получаем конфигурацию клиента по ID
 			cfg, err := clientMgr.GetClient(id)
 			if err != nil {
-				http.Error(w, `{"error":"not found"}`, http.StatusNotFound)
+				requestid.Error(w, r, "not found", http.StatusNotFound)
 				return
 			}
 
 			// Отправляем конфигурацию в формате JSON
-			w.Header().Set("Content-Type", "application/json")
-			err = json.NewEncoder(w).Encode(cfg)
+			writeClient(w, cfg, log)
+
+		case http.MethodPut, http.MethodPatch:
+			// Изменяем конфигурацию, сохраняя текущий баланс токенов
+			var patch ratelimiter.ClientPatch
+			if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+				requestid.Error(w, r, "invalid body", http.StatusBadRequest)
+				return
+			}
+			// PUT заменяет конфигурацию целиком, поэтому все поля обязательны
+			if r.Method == http.MethodPut && (patch.Capacity == nil || patch.RefillRate == nil || patch.RefillInterval == nil) {
+				requestid.Error(w, r, "capacity, refill_rate and refill_interval are required", http.StatusBadRequest)
+				return
+			}
+			cfg, err := clientMgr.UpdateClient(id, patch, r.Header.Get("If-Match"))
 			if err != nil {
-				log.Errorf("encode client error: %v", err)
+				writeClientError(w, r, err, log)
 				return
 			}
+			log.Infof("client %s updated", id)
+			writeClient(w, cfg, log)
 
 		case http.MethodDelete:
 			// Удаляем клиента по ID
 			if err := clientMgr.RemoveClient(id); err != nil {
-				http.Error(w, `{"error":"not found"}`, http.StatusNotFound)
+				requestid.Error(w, r, "not found", http.StatusNotFound)
 				return
 			}
 			w.WriteHeader(http.StatusNoContent) // 204 No Content при успешном удалении
 
 		default:
 			// Любые другие методы не разрешены
-			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			requestid.Error(w, r, "method not allowed", http.StatusMethodNotAllowed)
 		}
 	})
 
-	// Все остальные пути обрабатываются прокси-обработчиком
-	mux.Handle("/", proxyHandler)
+	// Управление составом бэкендов
+	registerServers(mux, members, log)
+}
+
+// clientList — страница списка клиентов.
+type clientList struct {
+	Clients    []ratelimiter.ClientInfo `json:"clients"`
+	NextCursor string                   `json:"next_cursor,omitempty"`
+}
+
+// listClients отвечает страницей клиентов: GET /clients?limit=&cursor=&prefix=.
+// Для следующей страницы в cursor передаётся next_cursor из ответа.
+func listClients(w http.ResponseWriter, r *http.Request, clientMgr *ratelimiter.DBManager, log logger.Logger) {
+	q := r.URL.Query()
+	limit := 0
+	if v := q.Get("limit"); v != "" {
+		var err error
+		if limit, err = strconv.Atoi(v); err != nil || limit <= 0 {
+			requestid.Error(w, r, "invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+	clients, next, err := clientMgr.ListClients(q.Get("prefix"), q.Get("cursor"), limit)
+	if err != nil {
+		log.Errorf("list clients error: %v", err)
+		requestid.Error(w, r, "internal error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, clientList{Clients: clients, NextCursor: next}, log)
+}
+
+// writeClient отправляет конфигурацию клиента вместе с её ETag.
+func writeClient(w http.ResponseWriter, cfg ratelimiter.ClientConfig, log logger.Logger) {
+	w.Header().Set("ETag", cfg.ETag())
+	writeJSON(w, cfg, log)
+}
+
+// writeClientError отвечает кодом, соответствующим ошибке изменения клиента.
+func writeClientError(w http.ResponseWriter, r *http.Request, err error, log logger.Logger) {
+	switch {
+	case errors.Is(err, ratelimiter.ErrClientNotFound):
+		requestid.Error(w, r, "not found", http.StatusNotFound)
+	case errors.Is(err, ratelimiter.ErrETagMismatch):
+		requestid.Error(w, r, "client was modified, reload and retry", http.StatusPreconditionFailed)
+	case errors.Is(err, ratelimiter.ErrInvalidConfig):
+		requestid.Error(w, r, err.Error(), http.StatusBadRequest)
+	default:
+		log.Errorf("update client error: %v", err)
+		requestid.Error(w, r, "internal error", http.StatusInternalServerError)
+	}
 }
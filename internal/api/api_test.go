@@ -3,21 +3,24 @@ package api
 import (
 	"bytes"
 	"encoding/json"
+	"github.com/coffee-realist/balancer/internal/balancer"
+	"github.com/coffee-realist/balancer/internal/balancer/healthcheck"
+	"github.com/coffee-realist/balancer/internal/balancer/round_robin"
+	"github.com/coffee-realist/balancer/internal/balancer/stats"
+	"github.com/coffee-realist/balancer/internal/logger"
 	"github.com/coffee-realist/balancer/internal/ratelimiter"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
-// dummyLog — пустая реализация logger.Logger для использования в тестах.
-type dummyLog struct{}
-
-func (dummyLog) Infof(string, ...interface{})  {}
-func (dummyLog) Errorf(string, ...interface{}) {}
-
 // setupAPI — настройка тестового API-сервера и менеджера клиентов.
 func setupAPI() (*httptest.Server, *ratelimiter.DBManager) {
 	mux := http.NewServeMux()
@@ -25,7 +28,7 @@ func setupAPI() (*httptest.Server, *ratelimiter.DBManager) {
 	if err != nil {
 		panic(err)
 	}
-	Register(mux, clientMgr, http.NotFoundHandler(), dummyLog{})
+	RegisterManagement(mux, clientMgr, nil, logger.Nop())
 	ts := httptest.NewServer(mux)
 	return ts, clientMgr
 }
@@ -63,6 +66,57 @@ func TestAPIClientCRUD(t *testing.T) {
 		assert.Equal(t, cfg.RefillRate, got.RefillRate)
 	})
 
+	// Тест для списка клиентов
+	t.Run("ListClients", func(t *testing.T) {
+		resp, err := http.Get(ts.URL + "/clients?prefix=client&limit=10")
+		assert.NoError(t, err)
+		defer func() { _ = resp.Body.Close() }()
+
+		var got clientList
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&got))
+		if assert.Len(t, got.Clients, 1) {
+			assert.Equal(t, id, got.Clients[0].ID)
+		}
+		assert.Empty(t, got.NextCursor)
+
+		resp, err = http.Get(ts.URL + "/clients?limit=abc")
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	// Тест для частичного изменения клиента с проверкой ETag
+	t.Run("PatchClient", func(t *testing.T) {
+		resp, err := http.Get(ts.URL + "/clients/" + id)
+		assert.NoError(t, err)
+		etag := resp.Header.Get("ETag")
+		assert.NotEmpty(t, etag)
+
+		patch := func(body, ifMatch string) *http.Response {
+			req, _ := http.NewRequest(http.MethodPatch, ts.URL+"/clients/"+id, strings.NewReader(body))
+			req.Header.Set("If-Match", ifMatch)
+			resp, err := http.DefaultClient.Do(req)
+			assert.NoError(t, err)
+			return resp
+		}
+
+		resp = patch(`{"refill_rate":3}`, etag)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.NotEqual(t, etag, resp.Header.Get("ETag"))
+		var got ratelimiter.ClientConfig
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&got))
+		assert.Equal(t, 3.0, got.RefillRate)
+		assert.Equal(t, cfg.Capacity, got.Capacity)
+
+		// Второй оператор с устаревшим ETag не перезаписывает изменение
+		assert.Equal(t, http.StatusPreconditionFailed, patch(`{"refill_rate":1}`, etag).StatusCode)
+		assert.Equal(t, http.StatusBadRequest, patch(`{"capacity":-1}`, "").StatusCode)
+
+		req, _ := http.NewRequest(http.MethodPut, ts.URL+"/clients/"+id, strings.NewReader(`{"capacity":5}`))
+		resp, err = http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, "PUT needs the full config")
+	})
+
 	// Тест для удаления клиента
 	t.Run("DeleteClient", func(t *testing.T) {
 		req, _ := http.NewRequest(http.MethodDelete, ts.URL+"/clients/"+id, nil)
@@ -79,6 +133,199 @@ func TestAPIClientCRUD(t *testing.T) {
 	})
 }
 
+// TestAPIServers — тестирование управления составом бэкендов.
+func TestAPIServers(t *testing.T) {
+	members := round_robin.NewRoundRobinBalancer([]string{"http://a:80"}).(balancer.Membership)
+	mux := http.NewServeMux()
+	RegisterManagement(mux, nil, members, logger.Nop())
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	do := func(method, path, body string) int {
+		req, _ := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+		resp, err := http.DefaultClient.Do(req)
+		if !assert.NoError(t, err) {
+			return 0
+		}
+		_ = resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	t.Run("AddServer", func(t *testing.T) {
+		assert.Equal(t, http.StatusCreated, do(http.MethodPost, "/servers", `{"server":"http://b:80","weight":2}`))
+		assert.Equal(t, http.StatusConflict, do(http.MethodPost, "/servers", `{"server":"http://b:80"}`))
+		assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/servers", `{"server":"b:80"}`))
+		assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/servers", `{"server":"http://c:80","weight":-1}`))
+	})
+
+	t.Run("SetWeight", func(t *testing.T) {
+		assert.Equal(t, http.StatusNoContent, do(http.MethodPut, "/servers?server=http://b:80", `{"weight":3}`))
+		assert.Equal(t, http.StatusNotFound, do(http.MethodPut, "/servers?server=http://x:80", `{"weight":3}`))
+		assert.Equal(t, http.StatusBadRequest, do(http.MethodPut, "/servers?server=http://b:80", `{"weight":0}`))
+		assert.Equal(t, http.StatusBadRequest, do(http.MethodPut, "/servers?server=http://b:80", `{"weight":2000000000}`))
+	})
+
+	t.Run("ListServers", func(t *testing.T) {
+		resp, err := http.Get(ts.URL + "/servers")
+		assert.NoError(t, err)
+		defer func() { _ = resp.Body.Close() }()
+		var got []balancer.MemberInfo
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&got))
+		assert.Equal(t, []balancer.MemberInfo{
+			{Server: "http://a:80", Weight: 1, State: balancer.StateEnabled},
+			{Server: "http://b:80", Weight: 3, State: balancer.StateEnabled},
+		}, got)
+	})
+
+	t.Run("DrainAndRemove", func(t *testing.T) {
+		assert.Equal(t, http.StatusAccepted, do(http.MethodDelete, "/servers?server=http://b:80&drain=true", ""))
+		assert.Len(t, members.Members(), 1, "idle server is removed at once")
+		assert.Equal(t, http.StatusNoContent, do(http.MethodDelete, "/servers?server=http://a:80", ""))
+		assert.Equal(t, http.StatusNotFound, do(http.MethodDelete, "/servers?server=http://a:80", ""))
+	})
+
+	t.Run("Unsupported", func(t *testing.T) {
+		mux := http.NewServeMux()
+		RegisterManagement(mux, nil, nil, logger.Nop())
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/servers", nil))
+		assert.Equal(t, http.StatusNotImplemented, rec.Code)
+	})
+}
+
+// stubHealth — Health с фиксированным набором нездоровых серверов.
+type stubHealth map[string]bool
+
+func (h stubHealth) Healthy(server string) bool { return !h[server] }
+
+// stubChecks — результаты активных проверок для тестов.
+type stubChecks map[string]healthcheck.Result
+
+func (c stubChecks) Last(server string) (healthcheck.Result, bool) {
+	res, ok := c[server]
+	return res, ok
+}
+
+// TestAPIAdminBackends — тестирование списка бэкендов и смены их состояния.
+func TestAPIAdminBackends(t *testing.T) {
+	members := round_robin.NewRoundRobinBalancer([]string{"http://a:80", "http://b:80"}).(balancer.Membership)
+	collector := stats.NewCollector()
+	collector.Done("http://a:80", time.Millisecond, http.StatusOK, nil)
+	collector.Done("http://a:80", time.Millisecond, http.StatusBadGateway, nil)
+	check := healthcheck.Result{Time: time.Unix(100, 0).UTC(), Status: http.StatusInternalServerError, Error: "unexpected status 500"}
+
+	mux := http.NewServeMux()
+	RegisterAdmin(mux, Backends{
+		Members: members,
+		Health:  stubHealth{"http://b:80": true},
+		Checks:  stubChecks{"http://b:80": check},
+		Stats:   collector,
+	}, logger.Nop())
+
+	do := func(method, path string) (int, []byte) {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		return rec.Code, rec.Body.Bytes()
+	}
+
+	t.Run("List", func(t *testing.T) {
+		code, body := do(http.MethodGet, "/admin/backends")
+		assert.Equal(t, http.StatusOK, code)
+		var got []BackendStatus
+		assert.NoError(t, json.Unmarshal(body, &got))
+		assert.Equal(t, []BackendStatus{
+			{ID: "a:80", Server: "http://a:80", State: balancer.StateEnabled, Healthy: true, Weight: 1, Requests: 2, Errors: 1, ErrorRate: 0.5},
+			{ID: "b:80", Server: "http://b:80", State: balancer.StateEnabled, Weight: 1, LastCheck: &check},
+		}, got)
+	})
+
+	t.Run("Get", func(t *testing.T) {
+		code, body := do(http.MethodGet, "/admin/backends/b:80")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Contains(t, string(body), `"server":"http://b:80"`)
+
+		code, _ = do(http.MethodGet, "/admin/backends/"+url.PathEscape("http://a:80"))
+		assert.Equal(t, http.StatusOK, code, "full URL is accepted as id")
+
+		code, _ = do(http.MethodGet, "/admin/backends/x:80")
+		assert.Equal(t, http.StatusNotFound, code)
+	})
+
+	t.Run("Actions", func(t *testing.T) {
+		// Запрос в обработке удерживает выводимый сервер в составе
+		members.(balancer.ConnAware).Increase("http://a:80")
+		for _, step := range []struct {
+			action string
+			state  balancer.AdminState
+		}{
+			{"disable", balancer.StateDisabled},
+			{"drain", balancer.StateDraining},
+			{"enable", balancer.StateEnabled},
+		} {
+			code, body := do(http.MethodPost, "/admin/backends/a:80/"+step.action)
+			assert.Equal(t, http.StatusOK, code, step.action)
+			var got BackendStatus
+			assert.NoError(t, json.Unmarshal(body, &got))
+			assert.Equal(t, step.state, got.State, step.action)
+			assert.Equal(t, step.state, members.Members()[0].State, step.action)
+		}
+		members.(balancer.ConnAware).Decrease("http://a:80")
+		assert.Len(t, members.Members(), 2, "enable cancels removal after drain")
+
+		// drain означает то же, что DELETE /servers?drain=true: сервер без запросов удаляется сразу
+		code, _ := do(http.MethodPost, "/admin/backends/a:80/drain")
+		assert.Equal(t, http.StatusNoContent, code)
+		assert.Len(t, members.Members(), 1)
+		require.NoError(t, members.Add("http://a:80", 1))
+
+		code, _ = do(http.MethodGet, "/admin/backends/a:80/drain")
+		assert.Equal(t, http.StatusMethodNotAllowed, code)
+		code, _ = do(http.MethodPost, "/admin/backends/a:80/reboot")
+		assert.Equal(t, http.StatusNotFound, code)
+		code, _ = do(http.MethodPost, "/admin/backends/x:80/drain")
+		assert.Equal(t, http.StatusNotFound, code)
+	})
+
+	t.Run("Unsupported", func(t *testing.T) {
+		mux := http.NewServeMux()
+		RegisterAdmin(mux, Backends{Servers: []string{"http://a:80"}}, logger.Nop())
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/backends", nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"state":"enabled"`)
+
+		rec = httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/admin/backends/a:80/disable", nil))
+		assert.Equal(t, http.StatusNotImplemented, rec.Code)
+	})
+}
+
+// TestAPILogLevel — тестирование изменения уровня журнала во время работы.
+func TestAPILogLevel(t *testing.T) {
+	level := new(slog.LevelVar)
+	mux := http.NewServeMux()
+	RegisterLogLevel(mux, level, logger.Nop())
+
+	do := func(method, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(method, "/admin/log-level", strings.NewReader(body)))
+		return rec
+	}
+
+	rec := do(http.MethodGet, "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"level":"info"}`, rec.Body.String())
+
+	rec = do(http.MethodPut, `{"level":"debug"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"level":"debug"}`, rec.Body.String())
+	assert.Equal(t, slog.LevelDebug, level.Level())
+
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPut, `{"level":"loud"}`).Code)
+	assert.Equal(t, slog.LevelDebug, level.Level())
+	assert.Equal(t, http.StatusMethodNotAllowed, do(http.MethodPost, "").Code)
+}
+
 // BenchmarkAPIAllow — бенчмаркинг метода Allow.
 func BenchmarkAPIAllow(b *testing.B) {
 	ts, mgr := setupAPI()
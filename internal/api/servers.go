@@ -0,0 +1,123 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/coffee-realist/balancer/internal/balancer"
+	"github.com/coffee-realist/balancer/internal/logger"
+	"github.com/coffee-realist/balancer/internal/requestid"
+)
+
+// serverRequest — тело запросов на добавление сервера и изменение веса.
+type serverRequest struct {
+	Server string `json:"server"`
+	Weight int    `json:"weight"`
+}
+
+// registerServers регистрирует управление составом бэкендов:
+// - GET /servers — список серверов с весами и числом запросов в обработке,
+// - POST /servers — добавление сервера ({"server": "...", "weight": 1}),
+// - PUT /servers?server=<url> — изменение веса ({"weight": 2}),
+// - DELETE /servers?server=<url> — удаление; с drain=true сервер перестаёт выбираться
+// и удаляется после завершения начатых запросов.
+func registerServers(mux *http.ServeMux, members balancer.Membership, log logger.Logger) {
+	mux.HandleFunc("/servers", func(w http.ResponseWriter, r *http.Request) {
+		if members == nil {
+			requestid.Error(w, r, "membership not supported by algorithm", http.StatusNotImplemented)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			// Отправляем текущий состав в формате JSON
+			w.Header().Set("Content-Type", "application/json")
+			if err := json.NewEncoder(w).Encode(members.Members()); err != nil {
+				log.Errorf("encode servers error: %v", err)
+			}
+
+		case http.MethodPost:
+			var req serverRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				requestid.Error(w, r, "invalid body", http.StatusBadRequest)
+				return
+			}
+			if !validServer(req.Server) {
+				requestid.Error(w, r, "invalid server", http.StatusBadRequest)
+				return
+			}
+			if err := members.Add(req.Server, req.Weight); err != nil {
+				writeMembershipError(w, r, err)
+				return
+			}
+			log.Infof("server %s added with weight %d", req.Server, req.Weight)
+			w.WriteHeader(http.StatusCreated)
+
+		case http.MethodPut:
+			server := r.URL.Query().Get("server")
+			if server == "" {
+				requestid.Error(w, r, "missing server", http.StatusBadRequest)
+				return
+			}
+			var req serverRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				requestid.Error(w, r, "invalid body", http.StatusBadRequest)
+				return
+			}
+			if err := members.SetWeight(server, req.Weight); err != nil {
+				writeMembershipError(w, r, err)
+				return
+			}
+			log.Infof("server %s weight set to %d", server, req.Weight)
+			w.WriteHeader(http.StatusNoContent)
+
+		case http.MethodDelete:
+			server := r.URL.Query().Get("server")
+			if server == "" {
+				requestid.Error(w, r, "missing server", http.StatusBadRequest)
+				return
+			}
+			if r.URL.Query().Get("drain") == "true" {
+				if err := members.Drain(server); err != nil {
+					writeMembershipError(w, r, err)
+					return
+				}
+				log.Infof("server %s draining", server)
+				w.WriteHeader(http.StatusAccepted) // Удаление завершится после начатых запросов
+				return
+			}
+			if err := members.Remove(server); err != nil {
+				writeMembershipError(w, r, err)
+				return
+			}
+			log.Infof("server %s removed", server)
+			w.WriteHeader(http.StatusNoContent)
+
+		default:
+			requestid.Error(w, r, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+// validServer проверяет, что адрес сервера — абсолютный URL со схемой и хостом.
+func validServer(server string) bool {
+	u, err := url.Parse(server)
+	return err == nil && u.Scheme != "" && u.Host != ""
+}
+
+// writeMembershipError отвечает кодом, соответствующим ошибке изменения состава.
+func writeMembershipError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, balancer.ErrUnknownServer):
+		requestid.Error(w, r, "server not found", http.StatusNotFound)
+	case errors.Is(err, balancer.ErrServerExists):
+		requestid.Error(w, r, "server already exists", http.StatusConflict)
+	case errors.Is(err, balancer.ErrInvalidWeight):
+		requestid.Error(w, r, fmt.Sprintf("weight must be between 1 and %d", balancer.MaxWeight), http.StatusBadRequest)
+	default:
+		requestid.Error(w, r, "internal error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,219 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/coffee-realist/balancer/internal/logger"
+	"github.com/coffee-realist/balancer/internal/requestid"
+)
+
+// Role — уровень доступа к управляющему API.
+type Role string
+
+const (
+	RoleRead  Role = "read"  // Только чтение: GET и HEAD
+	RoleWrite Role = "write" // Чтение и изменение
+)
+
+// Allows сообщает, разрешён ли роли HTTP-метод.
+func (r Role) Allows(method string) bool {
+	switch r {
+	case RoleWrite:
+		return true
+	case RoleRead:
+		return method == http.MethodGet || method == http.MethodHead
+	}
+	return false
+}
+
+// ParseRole разбирает название роли; пустая строка означает RoleRead.
+func ParseRole(s string) (Role, error) {
+	switch Role(strings.TrimSpace(s)) {
+	case "", RoleRead:
+		return RoleRead, nil
+	case RoleWrite:
+		return RoleWrite, nil
+	}
+	return "", fmt.Errorf("unknown role %q", s)
+}
+
+// Authenticator определяет роль автора запроса. ok == false означает,
+// что запрос не содержит подходящих этому способу учётных данных.
+type Authenticator interface {
+	Authenticate(r *http.Request) (role Role, ok bool)
+}
+
+// Chain проверяет запрос способами по очереди до первого успешного.
+type Chain []Authenticator
+
+// Authenticate возвращает роль от первого способа, опознавшего запрос.
+func (c Chain) Authenticate(r *http.Request) (Role, bool) {
+	for _, a := range c {
+		if role, ok := a.Authenticate(r); ok {
+			return role, true
+		}
+	}
+	return "", false
+}
+
+// bearerToken извлекает токен из заголовка Authorization: Bearer <token>.
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// StaticTokens — статические bearer-токены. Хранятся SHA-256 токенов, поэтому
+// поиск не зависит от совпадающего префикса и токены не лежат в памяти открыто.
+type StaticTokens map[[sha256.Size]byte]Role
+
+// LoadTokenFile читает токены из файла: по одному на строку в виде "<токен> [read|write]".
+// Пустые строки и строки, начинающиеся с #, пропускаются; роль по умолчанию — read.
+func LoadTokenFile(path string) (StaticTokens, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	tokens := StaticTokens{}
+	sc := bufio.NewScanner(f)
+	for line := 1; sc.Scan(); line++ {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) > 2 {
+			return nil, fmt.Errorf("%s:%d: expected \"<token> [role]\"", path, line)
+		}
+		role := RoleRead
+		if len(fields) == 2 {
+			if role, err = ParseRole(fields[1]); err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+			}
+		}
+		tokens[sha256.Sum256([]byte(fields[0]))] = role
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// Authenticate ищет bearer-токен среди статических.
+func (t StaticTokens) Authenticate(r *http.Request) (Role, bool) {
+	token, ok := bearerToken(r)
+	if !ok {
+		return "", false
+	}
+	role, ok := t[sha256.Sum256([]byte(token))]
+	return role, ok
+}
+
+// HMACTokens — подписанные токены со сроком действия вида "<роль>.<unix-время истечения>.<hex HMAC-SHA256>",
+// где подпись вычисляется от "<роль>.<unix-время истечения>" общим секретом.
+type HMACTokens struct {
+	Secret []byte
+	now    func() time.Time // Подменяется в тестах
+}
+
+// SignToken выпускает подписанный токен для роли, действующий до exp.
+func SignToken(secret []byte, role Role, exp time.Time) string {
+	payload := string(role) + "." + strconv.FormatInt(exp.Unix(), 10)
+	return payload + "." + hex.EncodeToString(sign(secret, payload))
+}
+
+// sign вычисляет HMAC-SHA256 от payload.
+func sign(secret []byte, payload string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+// Authenticate проверяет подпись и срок действия bearer-токена.
+func (h HMACTokens) Authenticate(r *http.Request) (Role, bool) {
+	token, ok := bearerToken(r)
+	if !ok {
+		return "", false
+	}
+	payload, sigHex, ok := cutLast(token, ".")
+	if !ok {
+		return "", false
+	}
+	sig, err := hex.DecodeString(sigHex)
+	if err != nil || !hmac.Equal(sig, sign(h.Secret, payload)) {
+		return "", false
+	}
+	roleName, expStr, _ := strings.Cut(payload, ".")
+	exp, err := strconv.ParseInt(expStr, 10, 64)
+	if err != nil {
+		return "", false
+	}
+	now := time.Now
+	if h.now != nil {
+		now = h.now
+	}
+	if now().Unix() >= exp {
+		return "", false
+	}
+	role, err := ParseRole(roleName)
+	if err != nil || roleName == "" {
+		return "", false
+	}
+	return role, true
+}
+
+// cutLast делит s по последнему вхождению sep.
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
+
+// ClientCerts опознаёт клиента по сертификату, проверенному при TLS-рукопожатии
+// (mTLS): роль определяется по Common Name сертификата.
+type ClientCerts map[string]Role
+
+// Authenticate ищет роль по CN проверенного клиентского сертификата.
+func (c ClientCerts) Authenticate(r *http.Request) (Role, bool) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return "", false
+	}
+	role, ok := c[r.TLS.VerifiedChains[0][0].Subject.CommonName]
+	return role, ok
+}
+
+// Middleware пропускает запрос, только если автор опознан и его роли разрешён метод.
+// Ошибки возвращаются в формате {"error":"...","request_id":"..."}: 401 для неопознанных,
+// 403 для запросов на изменение с ролью read.
+func Middleware(next http.Handler, authn Authenticator, log logger.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log := logger.FromContext(r.Context(), log)
+		role, ok := authn.Authenticate(r)
+		if !ok {
+			log.Errorf("admin auth failed: %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			requestid.Error(w, r, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if !role.Allows(r.Method) {
+			log.Errorf("admin access denied: %s %s for role %s", r.Method, r.URL.Path, role)
+			requestid.Error(w, r, "forbidden", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,120 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/coffee-realist/balancer/internal/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// bearer создаёт запрос с bearer-токеном.
+func bearer(method, token string) *http.Request {
+	r := httptest.NewRequest(method, "/clients", nil)
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	return r
+}
+
+// TestLoadTokenFile проверяет разбор файла токенов и поиск по токену.
+func TestLoadTokenFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens")
+	require.NoError(t, os.WriteFile(path, []byte("# dashboards\ndash-token read\n\nops-token write\nplain\n"), 0o600))
+
+	tokens, err := LoadTokenFile(path)
+	require.NoError(t, err)
+	for token, want := range map[string]Role{"dash-token": RoleRead, "ops-token": RoleWrite, "plain": RoleRead} {
+		role, ok := tokens.Authenticate(bearer(http.MethodGet, token))
+		assert.True(t, ok, token)
+		assert.Equal(t, want, role, token)
+	}
+	_, ok := tokens.Authenticate(bearer(http.MethodGet, "unknown"))
+	assert.False(t, ok)
+	_, ok = tokens.Authenticate(bearer(http.MethodGet, ""))
+	assert.False(t, ok)
+
+	require.NoError(t, os.WriteFile(path, []byte("token admin\n"), 0o600))
+	_, err = LoadTokenFile(path)
+	assert.ErrorContains(t, err, ":1:")
+}
+
+// TestHMACTokens проверяет подпись, срок действия и подмену роли.
+func TestHMACTokens(t *testing.T) {
+	now := time.Unix(1_000_000, 0)
+	h := HMACTokens{Secret: []byte("secret"), now: func() time.Time { return now }}
+
+	token := SignToken(h.Secret, RoleWrite, now.Add(time.Hour))
+	role, ok := h.Authenticate(bearer(http.MethodPost, token))
+	assert.True(t, ok)
+	assert.Equal(t, RoleWrite, role)
+
+	_, ok = h.Authenticate(bearer(http.MethodGet, SignToken(h.Secret, RoleRead, now)))
+	assert.False(t, ok, "expired")
+	_, ok = h.Authenticate(bearer(http.MethodGet, SignToken([]byte("other"), RoleRead, now.Add(time.Hour))))
+	assert.False(t, ok, "wrong secret")
+	_, ok = h.Authenticate(bearer(http.MethodGet, "read"+token[len("write"):]))
+	assert.False(t, ok, "tampered role")
+	_, ok = h.Authenticate(bearer(http.MethodGet, "garbage"))
+	assert.False(t, ok)
+}
+
+// TestClientCerts проверяет определение роли по CN проверенного сертификата.
+func TestClientCerts(t *testing.T) {
+	certs := ClientCerts{"dashboard": RoleRead}
+	withCert := func(cn string) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/clients", nil)
+		r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: cn}}}}}
+		return r
+	}
+
+	role, ok := certs.Authenticate(withCert("dashboard"))
+	assert.True(t, ok)
+	assert.Equal(t, RoleRead, role)
+	_, ok = certs.Authenticate(withCert("stranger"))
+	assert.False(t, ok)
+	_, ok = certs.Authenticate(httptest.NewRequest(http.MethodGet, "/clients", nil))
+	assert.False(t, ok, "plain HTTP")
+}
+
+// TestMiddleware проверяет коды и тела ответов для ролей read и write.
+func TestMiddleware(t *testing.T) {
+	tokens := StaticTokens{
+		sha256.Sum256([]byte("ro")): RoleRead,
+		sha256.Sum256([]byte("rw")): RoleWrite,
+	}
+	authn := Chain{tokens, HMACTokens{Secret: []byte("secret")}}
+	h := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}), authn, logger.Nop())
+
+	serve := func(r *http.Request) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		return rec
+	}
+
+	rec := serve(bearer(http.MethodGet, ""))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.JSONEq(t, `{"error":"unauthorized"}`, rec.Body.String())
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.NotEmpty(t, rec.Header().Get("WWW-Authenticate"))
+
+	assert.Equal(t, http.StatusNoContent, serve(bearer(http.MethodGet, "ro")).Code)
+	rec = serve(bearer(http.MethodDelete, "ro"))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.JSONEq(t, `{"error":"forbidden"}`, rec.Body.String())
+	assert.Equal(t, http.StatusNoContent, serve(bearer(http.MethodDelete, "rw")).Code)
+
+	signed := SignToken([]byte("secret"), RoleWrite, time.Now().Add(time.Minute))
+	assert.Equal(t, http.StatusNoContent, serve(bearer(http.MethodPost, signed)).Code)
+}
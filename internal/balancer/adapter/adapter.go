@@ -5,9 +5,21 @@ import (
 	"github.com/coffee-realist/balancer/internal/balancer/least_conn"
 	"github.com/coffee-realist/balancer/internal/balancer/p2c"
 	"github.com/coffee-realist/balancer/internal/balancer/round_robin"
+	"net/http"
 	"sync/atomic"
+	"time"
 )
 
+// Режимы AdaptiveBalancer по возрастанию нагрузки.
+const (
+	ModeRR  = "rr"
+	ModeP2C = "p2c"
+	ModeLC  = "lc"
+)
+
+// modes — режимы в порядке индексов счётчика picks.
+var modes = [...]string{ModeRR, ModeP2C, ModeLC}
+
 // AdaptiveBalancer выбирает стратегию по нагрузке
 // и делегирует ConnAware-события на вложенные реализации.
 type AdaptiveBalancer struct {
@@ -18,6 +30,7 @@ type AdaptiveBalancer struct {
 	lowThresh  int64
 	highThresh int64
 	active     int64
+	picks      [len(modes)]atomic.Uint64 // Успешные выборы по режимам
 }
 
 func NewAdaptiveBalancer(
@@ -43,38 +56,152 @@ func NewAdaptiveBalancer(
 
 // Next выбирает стратегию и увеличивает общий счётчик active.
 func (a *AdaptiveBalancer) Next() string {
+	srv, _ := a.Pick(nil)
+	return srv
+}
+
+// Pick выбирает стратегию по нагрузке и делегирует ей запрос,
+// сохраняя типизированную ошибку вложенной стратегии.
+func (a *AdaptiveBalancer) Pick(r *http.Request) (string, error) {
 	cur := atomic.LoadInt64(&a.active)
-	var srv string
+	var (
+		b    balancer.Balancer
+		mode int
+	)
 
 	switch {
 	case cur < a.lowThresh:
-		srv = a.rr.Next()
+		b, mode = a.rr, 0
 	case cur < a.highThresh:
-		srv = a.p2c.Next()
+		b, mode = a.p2c, 1
 	default:
-		srv = a.lc.Next()
+		b, mode = a.lc, 2
 	}
+	srv, err := balancer.AsRequestBalancer(b).Pick(r)
+	if err != nil {
+		return "", err
+	}
+	a.picks[mode].Add(1)
 
-	// общий счётчик in-flight
+	// общий счётчик in-flight, уменьшается в Done
 	atomic.AddInt64(&a.active, 1)
-	return srv
+	return srv, nil
 }
 
-// Done нужно вызывать, когда запрос завершён: уменьшаем active.
-func (a *AdaptiveBalancer) Done() {
+// Done вызывается прокси, когда запрос к серверу завершён: уменьшает active
+// и передаёт результат вложенным стратегиям, которые его учитывают.
+func (a *AdaptiveBalancer) Done(server string, latency time.Duration, status int, err error) {
 	atomic.AddInt64(&a.active, -1)
+	for _, b := range []balancer.Balancer{a.rr, a.lc, a.p2c} {
+		if fa, ok := b.(balancer.FeedbackAware); ok {
+			fa.Done(server, latency, status, err)
+		}
+	}
+}
+
+// Release уменьшает active для сервера, выбранного через Pick, но не использованного.
+func (a *AdaptiveBalancer) Release(string) {
+	atomic.AddInt64(&a.active, -1)
+}
+
+// SetHealth передаёт внешний источник здоровья вложенным стратегиям, которые его поддерживают.
+func (a *AdaptiveBalancer) SetHealth(h balancer.Health) {
+	for _, b := range []balancer.Balancer{a.rr, a.lc, a.p2c} {
+		if ha, ok := b.(balancer.HealthAware); ok {
+			ha.SetHealth(h)
+		}
+	}
+}
+
+// Active возвращает число запросов, выбранных через Pick и ещё не завершённых.
+func (a *AdaptiveBalancer) Active() int64 {
+	return atomic.LoadInt64(&a.active)
+}
+
+// ModePicks возвращает число выборов сервера в каждом режиме с момента запуска.
+func (a *AdaptiveBalancer) ModePicks() map[string]uint64 {
+	picks := make(map[string]uint64, len(modes))
+	for i, m := range modes {
+		picks[m] = a.picks[i].Load()
+	}
+	return picks
 }
 
 // Increase делегирует только тем стратегиям, которые это поддерживают.
 func (a *AdaptiveBalancer) Increase(server string) {
 	a.p2c.Increase(server)
 	a.lc.Increase(server)
+	// RR учитывает соединения только для вывода серверов из ротации
+	if ca, ok := a.rr.(balancer.ConnAware); ok {
+		ca.Increase(server)
+	}
 }
 
 // Decrease аналогично
 func (a *AdaptiveBalancer) Decrease(server string) {
 	a.p2c.Decrease(server)
 	a.lc.Decrease(server)
+	if ca, ok := a.rr.(balancer.ConnAware); ok {
+		ca.Decrease(server)
+	}
+}
+
+// members возвращает вложенные стратегии, поддерживающие изменение состава.
+func (a *AdaptiveBalancer) members() []balancer.Membership {
+	var ms []balancer.Membership
+	for _, b := range []balancer.Balancer{a.rr, a.lc, a.p2c} {
+		if m, ok := b.(balancer.Membership); ok {
+			ms = append(ms, m)
+		}
+	}
+	return ms
+}
+
+// Add добавляет сервер во все вложенные стратегии.
+func (a *AdaptiveBalancer) Add(server string, weight int) error {
+	return a.each(func(m balancer.Membership) error { return m.Add(server, weight) })
+}
+
+// Remove удаляет сервер из всех вложенных стратегий.
+func (a *AdaptiveBalancer) Remove(server string) error {
+	return a.each(func(m balancer.Membership) error { return m.Remove(server) })
+}
+
+// SetWeight меняет вес сервера во всех вложенных стратегиях.
+func (a *AdaptiveBalancer) SetWeight(server string, weight int) error {
+	return a.each(func(m balancer.Membership) error { return m.SetWeight(server, weight) })
+}
+
+// Drain выводит сервер из ротации во всех вложенных стратегиях. Каждая стратегия
+// удаляет его сама, когда завершатся начатые запросы (Increase/Decrease получают все).
+func (a *AdaptiveBalancer) Drain(server string) error {
+	return a.each(func(m balancer.Membership) error { return m.Drain(server) })
+}
+
+// SetState меняет административное состояние сервера во всех вложенных стратегиях.
+func (a *AdaptiveBalancer) SetState(server string, state balancer.AdminState) error {
+	return a.each(func(m balancer.Membership) error { return m.SetState(server, state) })
+}
+
+// Members возвращает состав первой вложенной стратегии, поддерживающей Membership:
+// изменения применяются ко всем одинаково.
+func (a *AdaptiveBalancer) Members() []balancer.MemberInfo {
+	if ms := a.members(); len(ms) > 0 {
+		return ms[0].Members()
+	}
+	return nil
+}
+
+// each применяет изменение ко всем вложенным стратегиям и возвращает первую ошибку.
+// Стратегии без поддержки Membership пропускаются.
+func (a *AdaptiveBalancer) each(fn func(balancer.Membership) error) error {
+	var first error
+	for _, m := range a.members() {
+		if err := fn(m); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
 }
 
 // Stop завершает фоновые горутины
@@ -1,9 +1,16 @@
 package adapter
 
 import (
+	"errors"
+	"net/http"
 	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/coffee-realist/balancer/internal/balancer"
+	"github.com/coffee-realist/balancer/internal/balancer/least_conn"
+	"github.com/coffee-realist/balancer/internal/balancer/p2c"
+	"github.com/coffee-realist/balancer/internal/balancer/round_robin"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -14,14 +21,18 @@ func (s *stubRR) Next() string { return s.srv }
 
 // stubLC — фиктивная реализация для LeastConnBalancer, поддерживающая операции Increase и Decrease.
 type stubLC struct {
-	srv string
-	cnt int64
+	srv  string
+	cnt  int64
+	done int64
 }
 
 func (s *stubLC) Next() string      { return s.srv }
 func (s *stubLC) Increase(_ string) { atomic.AddInt64(&s.cnt, 1) }
 func (s *stubLC) Decrease(_ string) { atomic.AddInt64(&s.cnt, -1) }
 func (s *stubLC) Stop()             {}
+func (s *stubLC) Done(string, time.Duration, int, error) {
+	atomic.AddInt64(&s.done, 1)
+}
 
 type stubP2C = stubLC // Используется как псевдоним для stubLC.
 
@@ -40,7 +51,7 @@ func TestAdaptiveBalancer_SwitchingAndConnAware(t *testing.T) {
 	ab.Decrease(srv)
 	assert.Equal(t, int64(0), atomic.LoadInt64(&lc.cnt))
 	assert.Equal(t, int64(0), atomic.LoadInt64(&p2c.cnt))
-	ab.Done()
+	ab.Done(srv, 0, http.StatusOK, nil)
 
 	// Тест 2: средняя нагрузка → использует P2CBalancer (p2c)
 	atomic.StoreInt64(&ab.active, 6)
@@ -49,7 +60,7 @@ func TestAdaptiveBalancer_SwitchingAndConnAware(t *testing.T) {
 	ab.Increase(srv)
 	assert.Equal(t, int64(1), atomic.LoadInt64(&p2c.cnt))
 	ab.Decrease(srv)
-	ab.Done()
+	ab.Done(srv, 0, http.StatusOK, nil)
 
 	// Тест 3: высокая нагрузка → использует LeastConnBalancer (lc)
 	atomic.StoreInt64(&ab.active, 12)
@@ -58,7 +69,51 @@ func TestAdaptiveBalancer_SwitchingAndConnAware(t *testing.T) {
 	ab.Increase(srv)
 	assert.Equal(t, int64(1), atomic.LoadInt64(&lc.cnt))
 	ab.Decrease(srv)
-	ab.Done()
+	ab.Done(srv, 0, http.StatusOK, nil)
+
+	// Каждый режим выбран по одному разу
+	assert.Equal(t, map[string]uint64{ModeRR: 1, ModeP2C: 1, ModeLC: 1}, ab.ModePicks())
+}
+
+// TestAdaptiveBalancer_DoneReleasesActive проверяет, что Done уменьшает active
+// и передаёт результат вложенным FeedbackAware-стратегиям.
+func TestAdaptiveBalancer_DoneReleasesActive(t *testing.T) {
+	rr := &stubRR{srv: "rr"}
+	lc := &stubLC{srv: "lc"}
+	p2c := &stubP2C{srv: "p2c"}
+	ab := NewAdaptiveBalancer(rr, lc, p2c, 2, 4)
+
+	// Последовательные завершённые запросы не накапливают нагрузку
+	for i := 0; i < 10; i++ {
+		srv := ab.Next()
+		assert.Equal(t, "rr", srv)
+		ab.Done(srv, time.Millisecond, http.StatusOK, nil)
+	}
+	assert.Equal(t, int64(0), ab.Active())
+	assert.Equal(t, int64(10), atomic.LoadInt64(&lc.done))
+	assert.Equal(t, int64(10), atomic.LoadInt64(&p2c.done))
+
+	// Одновременные запросы переключают режимы и освобождаются через Done
+	var picked []string
+	for i := 0; i < 5; i++ {
+		picked = append(picked, ab.Next())
+	}
+	assert.Equal(t, []string{"rr", "rr", "p2c", "p2c", "lc"}, picked)
+	for _, srv := range picked {
+		ab.Done(srv, time.Millisecond, 0, errors.New("reset"))
+	}
+	assert.Equal(t, int64(0), ab.Active())
+
+	// Невостребованный выбор освобождается через Release
+	srv := ab.Next()
+	ab.Release(srv)
+	assert.Equal(t, int64(0), ab.Active())
+
+	// Ошибка выбора не увеличивает active
+	empty := NewAdaptiveBalancer(&stubRR{}, lc, p2c, 2, 4)
+	_, err := empty.Pick(nil)
+	assert.Error(t, err)
+	assert.Equal(t, int64(0), empty.Active())
 }
 
 // BenchmarkAdaptiveBalancer_Next проверяет производительность метода Next при высокой нагрузке.
@@ -76,3 +131,40 @@ func BenchmarkAdaptiveBalancer_Next(b *testing.B) {
 		_ = ab.Next()
 	}
 }
+
+// TestAdaptiveBalancer_Membership проверяет, что изменения состава применяются ко всем стратегиям
+// и выводимый сервер удаляется после завершения начатых запросов.
+func TestAdaptiveBalancer_Membership(t *testing.T) {
+	servers := []string{"A"}
+	ab := NewAdaptiveBalancer(
+		round_robin.NewRoundRobinBalancer(servers),
+		least_conn.NewLeastConnBalancer(servers),
+		p2c.NewP2CBalancer(servers),
+		1, 2,
+	)
+
+	assert.NoError(t, ab.Add("B", 1))
+	assert.ErrorIs(t, ab.Add("B", 1), balancer.ErrServerExists)
+	for _, b := range []balancer.Balancer{ab.rr, ab.lc, ab.p2c} {
+		assert.Len(t, b.(balancer.Membership).Members(), 2)
+	}
+
+	ab.Increase("B")
+	assert.NoError(t, ab.Drain("B"))
+	for _, load := range []int64{0, 1, 2} { // RR, P2C, LC
+		atomic.StoreInt64(&ab.active, load)
+		srv, err := ab.Pick(nil)
+		assert.NoError(t, err)
+		assert.Equal(t, "A", srv)
+	}
+	assert.Equal(t, []balancer.MemberInfo{
+		{Server: "A", Weight: 1, State: balancer.StateEnabled},
+		{Server: "B", Weight: 1, State: balancer.StateDraining, Removing: true, Active: 1},
+	}, ab.Members())
+
+	ab.Decrease("B")
+	for _, b := range []balancer.Balancer{ab.rr, ab.lc, ab.p2c} {
+		assert.Len(t, b.(balancer.Membership).Members(), 1)
+	}
+	assert.ErrorIs(t, ab.Remove("B"), balancer.ErrUnknownServer)
+}
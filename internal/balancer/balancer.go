@@ -1,10 +1,102 @@
 package balancer
 
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// ErrUnknownServer возвращается, когда операция адресована серверу, которого нет в стратегии.
+	ErrUnknownServer = errors.New("unknown server")
+	// ErrNoBackends возвращается, когда в стратегии нет ни одного сервера.
+	ErrNoBackends = errors.New("no backends configured")
+	// ErrNoHealthyBackends возвращается, когда серверы есть, но все они недоступны.
+	ErrNoHealthyBackends = errors.New("no healthy backends")
+	// ErrServerExists возвращается при добавлении сервера, который уже есть в стратегии.
+	ErrServerExists = errors.New("server already exists")
+	// ErrInvalidWeight возвращается при попытке задать вес вне диапазона от 1 до MaxWeight.
+	ErrInvalidWeight = errors.New("weight out of range")
+	// ErrInvalidState возвращается при попытке задать неизвестное административное состояние.
+	ErrInvalidState = errors.New("invalid server state")
+	// ErrNoAlternateBackend возвращается, когда нет сервера, кроме уже используемых для запроса.
+	ErrNoAlternateBackend = errors.New("no alternate backend")
+)
+
+// MaxWeight — наибольший допустимый вес сервера. Порядок обхода Pool хранит по вхождению
+// на единицу веса, поэтому вес ограничен.
+const MaxWeight = 1000
+
 // Balancer — минимальный интерфейс, возвращает следующий сервер.
 type Balancer interface {
 	Next() string
 }
 
+// RequestBalancer — выбор сервера с учётом запроса.
+// В отличие от Balancer, причина отказа возвращается типизированной ошибкой.
+type RequestBalancer interface {
+	// Pick возвращает сервер для запроса или ErrNoBackends / ErrNoHealthyBackends.
+	Pick(r *http.Request) (string, error)
+}
+
+// RequestAware — для стратегий, которым для выбора сервера нужен сам запрос
+// (хеширование по ключу, sticky-сессии).
+type RequestAware interface {
+	// NextFor возвращает сервер для конкретного запроса.
+	NextFor(r *http.Request) string
+}
+
+// Ranker — для стратегий, способных вернуть упорядоченный список запасных серверов,
+// чтобы прокси мог перейти ко второму выбору, если первый не ответил.
+type Ranker interface {
+	// Rank возвращает серверы для запроса в порядке предпочтения.
+	Rank(r *http.Request) ([]string, error)
+}
+
+// FeedbackAware — для стратегий, учитывающих результат запросов к бэкендам
+// (пассивный health, оценка задержек, доля ошибок).
+// Прокси вызывает Done после каждого обращения к бэкенду, в дополнение к ConnAware.Decrease.
+type FeedbackAware interface {
+	// Done сообщает время ответа сервера, HTTP-статус (0, если ответа не было)
+	// и ошибку транспорта (nil при успехе). Нулевой статус без ошибки означает попытку,
+	// отменённую со стороны клиента (клиент ушёл или ответил другой хеджированный запрос):
+	// о сервере она ничего не говорит.
+	Done(server string, latency time.Duration, status int, err error)
+}
+
+// Excluder — для стратегий, умеющих выбрать сервер, отличный от уже используемых для запроса
+// (повтор или хеджированный запрос к другому бэкенду).
+type Excluder interface {
+	PickExcluding(r *http.Request, exclude []string) (string, error)
+}
+
+// Releaser — для стратегий, резервирующих ресурсы при выборе сервера до вызова Done.
+// Прокси вызывает Release, если выбранный сервер не будет использован
+// (например, повторная попытка выпала на тот же сервер).
+type Releaser interface {
+	Release(server string)
+}
+
+// Health — внешний источник сведений о доступности бэкендов
+// (пассивная проверка по трафику, активные health checks).
+type Health interface {
+	// Healthy сообщает, можно ли отправлять запросы на сервер.
+	Healthy(server string) bool
+}
+
+// Versioned — для источников Health, сообщающих об изменениях через номер версии.
+// Стратегии с предвычисленными структурами (таблица Maglev) перестраивают их при смене версии.
+type Versioned interface {
+	Version() uint64
+}
+
+// HealthAware — для стратегий, умеющих пропускать серверы, недоступные по внешнему источнику.
+type HealthAware interface {
+	SetHealth(h Health)
+}
+
 // ConnAware — для стратегий, которым нужно отслеживать подключения.
 type ConnAware interface {
 	// Increase увеличивает счётчик активных соединений на сервере.
@@ -13,7 +105,172 @@ type ConnAware interface {
 	Decrease(server string)
 }
 
+// Membership — для стратегий, состав серверов которых меняется во время работы.
+type Membership interface {
+	// Add добавляет сервер; нулевой вес означает 1. Возвращает ErrServerExists или ErrInvalidWeight.
+	Add(server string, weight int) error
+	// Remove сразу удаляет сервер; уже начатые запросы к нему завершаются как обычно.
+	Remove(server string) error
+	// SetWeight меняет вес сервера.
+	SetWeight(server string, weight int) error
+	// Drain прекращает выбор сервера и удаляет его, когда завершатся начатые запросы (см. ConnAware).
+	Drain(server string) error
+	// SetState включает сервер (StateEnabled, в том числе отменяя Drain) или выводит его
+	// из ротации без удаления (StateDisabled). StateDraining задаётся только через Drain,
+	// для него возвращается ErrInvalidState.
+	SetState(server string, state AdminState) error
+	// Members возвращает текущий состав серверов.
+	Members() []MemberInfo
+}
+
+// AdminState — административное состояние сервера, заданное оператором.
+type AdminState string
+
+const (
+	StateEnabled  AdminState = "enabled"  // Сервер участвует в выборе
+	StateDraining AdminState = "draining" // Новые запросы не направляются, сервер удаляется после начатых (см. Drain)
+	StateDisabled AdminState = "disabled" // Сервер выведен из ротации на обслуживание
+)
+
+// MemberInfo описывает сервер в составе стратегии.
+type MemberInfo struct {
+	Server   string     `json:"server"`
+	Weight   int        `json:"weight"`
+	State    AdminState `json:"state"`
+	Removing bool       `json:"removing,omitempty"` // Будет удалён после завершения начатых запросов
+	Active   int64      `json:"active"`             // Запросы в обработке
+}
+
 // Stoppable — для стратегий, у которых есть фоновые горутины (health-checks, refill).
 type Stoppable interface {
 	Stop()
 }
+
+// AsRequestBalancer возвращает RequestBalancer для любой стратегии.
+// Стратегии, реализующие Pick, используются напрямую, остальные оборачиваются:
+// NextFor (или Next) вызывается как раньше, а пустая строка превращается в ErrNoBackends.
+func AsRequestBalancer(b Balancer) RequestBalancer {
+	if rb, ok := b.(RequestBalancer); ok {
+		return rb
+	}
+	return &requestShim{b: b}
+}
+
+// maxRepicks ограничивает число повторных выборов в PickExcluding для стратегий без исключения.
+const maxRepicks = 3
+
+// PickExcluding выбирает для запроса сервер не из exclude. Стратегии-Excluder выбирают сами,
+// у Ranker берётся первый подходящий сервер списка, остальные стратегии опрашиваются
+// повторно; невостребованный выбор освобождается через Releaser.
+func PickExcluding(b Balancer, r *http.Request, exclude []string) (string, error) {
+	if ex, ok := b.(Excluder); ok {
+		return ex.PickExcluding(r, exclude)
+	}
+	if rk, ok := b.(Ranker); ok {
+		servers, err := rk.Rank(r)
+		if err != nil {
+			return "", err
+		}
+		for _, s := range servers {
+			if !slices.Contains(exclude, s) {
+				return s, nil
+			}
+		}
+		return "", ErrNoAlternateBackend
+	}
+
+	rb := AsRequestBalancer(b)
+	for range maxRepicks {
+		s, err := rb.Pick(r)
+		if err != nil {
+			return "", err
+		}
+		if !slices.Contains(exclude, s) {
+			return s, nil
+		}
+		if rl, ok := b.(Releaser); ok {
+			rl.Release(s)
+		}
+	}
+	return "", ErrNoAlternateBackend
+}
+
+// requestShim адаптирует Next-стратегии к интерфейсу RequestBalancer.
+type requestShim struct {
+	b Balancer
+}
+
+// Pick делегирует выбор исходной стратегии.
+func (s *requestShim) Pick(r *http.Request) (string, error) {
+	var srv string
+	if ra, ok := s.b.(RequestAware); ok && r != nil {
+		srv = ra.NextFor(r)
+	} else {
+		srv = s.b.Next()
+	}
+	if srv == "" {
+		return "", ErrNoBackends
+	}
+	return srv, nil
+}
+
+// AllHealthy объединяет несколько источников: сервер здоров, только если здоров во всех.
+// Версия объединения — сумма версий источников, поэтому меняется при изменении любого из них.
+func AllHealthy(sources ...Health) Health {
+	return allHealthy(sources)
+}
+
+// allHealthy — объединение источников здоровья.
+type allHealthy []Health
+
+// Healthy сообщает, что сервер здоров во всех источниках.
+func (a allHealthy) Healthy(server string) bool {
+	for _, h := range a {
+		if !h.Healthy(server) {
+			return false
+		}
+	}
+	return true
+}
+
+// Version возвращает сумму версий источников, поддерживающих Versioned.
+func (a allHealthy) Version() uint64 {
+	var v uint64
+	for _, h := range a {
+		if vs, ok := h.(Versioned); ok {
+			v += vs.Version()
+		}
+	}
+	return v
+}
+
+// HealthSource — потокобезопасный держатель внешнего Health внутри стратегии.
+// Нулевое значение считает все серверы здоровыми.
+type HealthSource struct {
+	v atomic.Value // healthBox
+}
+
+// healthBox позволяет хранить в atomic.Value интерфейсы разных конкретных типов.
+type healthBox struct {
+	h Health
+}
+
+// Set заменяет источник здоровья.
+func (s *HealthSource) Set(h Health) {
+	s.v.Store(healthBox{h: h})
+}
+
+// Healthy сообщает состояние сервера по текущему источнику.
+func (s *HealthSource) Healthy(server string) bool {
+	box, _ := s.v.Load().(healthBox)
+	return box.h == nil || box.h.Healthy(server)
+}
+
+// Version возвращает версию источника, если он её поддерживает, иначе 0.
+func (s *HealthSource) Version() uint64 {
+	box, _ := s.v.Load().(healthBox)
+	if vs, ok := box.h.(Versioned); ok {
+		return vs.Version()
+	}
+	return 0
+}
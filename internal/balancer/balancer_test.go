@@ -0,0 +1,121 @@
+package balancer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// nextOnly — стратегия только с методом Next.
+type nextOnly struct{ srv string }
+
+func (n *nextOnly) Next() string { return n.srv }
+
+// requestAware — стратегия с методом NextFor.
+type requestAware struct{ nextOnly }
+
+func (a *requestAware) NextFor(r *http.Request) string { return r.Header.Get("X-Server") }
+
+// picker — стратегия, уже реализующая RequestBalancer.
+type picker struct{ nextOnly }
+
+func (p *picker) Pick(*http.Request) (string, error) { return "", ErrNoHealthyBackends }
+
+// TestAsRequestBalancer проверяет адаптацию стратегий к RequestBalancer.
+func TestAsRequestBalancer(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "http://lb/", nil)
+	req.Header.Set("X-Server", "by-request")
+
+	t.Run("Next-only strategy", func(t *testing.T) {
+		srv, err := AsRequestBalancer(&nextOnly{srv: "A"}).Pick(req)
+		assert.NoError(t, err)
+		assert.Equal(t, "A", srv)
+	})
+
+	t.Run("empty result becomes ErrNoBackends", func(t *testing.T) {
+		_, err := AsRequestBalancer(&nextOnly{}).Pick(req)
+		assert.ErrorIs(t, err, ErrNoBackends)
+	})
+
+	t.Run("NextFor receives request", func(t *testing.T) {
+		srv, err := AsRequestBalancer(&requestAware{nextOnly{srv: "A"}}).Pick(req)
+		assert.NoError(t, err)
+		assert.Equal(t, "by-request", srv)
+
+		srv, err = AsRequestBalancer(&requestAware{nextOnly{srv: "A"}}).Pick(nil)
+		assert.NoError(t, err)
+		assert.Equal(t, "A", srv, "nil request falls back to Next")
+	})
+
+	t.Run("native Pick is used as is", func(t *testing.T) {
+		_, err := AsRequestBalancer(&picker{}).Pick(req)
+		assert.ErrorIs(t, err, ErrNoHealthyBackends)
+	})
+}
+
+// versionedSet — источник здоровья с номером версии: перечисленные серверы недоступны.
+type versionedSet struct {
+	down    map[string]bool
+	version uint64
+}
+
+func (v *versionedSet) Healthy(server string) bool { return !v.down[server] }
+func (v *versionedSet) Version() uint64            { return v.version }
+
+// TestAllHealthy проверяет объединение источников здоровья и его версию.
+func TestAllHealthy(t *testing.T) {
+	active := &versionedSet{down: map[string]bool{"A": true}, version: 2}
+	passive := &versionedSet{down: map[string]bool{"B": true}, version: 3}
+	var src HealthSource
+	src.Set(AllHealthy(active, passive))
+
+	assert.False(t, src.Healthy("A"))
+	assert.False(t, src.Healthy("B"))
+	assert.True(t, src.Healthy("C"))
+	assert.Equal(t, uint64(5), src.Version())
+
+	passive.version++
+	assert.Equal(t, uint64(6), src.Version(), "version changes with any source")
+}
+
+// rotation выбирает серверы по кругу и запоминает освобождённые.
+type rotation struct {
+	servers  []string
+	i        int
+	released []string
+}
+
+func (r *rotation) Next() string {
+	s := r.servers[r.i%len(r.servers)]
+	r.i++
+	return s
+}
+
+func (r *rotation) Release(server string) { r.released = append(r.released, server) }
+
+// TestPickExcluding проверяет выбор сервера не из списка исключений.
+func TestPickExcluding(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "http://lb/", nil)
+
+	t.Run("repick skips excluded and releases it", func(t *testing.T) {
+		b := &rotation{servers: []string{"A", "B"}}
+		srv, err := PickExcluding(b, req, []string{"A"})
+		assert.NoError(t, err)
+		assert.Equal(t, "B", srv)
+		assert.Equal(t, []string{"A"}, b.released)
+	})
+
+	t.Run("single server has no alternate", func(t *testing.T) {
+		b := &rotation{servers: []string{"A"}}
+		_, err := PickExcluding(b, req, []string{"A"})
+		assert.ErrorIs(t, err, ErrNoAlternateBackend)
+		assert.Len(t, b.released, maxRepicks)
+	})
+
+	t.Run("pick error is returned", func(t *testing.T) {
+		_, err := PickExcluding(&picker{}, req, []string{"A"})
+		assert.ErrorIs(t, err, ErrNoHealthyBackends)
+	})
+}
@@ -0,0 +1,188 @@
+package consistent_hash
+
+import (
+	"math"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/coffee-realist/balancer/internal/balancer"
+	"github.com/coffee-realist/balancer/internal/balancer/hashkey"
+)
+
+const (
+	// DefaultVirtualNodes — число виртуальных узлов на сервер по умолчанию.
+	DefaultVirtualNodes = 160
+	// DefaultLoadFactor — допустимое превышение средней нагрузки по умолчанию.
+	DefaultLoadFactor = 1.25
+)
+
+// ConsistentHashBalancer направляет запросы с одинаковым ключом на один и тот же сервер
+// и ограничивает нагрузку на сервер долей от средней (bounded loads).
+type ConsistentHashBalancer interface {
+	balancer.Balancer
+	balancer.RequestBalancer
+	balancer.RequestAware
+	balancer.ConnAware
+	balancer.Excluder
+}
+
+// vnode — виртуальный узел на кольце.
+type vnode struct {
+	hash  uint64
+	owner int // Индекс сервера в servers
+}
+
+// chBalancer реализует кольцевое консистентное хеширование с ограничением нагрузки.
+type chBalancer struct {
+	servers    []string              // Список бэкендов
+	counts     map[string]*int64     // Атомарные счетчики активных соединений
+	ring       []vnode               // Виртуальные узлы, отсортированные по хешу
+	key        hashkey.Func          // Извлекатель ключа из запроса
+	loadFactor float64               // Множитель средней нагрузки для верхней границы
+	seq        uint64                // Счетчик для запросов без ключа
+	health     balancer.HealthSource // Внешний источник здоровья
+}
+
+// NewConsistentHashBalancer создаёт балансировщик на кольце с vnodes виртуальных узлов на сервер.
+// loadFactor задаёт верхнюю границу нагрузки как долю от средней: при 1.25 сервер
+// не получает новый ключ, если его счётчик соединений превысит среднее на 25%.
+// Значения vnodes <= 0 и loadFactor <= 0 заменяются значениями по умолчанию,
+// loadFactor меньше 1 поднимается до 1.
+func NewConsistentHashBalancer(servers []string, key hashkey.Func, vnodes int, loadFactor float64) ConsistentHashBalancer {
+	if vnodes <= 0 {
+		vnodes = DefaultVirtualNodes
+	}
+	if loadFactor <= 0 {
+		loadFactor = DefaultLoadFactor
+	}
+	if loadFactor < 1 {
+		loadFactor = 1
+	}
+
+	counts := make(map[string]*int64, len(servers))
+	ring := make([]vnode, 0, len(servers)*vnodes)
+	for i, s := range servers {
+		var zero int64
+		counts[s] = &zero
+		for v := 0; v < vnodes; v++ {
+			ring = append(ring, vnode{hash: hashkey.Sum64(s + "#" + strconv.Itoa(v)), owner: i})
+		}
+	}
+	sort.Slice(ring, func(i, j int) bool { return ring[i].hash < ring[j].hash })
+
+	return &chBalancer{
+		servers:    append([]string(nil), servers...),
+		counts:     counts,
+		ring:       ring,
+		key:        key,
+		loadFactor: loadFactor,
+	}
+}
+
+// Next выбирает сервер без учёта запроса: ключи генерируются по кругу,
+// поэтому такие вызовы распределяются равномерно.
+func (b *chBalancer) Next() string {
+	srv, _ := b.Pick(nil)
+	return srv
+}
+
+// NextFor выбирает сервер по ключу, извлечённому из запроса.
+func (b *chBalancer) NextFor(r *http.Request) string {
+	srv, _ := b.Pick(r)
+	return srv
+}
+
+// Pick ищет первый узел по часовой стрелке от хеша ключа,
+// пропуская недоступные серверы и серверы, нагрузка которых достигла верхней границы.
+func (b *chBalancer) Pick(r *http.Request) (string, error) {
+	return b.pick(r, nil)
+}
+
+// PickExcluding продолжает обход кольца за серверами из exclude, поэтому запасной сервер
+// для ключа тоже стабилен.
+func (b *chBalancer) PickExcluding(r *http.Request, exclude []string) (string, error) {
+	return b.pick(r, exclude)
+}
+
+// pick обходит кольцо от хеша ключа, пропуская серверы из exclude.
+func (b *chBalancer) pick(r *http.Request, exclude []string) (string, error) {
+	if len(b.ring) == 0 {
+		return "", balancer.ErrNoBackends
+	}
+	var key string
+	if r != nil && b.key != nil {
+		key = b.key(r)
+	}
+	if key == "" {
+		key = strconv.FormatUint(atomic.AddUint64(&b.seq, 1), 10)
+	}
+	limit, ok := b.capacity()
+	if !ok {
+		return "", balancer.ErrNoHealthyBackends
+	}
+
+	h := hashkey.Sum64(key)
+	start := sort.Search(len(b.ring), func(i int) bool { return b.ring[i].hash >= h })
+	var first string
+	for i := 0; i < len(b.ring); i++ {
+		srv := b.servers[b.ring[(start+i)%len(b.ring)].owner]
+		if !b.health.Healthy(srv) || slices.Contains(exclude, srv) {
+			continue
+		}
+		if first == "" {
+			first = srv
+		}
+		if atomic.LoadInt64(b.counts[srv]) < limit {
+			return srv, nil
+		}
+	}
+	if first == "" {
+		if len(exclude) > 0 {
+			return "", balancer.ErrNoAlternateBackend
+		}
+		// Все серверы стали недоступны после расчёта границы
+		return "", balancer.ErrNoHealthyBackends
+	}
+	// Недостижимо при loadFactor >= 1, оставлено на случай гонки счетчиков
+	return first, nil
+}
+
+// capacity вычисляет верхнюю границу нагрузки по здоровым серверам:
+// ceil(loadFactor * (total+1) / n). Возвращает false, если здоровых серверов нет.
+func (b *chBalancer) capacity() (int64, bool) {
+	var total int64
+	healthy := 0
+	for srv, ptr := range b.counts {
+		if b.health.Healthy(srv) {
+			total += atomic.LoadInt64(ptr)
+			healthy++
+		}
+	}
+	if healthy == 0 {
+		return 0, false
+	}
+	avg := float64(total+1) / float64(healthy)
+	return int64(math.Ceil(avg * b.loadFactor)), true
+}
+
+// SetHealth задаёт внешний источник здоровья бэкендов.
+func (b *chBalancer) SetHealth(h balancer.Health) {
+	b.health.Set(h)
+}
+
+// Increase атомарно увеличивает счетчик активных соединений для сервера.
+func (b *chBalancer) Increase(server string) {
+	if ptr, ok := b.counts[server]; ok {
+		atomic.AddInt64(ptr, 1)
+	}
+}
+
+// Decrease атомарно уменьшает счетчик активных соединений для сервера.
+func (b *chBalancer) Decrease(server string) {
+	if ptr, ok := b.counts[server]; ok {
+		atomic.AddInt64(ptr, -1)
+	}
+}
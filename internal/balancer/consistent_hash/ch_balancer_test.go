@@ -0,0 +1,135 @@
+package consistent_hash
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/coffee-realist/balancer/internal/balancer"
+	"github.com/coffee-realist/balancer/internal/balancer/hashkey"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// requestWithKey создаёт запрос с ключом в заголовке X-Key.
+func requestWithKey(key string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "http://lb/", nil)
+	req.Header.Set("X-Key", key)
+	return req
+}
+
+// headerKey возвращает извлекатель ключа из заголовка X-Key.
+func headerKey(t testing.TB) hashkey.Func {
+	key, err := hashkey.New(hashkey.SourceHeader, "X-Key")
+	require.NoError(t, err)
+	return key
+}
+
+// TestConsistentHash_Affinity проверяет, что одинаковые ключи попадают на один сервер.
+func TestConsistentHash_Affinity(t *testing.T) {
+	servers := []string{"A", "B", "C", "D"}
+	b := NewConsistentHashBalancer(servers, headerKey(t), 0, 0)
+
+	for i := 0; i < 100; i++ {
+		key := "user-" + strconv.Itoa(i)
+		first := b.NextFor(requestWithKey(key))
+		assert.Contains(t, servers, first)
+		for j := 0; j < 5; j++ {
+			assert.Equal(t, first, b.NextFor(requestWithKey(key)), "key %s moved", key)
+		}
+	}
+}
+
+// TestConsistentHash_MinimalDisruption проверяет, что при удалении сервера
+// переезжают только ключи, принадлежавшие ему.
+func TestConsistentHash_MinimalDisruption(t *testing.T) {
+	key := headerKey(t)
+	before := NewConsistentHashBalancer([]string{"A", "B", "C", "D"}, key, 0, 0)
+	after := NewConsistentHashBalancer([]string{"A", "B", "C"}, key, 0, 0)
+
+	moved := 0
+	for i := 0; i < 1000; i++ {
+		req := requestWithKey("k" + strconv.Itoa(i))
+		was, now := before.NextFor(req), after.NextFor(req)
+		if was != "D" {
+			assert.Equal(t, was, now, "key owned by %s must not move", was)
+		} else {
+			moved++
+		}
+	}
+	// Примерно четверть ключей принадлежала D
+	assert.InDelta(t, 250, moved, 100)
+}
+
+// TestConsistentHash_BoundedLoads проверяет перелив ключа на следующий узел кольца
+// при превышении верхней границы нагрузки.
+func TestConsistentHash_BoundedLoads(t *testing.T) {
+	servers := []string{"A", "B", "C"}
+	b := NewConsistentHashBalancer(servers, headerKey(t), 0, 1.25)
+	req := requestWithKey("hot-key")
+
+	home := b.NextFor(req)
+	counts := make(map[string]int)
+	for i := 0; i < 30; i++ {
+		srv := b.NextFor(req)
+		b.Increase(srv)
+		counts[srv]++
+	}
+	// Горячий ключ не может занять больше ceil(1.25 * 30 / 3) = 13 соединений на сервере
+	for _, srv := range servers {
+		assert.LessOrEqual(t, counts[srv], 13, "server %s overloaded", srv)
+	}
+	assert.Equal(t, 13, counts[home])
+
+	// После освобождения соединений ключ возвращается на свой сервер
+	for srv, n := range counts {
+		for i := 0; i < n; i++ {
+			b.Decrease(srv)
+		}
+	}
+	assert.Equal(t, home, b.NextFor(req))
+}
+
+// TestConsistentHash_NoKeyAndEmpty проверяет запросы без ключа и пустой список серверов.
+func TestConsistentHash_NoKeyAndEmpty(t *testing.T) {
+	servers := []string{"A", "B", "C"}
+	b := NewConsistentHashBalancer(servers, nil, 0, 0)
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		seen[b.Next()] = true
+	}
+	assert.Len(t, seen, 3, "keyless picks should spread across servers")
+
+	empty := NewConsistentHashBalancer(nil, nil, 0, 0)
+	assert.Equal(t, "", empty.Next())
+}
+
+// TestConsistentHash_PickExcluding проверяет выбор следующего по кольцу сервера вместо исключённого.
+func TestConsistentHash_PickExcluding(t *testing.T) {
+	b := NewConsistentHashBalancer([]string{"A", "B", "C"}, headerKey(t), 0, 0)
+	req := requestWithKey("user-1")
+	home, err := b.Pick(req)
+	require.NoError(t, err)
+
+	alt, err := b.PickExcluding(req, []string{home})
+	require.NoError(t, err)
+	assert.NotEqual(t, home, alt)
+
+	_, err = b.PickExcluding(req, []string{"A", "B", "C"})
+	assert.ErrorIs(t, err, balancer.ErrNoAlternateBackend)
+}
+
+// BenchmarkConsistentHash_NextFor измеряет производительность выбора по ключу при 100 серверах.
+func BenchmarkConsistentHash_NextFor(b *testing.B) {
+	servers := make([]string, 100)
+	for i := range servers {
+		servers[i] = "s" + strconv.Itoa(i)
+	}
+	ch := NewConsistentHashBalancer(servers, headerKey(b), 0, 0)
+	req := requestWithKey("bench")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = ch.NextFor(req)
+	}
+}
@@ -0,0 +1,82 @@
+package hashkey
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/http"
+
+	"github.com/coffee-realist/balancer/internal/forwarded"
+)
+
+// Источники ключа хеширования.
+const (
+	SourceHeader = "header" // Значение HTTP-заголовка
+	SourceCookie = "cookie" // Значение cookie
+	SourceQuery  = "query"  // Значение query-параметра
+	SourcePath   = "path"   // Путь запроса
+	SourceIP     = "ip"     // IP-адрес клиента
+)
+
+// Func извлекает из запроса ключ, по которому хеширующие стратегии выбирают сервер.
+type Func func(r *http.Request) string
+
+// New возвращает извлекатель ключа для указанного источника.
+// name обязателен для header, cookie и query. Пустой source означает IP клиента.
+// Если в запросе нет нужного атрибута, в качестве ключа используется IP клиента.
+func New(source, name string) (Func, error) {
+	var get Func
+	switch source {
+	case SourceHeader:
+		get = func(r *http.Request) string { return r.Header.Get(name) }
+	case SourceCookie:
+		get = func(r *http.Request) string {
+			c, err := r.Cookie(name)
+			if err != nil {
+				return ""
+			}
+			return c.Value
+		}
+	case SourceQuery:
+		get = func(r *http.Request) string { return r.URL.Query().Get(name) }
+	case SourcePath:
+		return func(r *http.Request) string { return r.URL.Path }, nil
+	case SourceIP, "":
+		return ClientIP, nil
+	default:
+		return nil, fmt.Errorf("unknown hash key source %q", source)
+	}
+	if name == "" {
+		return nil, fmt.Errorf("hash key source %q requires a name", source)
+	}
+	return func(r *http.Request) string {
+		if k := get(r); k != "" {
+			return k
+		}
+		return ClientIP(r)
+	}, nil
+}
+
+// ClientIP возвращает IP-адрес клиента: определённый forwarded.Middleware с учётом
+// доверенных прокси, иначе адрес соединения без порта. За доверенным балансировщиком
+// все клиенты иначе получили бы один ключ — его адрес.
+func ClientIP(r *http.Request) string {
+	return forwarded.FromRequest(r).IP
+}
+
+// Sum64 вычисляет 64-битный хеш строки: FNV-1a с финальным перемешиванием splitmix64,
+// чтобы близкие строки (например, "srv#1" и "srv#2") равномерно расходились по кольцу.
+func Sum64(s string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(s))
+	return Mix64(h.Sum64())
+}
+
+// Mix64 — финализатор splitmix64.
+func Mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
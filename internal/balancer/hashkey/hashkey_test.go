@@ -0,0 +1,80 @@
+package hashkey
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/coffee-realist/balancer/internal/forwarded"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestNew_Sources проверяет извлечение ключа из каждого поддерживаемого источника.
+func TestNew_Sources(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "http://lb/users/42?tenant=acme", nil)
+	req.RemoteAddr = "10.0.0.7:53211"
+	req.Header.Set("X-User-ID", "u-1")
+	req.AddCookie(&http.Cookie{Name: "session", Value: "s-1"})
+
+	cases := []struct {
+		source, name, want string
+	}{
+		{SourceHeader, "X-User-ID", "u-1"},
+		{SourceCookie, "session", "s-1"},
+		{SourceQuery, "tenant", "acme"},
+		{SourcePath, "", "/users/42"},
+		{SourceIP, "", "10.0.0.7"},
+		{"", "", "10.0.0.7"},
+	}
+	for _, c := range cases {
+		t.Run(c.source, func(t *testing.T) {
+			key, err := New(c.source, c.name)
+			require.NoError(t, err)
+			assert.Equal(t, c.want, key(req))
+		})
+	}
+}
+
+// TestClientIP проверяет, что за доверенным прокси ключом служит адрес исходного клиента.
+func TestClientIP(t *testing.T) {
+	trusted, err := forwarded.ParseTrustedProxies([]string{"10.0.0.0/8"})
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodGet, "http://lb/", nil)
+	req.RemoteAddr = "10.0.0.7:53211"
+	req.Header.Set(forwarded.HeaderXForwardedFor, "198.51.100.4")
+
+	assert.Equal(t, "10.0.0.7", ClientIP(req), "headers are ignored without forwarded.Middleware")
+	var got string
+	forwarded.Middleware(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		got = ClientIP(r)
+	}), trusted).ServeHTTP(httptest.NewRecorder(), req)
+	assert.Equal(t, "198.51.100.4", got)
+}
+
+// TestNew_FallbackAndErrors проверяет откат на IP клиента и ошибки конфигурации.
+func TestNew_FallbackAndErrors(t *testing.T) {
+	t.Run("missing attribute falls back to client IP", func(t *testing.T) {
+		key, err := New(SourceHeader, "X-User-ID")
+		require.NoError(t, err)
+		req := httptest.NewRequest(http.MethodGet, "http://lb/", nil)
+		req.RemoteAddr = "192.168.1.1:1234"
+		assert.Equal(t, "192.168.1.1", key(req))
+	})
+
+	t.Run("unknown source", func(t *testing.T) {
+		_, err := New("body", "")
+		assert.Error(t, err)
+	})
+
+	t.Run("name required", func(t *testing.T) {
+		_, err := New(SourceCookie, "")
+		assert.Error(t, err)
+	})
+}
+
+// TestSum64_Stable проверяет детерминированность хеша.
+func TestSum64_Stable(t *testing.T) {
+	assert.Equal(t, Sum64("key"), Sum64("key"))
+	assert.NotEqual(t, Sum64("srv#1"), Sum64("srv#2"))
+}
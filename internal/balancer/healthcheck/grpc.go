@@ -0,0 +1,172 @@
+package healthcheck
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// grpcHealthPath — метод стандартного сервиса проверки здоровья gRPC.
+const grpcHealthPath = "/grpc.health.v1.Health/Check"
+
+// grpcServing — значение HealthCheckResponse.ServingStatus.SERVING.
+const grpcServing = 1
+
+// grpcStatusNames — имена значений HealthCheckResponse.ServingStatus для сообщений об ошибках.
+var grpcStatusNames = map[uint64]string{0: "UNKNOWN", 1: "SERVING", 2: "NOT_SERVING", 3: "SERVICE_UNKNOWN"}
+
+// newGRPCClient создаёт HTTP/2-клиент для gRPC-проверок: h2c для http:// и HTTP/2 поверх TLS для https://.
+func newGRPCClient(cfg Config) *http.Client {
+	var protocols http.Protocols
+	protocols.SetHTTP2(true)
+	protocols.SetUnencryptedHTTP2(true)
+	return &http.Client{
+		Timeout: cfg.Timeout,
+		Transport: &http.Transport{
+			Protocols:       &protocols,
+			TLSClientConfig: newTLSConfig(cfg),
+		},
+	}
+}
+
+// probeGRPC вызывает grpc.health.v1.Health/Check и проверяет, что сервис в состоянии SERVING.
+// Сообщения protobuf кодируются вручную: у запроса и ответа по одному полю.
+func (c *Checker) probeGRPC(ctx context.Context, server string) error {
+	target := url.URL{Scheme: "http", Host: hostPort(server), Path: grpcHealthPath}
+	if u, err := url.Parse(server); err == nil && u.Scheme == "https" {
+		target.Scheme = "https"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target.String(), bytes.NewReader(grpcFrame(encodeHealthRequest(c.cfg.GRPCService))))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("TE", "trailers")
+
+	resp, err := c.grpc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	// Трейлеры доступны только после чтения тела
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodyBytes))
+	if err != nil {
+		return err
+	}
+	code := resp.Trailer.Get("Grpc-Status")
+	if code == "" {
+		// Ответ только из заголовков (trailers-only) при ошибке вызова
+		code = resp.Header.Get("Grpc-Status")
+	}
+	if code != "0" {
+		msg := resp.Trailer.Get("Grpc-Message")
+		if msg == "" {
+			msg = resp.Header.Get("Grpc-Message")
+		}
+		return fmt.Errorf("grpc status %s: %s", code, msg)
+	}
+
+	msg, err := grpcMessage(body)
+	if err != nil {
+		return err
+	}
+	status, err := decodeHealthResponse(msg)
+	if err != nil {
+		return err
+	}
+	if status != grpcServing {
+		name, ok := grpcStatusNames[status]
+		if !ok {
+			name = fmt.Sprint(status)
+		}
+		return fmt.Errorf("grpc service is %s", name)
+	}
+	return nil
+}
+
+// grpcFrame оборачивает сообщение в кадр gRPC: флаг сжатия и длина в big-endian.
+func grpcFrame(msg []byte) []byte {
+	frame := make([]byte, 5, 5+len(msg))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(msg)))
+	return append(frame, msg...)
+}
+
+// grpcMessage извлекает сообщение из первого кадра gRPC.
+func grpcMessage(body []byte) ([]byte, error) {
+	if len(body) < 5 {
+		return nil, errors.New("grpc response too short")
+	}
+	if body[0] != 0 {
+		return nil, errors.New("compressed grpc response is not supported")
+	}
+	n := binary.BigEndian.Uint32(body[1:5])
+	if uint64(len(body)-5) < uint64(n) {
+		return nil, errors.New("grpc response truncated")
+	}
+	return body[5 : 5+n], nil
+}
+
+// encodeHealthRequest кодирует HealthCheckRequest{service = 1}.
+func encodeHealthRequest(service string) []byte {
+	if service == "" {
+		return nil
+	}
+	msg := []byte{0x0a} // поле 1, тип length-delimited
+	msg = binary.AppendUvarint(msg, uint64(len(service)))
+	return append(msg, service...)
+}
+
+// decodeHealthResponse извлекает поле status (1, varint) из HealthCheckResponse,
+// пропуская неизвестные поля. Отсутствующее поле означает UNKNOWN.
+func decodeHealthResponse(msg []byte) (uint64, error) {
+	var status uint64
+	for len(msg) > 0 {
+		tag, n := binary.Uvarint(msg)
+		if n <= 0 {
+			return 0, errors.New("malformed grpc health response")
+		}
+		msg = msg[n:]
+		field, wire := tag>>3, tag&7
+
+		switch wire {
+		case 0: // varint
+			v, n := binary.Uvarint(msg)
+			if n <= 0 {
+				return 0, errors.New("malformed grpc health response")
+			}
+			msg = msg[n:]
+			if field == 1 {
+				status = v
+			}
+		case 1: // 64-bit
+			if len(msg) < 8 {
+				return 0, errors.New("malformed grpc health response")
+			}
+			msg = msg[8:]
+		case 2: // length-delimited
+			l, n := binary.Uvarint(msg)
+			if n <= 0 || uint64(len(msg)-n) < l {
+				return 0, errors.New("malformed grpc health response")
+			}
+			msg = msg[n+int(l):]
+		case 5: // 32-bit
+			if len(msg) < 4 {
+				return 0, errors.New("malformed grpc health response")
+			}
+			msg = msg[4:]
+		default:
+			return 0, errors.New("malformed grpc health response")
+		}
+	}
+	return status, nil
+}
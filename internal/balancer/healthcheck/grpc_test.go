@@ -0,0 +1,88 @@
+package healthcheck
+
+import (
+	"encoding/binary"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newGRPCHealthServer запускает h2c-сервер, отвечающий на grpc.health.v1.Health/Check
+// статусами из statuses по имени сервиса. Неизвестный сервис — код NOT_FOUND (5).
+func newGRPCHealthServer(t *testing.T, statuses map[string]uint64) *httptest.Server {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != grpcHealthPath || r.Header.Get("Content-Type") != "application/grpc" || r.ProtoMajor != 2 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		msg, err := grpcMessage(body)
+		require.NoError(t, err)
+		var service string
+		if len(msg) > 0 {
+			l, n := binary.Uvarint(msg[1:])
+			service = string(msg[1+n : 1+n+int(l)])
+		}
+
+		w.Header().Set("Content-Type", "application/grpc")
+		status, ok := statuses[service]
+		if !ok {
+			w.Header().Set("Grpc-Status", "5")
+			w.Header().Set("Grpc-Message", "unknown service")
+			return
+		}
+		// Неизвестное поле 2 перед статусом должно пропускаться
+		_, _ = w.Write(grpcFrame([]byte{0x12, 0x01, 'x', 0x08, byte(status)}))
+		w.Header().Set(http.TrailerPrefix+"Grpc-Status", "0")
+	}))
+	var protocols http.Protocols
+	protocols.SetUnencryptedHTTP2(true)
+	srv.Config.Protocols = &protocols
+	srv.Start()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// TestProbe_GRPC проверяет gRPC-проверку по статусу сервиса.
+func TestProbe_GRPC(t *testing.T) {
+	srv := newGRPCHealthServer(t, map[string]uint64{"": grpcServing, "billing": 2})
+
+	c := newTestChecker(t, []string{srv.URL}, Config{Type: TypeGRPC})
+	res := c.probe(srv.URL)
+	assert.True(t, res.Healthy, res.Error)
+
+	billing := newTestChecker(t, []string{srv.URL}, Config{Type: TypeGRPC, GRPCService: "billing"})
+	assert.Equal(t, "grpc service is NOT_SERVING", billing.probe(srv.URL).Error)
+
+	unknown := newTestChecker(t, []string{srv.URL}, Config{Type: TypeGRPC, GRPCService: "orders"})
+	assert.Equal(t, "grpc status 5: unknown service", unknown.probe(srv.URL).Error)
+}
+
+// TestProbe_GRPCPlainHTTP проверяет, что обычный HTTP-сервер не проходит gRPC-проверку.
+func TestProbe_GRPCPlainHTTP(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	c := newTestChecker(t, []string{srv.URL}, Config{Type: TypeGRPC})
+	assert.False(t, c.probe(srv.URL).Healthy)
+}
+
+// TestDecodeHealthResponse проверяет разбор HealthCheckResponse.
+func TestDecodeHealthResponse(t *testing.T) {
+	status, err := decodeHealthResponse([]byte{0x08, 0x01})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(grpcServing), status)
+
+	status, err = decodeHealthResponse(nil)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), status, "missing field means UNKNOWN")
+
+	_, err = decodeHealthResponse([]byte{0x08})
+	assert.Error(t, err)
+	_, err = grpcMessage([]byte{0, 0, 0, 0, 9, 1})
+	assert.Error(t, err)
+}
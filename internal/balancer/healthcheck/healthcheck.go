@@ -0,0 +1,386 @@
+package healthcheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Значения по умолчанию (повторяют прежние проверки p2c).
+const (
+	DefaultPath     = "/health"
+	DefaultTimeout  = time.Second
+	DefaultInterval = 2 * time.Second
+	// maxBodyBytes ограничивает объём тела ответа, читаемого для проверки содержимого.
+	maxBodyBytes = 64 << 10
+)
+
+// Протоколы проверки.
+const (
+	TypeHTTP = "http" // HTTP-запрос с проверкой статуса и тела (по умолчанию)
+	TypeTCP  = "tcp"  // Установка TCP-соединения
+	TypeTLS  = "tls"  // TCP-соединение и TLS-рукопожатие
+	TypeGRPC = "grpc" // grpc.health.v1.Health/Check поверх h2c (или HTTP/2 с TLS для https://)
+)
+
+// StatusRange — диапазон HTTP-статусов, считающихся успешными (включительно).
+type StatusRange struct {
+	Min, Max int
+}
+
+// Config описывает проверку. Нулевые поля заменяются значениями по умолчанию.
+type Config struct {
+	Type          string            // Протокол проверки для пула, по умолчанию TypeHTTP
+	ServerTypes   map[string]string // Протокол для отдельных серверов
+	GRPCService   string            // Имя сервиса для gRPC-проверки, пустое — сервер в целом
+	TLSServerName string            // SNI и имя для проверки сертификата, по умолчанию хост сервера
+	TLSSkipVerify bool              // Не проверять сертификат бэкенда
+	Path          string            // Путь проверки, по умолчанию /health
+	Method        string            // HTTP-метод: HEAD, либо GET, если задана проверка тела
+	Statuses      []StatusRange     // Успешные статусы, по умолчанию любой ниже 500
+	BodyContains  string            // Подстрока, которая должна быть в теле ответа
+	BodyRegexp    string            // Регулярное выражение для тела ответа
+	Host          string            // Значение заголовка Host
+	Timeout       time.Duration     // Таймаут одной проверки
+	Interval      time.Duration     // Период проверок
+	Jitter        time.Duration     // Случайная добавка к периоду, чтобы реплики не проверяли синхронно
+	Rise          int               // Успешных проверок подряд для возврата в ротацию
+	Fall          int               // Неудачных проверок подряд для исключения
+}
+
+// Result — результат последней проверки сервера.
+type Result struct {
+	Time    time.Time     `json:"time"`
+	Healthy bool          `json:"healthy"`
+	Status  int           `json:"status,omitempty"`
+	Latency time.Duration `json:"latency"`
+	Error   string        `json:"error,omitempty"`
+}
+
+// target — состояние одного сервера.
+type target struct {
+	healthy bool   // Текущее состояние с учётом rise/fall
+	streak  int    // Длина серии результатов, противоположных текущему состоянию
+	last    Result // Последняя проверка
+}
+
+// Checker периодически проверяет все серверы одним циклом и реализует balancer.Health,
+// поэтому его может использовать любая стратегия, поддерживающая balancer.HealthAware.
+type Checker struct {
+	cfg     Config
+	re      *regexp.Regexp
+	client  *http.Client
+	grpc    *http.Client // Клиент с поддержкой h2c для gRPC-проверок
+	mu      sync.RWMutex
+	targets map[string]*target
+	order   []string
+	version atomic.Uint64 // Увеличивается при каждой смене состояния сервера
+	stop    chan struct{}
+}
+
+// New создаёт Checker и запускает цикл проверок. До первой проверки все серверы считаются здоровыми.
+func New(servers []string, cfg Config) (*Checker, error) {
+	if cfg.Type == "" {
+		cfg.Type = TypeHTTP
+	}
+	if !validType(cfg.Type) {
+		return nil, fmt.Errorf("unknown health check type %q", cfg.Type)
+	}
+	for srv, t := range cfg.ServerTypes {
+		if !validType(t) {
+			return nil, fmt.Errorf("unknown health check type %q for %s", t, srv)
+		}
+	}
+	if cfg.Path == "" {
+		cfg.Path = DefaultPath
+	}
+	if cfg.Method == "" {
+		cfg.Method = http.MethodHead
+		if cfg.BodyContains != "" || cfg.BodyRegexp != "" {
+			cfg.Method = http.MethodGet
+		}
+	}
+	if len(cfg.Statuses) == 0 {
+		cfg.Statuses = []StatusRange{{Min: 100, Max: 499}}
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultInterval
+	}
+	if cfg.Rise <= 0 {
+		cfg.Rise = 1
+	}
+	if cfg.Fall <= 0 {
+		cfg.Fall = 1
+	}
+
+	var re *regexp.Regexp
+	if cfg.BodyRegexp != "" {
+		var err error
+		if re, err = regexp.Compile(cfg.BodyRegexp); err != nil {
+			return nil, fmt.Errorf("invalid body regexp: %w", err)
+		}
+	}
+
+	targets := make(map[string]*target, len(servers))
+	for _, s := range servers {
+		targets[s] = &target{healthy: true}
+	}
+	c := &Checker{
+		cfg:     cfg,
+		re:      re,
+		client:  &http.Client{Timeout: cfg.Timeout},
+		grpc:    newGRPCClient(cfg),
+		targets: targets,
+		order:   append([]string(nil), servers...),
+		stop:    make(chan struct{}),
+	}
+
+	// Запуск цикла проверок
+	go c.run()
+
+	return c, nil
+}
+
+// Healthy сообщает состояние сервера. Неизвестные серверы считаются здоровыми.
+func (c *Checker) Healthy(server string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	t, ok := c.targets[server]
+	return !ok || t.healthy
+}
+
+// Version возвращает номер версии состояния, меняющийся при каждой смене состояния сервера.
+func (c *Checker) Version() uint64 {
+	return c.version.Load()
+}
+
+// Last возвращает результат последней проверки сервера.
+func (c *Checker) Last(server string) (Result, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	t, ok := c.targets[server]
+	if !ok || t.last.Time.IsZero() {
+		return Result{}, false
+	}
+	return t.last, true
+}
+
+// Add начинает проверять сервер со следующего раунда. До первой проверки сервер считается здоровым.
+func (c *Checker) Add(server string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.targets[server]; ok {
+		return
+	}
+	c.targets[server] = &target{healthy: true}
+	c.order = append(c.order, server)
+}
+
+// Remove прекращает проверки сервера.
+func (c *Checker) Remove(server string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.targets[server]; !ok {
+		return
+	}
+	delete(c.targets, server)
+	order := make([]string, 0, len(c.order))
+	for _, s := range c.order {
+		if s != server {
+			order = append(order, s)
+		}
+	}
+	c.order = order
+}
+
+// Stop останавливает цикл проверок.
+func (c *Checker) Stop() {
+	close(c.stop)
+}
+
+// run проверяет все серверы раз в Interval + случайная добавка до Jitter.
+func (c *Checker) run() {
+	timer := time.NewTimer(c.nextDelay())
+	defer timer.Stop()
+	for {
+		select {
+		case <-timer.C:
+			c.checkAll()
+			timer.Reset(c.nextDelay())
+		case <-c.stop:
+			return
+		}
+	}
+}
+
+// nextDelay возвращает задержку до следующего раунда проверок.
+func (c *Checker) nextDelay() time.Duration {
+	if c.cfg.Jitter <= 0 {
+		return c.cfg.Interval
+	}
+	return c.cfg.Interval + rand.N(c.cfg.Jitter)
+}
+
+// checkAll параллельно проверяет все серверы и дожидается результатов раунда.
+func (c *Checker) checkAll() {
+	c.mu.RLock()
+	servers := c.order
+	c.mu.RUnlock()
+
+	var wg sync.WaitGroup
+	for _, srv := range servers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.record(srv, c.probe(srv))
+		}()
+	}
+	wg.Wait()
+}
+
+// probe выполняет проверку сервера протоколом, выбранным для него в конфигурации.
+func (c *Checker) probe(server string) Result {
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), c.cfg.Timeout)
+	defer cancel()
+
+	var (
+		status int
+		err    error
+	)
+	switch c.typeOf(server) {
+	case TypeTCP:
+		err = c.probeTCP(ctx, server)
+	case TypeTLS:
+		err = c.probeTLS(ctx, server)
+	case TypeGRPC:
+		err = c.probeGRPC(ctx, server)
+	default:
+		status, err = c.probeHTTP(ctx, server)
+	}
+
+	res := Result{Time: start, Status: status, Latency: time.Since(start), Healthy: err == nil}
+	if err != nil {
+		res.Error = err.Error()
+	}
+	return res
+}
+
+// probeHTTP выполняет HTTP-проверку сервера и возвращает статус ответа.
+func (c *Checker) probeHTTP(ctx context.Context, server string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, c.cfg.Method, server+c.cfg.Path, nil)
+	if err != nil {
+		return 0, err
+	}
+	if c.cfg.Host != "" {
+		req.Host = c.cfg.Host
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if !c.statusOK(resp.StatusCode) {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	if c.cfg.BodyContains != "" || c.re != nil {
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodyBytes))
+		if err != nil {
+			return resp.StatusCode, err
+		}
+		if c.cfg.BodyContains != "" && !strings.Contains(string(body), c.cfg.BodyContains) {
+			return resp.StatusCode, errors.New("body does not contain expected text")
+		}
+		if c.re != nil && !c.re.Match(body) {
+			return resp.StatusCode, errors.New("body does not match expected pattern")
+		}
+	}
+	return resp.StatusCode, nil
+}
+
+// typeOf возвращает протокол проверки сервера: собственный либо общий для пула.
+func (c *Checker) typeOf(server string) string {
+	if t, ok := c.cfg.ServerTypes[server]; ok {
+		return t
+	}
+	return c.cfg.Type
+}
+
+// validType сообщает, поддерживается ли протокол проверки.
+func validType(t string) bool {
+	switch t {
+	case TypeHTTP, TypeTCP, TypeTLS, TypeGRPC:
+		return true
+	}
+	return false
+}
+
+// statusOK проверяет статус по диапазонам успешных статусов.
+func (c *Checker) statusOK(status int) bool {
+	for _, r := range c.cfg.Statuses {
+		if status >= r.Min && status <= r.Max {
+			return true
+		}
+	}
+	return false
+}
+
+// record сохраняет результат и меняет состояние сервера после Rise успехов
+// или Fall неудач подряд.
+func (c *Checker) record(server string, res Result) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t, ok := c.targets[server]
+	if !ok {
+		return
+	}
+	t.last = res
+	if res.Healthy == t.healthy {
+		t.streak = 0
+		return
+	}
+	t.streak++
+	threshold := c.cfg.Fall
+	if !t.healthy {
+		threshold = c.cfg.Rise
+	}
+	if t.streak >= threshold {
+		t.healthy = res.Healthy
+		t.streak = 0
+		c.version.Add(1)
+	}
+}
+
+// ParseStatusRanges разбирает диапазоны статусов вида "200-299" или "404".
+func ParseStatusRanges(specs []string) ([]StatusRange, error) {
+	ranges := make([]StatusRange, 0, len(specs))
+	for _, spec := range specs {
+		lo, hi, isRange := strings.Cut(strings.TrimSpace(spec), "-")
+		from, err := strconv.Atoi(strings.TrimSpace(lo))
+		if err != nil {
+			return nil, fmt.Errorf("invalid status range %q", spec)
+		}
+		to := from
+		if isRange {
+			if to, err = strconv.Atoi(strings.TrimSpace(hi)); err != nil || to < from {
+				return nil, fmt.Errorf("invalid status range %q", spec)
+			}
+		}
+		ranges = append(ranges, StatusRange{Min: from, Max: to})
+	}
+	return ranges, nil
+}
@@ -0,0 +1,202 @@
+package healthcheck
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestChecker создаёт Checker с редким циклом проверок; проверки вызываются в тестах вручную.
+func newTestChecker(t *testing.T, servers []string, cfg Config) *Checker {
+	cfg.Interval = time.Hour
+	c, err := New(servers, cfg)
+	require.NoError(t, err)
+	t.Cleanup(c.Stop)
+	return c
+}
+
+// TestProbe_Defaults проверяет значения по умолчанию: HEAD /health, успех при статусе ниже 500.
+func TestProbe_Defaults(t *testing.T) {
+	var method, path string
+	code := http.StatusNotFound
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path = r.Method, r.URL.Path
+		w.WriteHeader(code)
+	}))
+	defer srv.Close()
+
+	c := newTestChecker(t, []string{srv.URL}, Config{})
+	res := c.probe(srv.URL)
+	assert.True(t, res.Healthy)
+	assert.Equal(t, http.StatusNotFound, res.Status)
+	assert.Equal(t, http.MethodHead, method)
+	assert.Equal(t, DefaultPath, path)
+
+	code = http.StatusServiceUnavailable
+	res = c.probe(srv.URL)
+	assert.False(t, res.Healthy)
+	assert.Contains(t, res.Error, "503")
+}
+
+// TestProbe_StatusRanges проверяет настраиваемые диапазоны успешных статусов.
+func TestProbe_StatusRanges(t *testing.T) {
+	code := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(code)
+	}))
+	defer srv.Close()
+
+	ranges, err := ParseStatusRanges([]string{"200-299", "418"})
+	require.NoError(t, err)
+	c := newTestChecker(t, []string{srv.URL}, Config{Statuses: ranges})
+
+	for status, want := range map[int]bool{200: true, 204: true, 418: true, 301: false, 404: false, 500: false} {
+		code = status
+		assert.Equal(t, want, c.probe(srv.URL).Healthy, "status %d", status)
+	}
+}
+
+// TestProbe_Body проверяет поиск подстроки и регулярного выражения в теле ответа.
+func TestProbe_Body(t *testing.T) {
+	body := `{"status":"ok","db":"up"}`
+	var method string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method = r.Method
+		_, _ = w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	c := newTestChecker(t, []string{srv.URL}, Config{BodyContains: `"status":"ok"`, BodyRegexp: `"db":"(up|degraded)"`})
+	assert.True(t, c.probe(srv.URL).Healthy)
+	assert.Equal(t, http.MethodGet, method, "body checks need GET")
+
+	body = `{"status":"ok","db":"down"}`
+	res := c.probe(srv.URL)
+	assert.False(t, res.Healthy)
+	assert.Equal(t, "body does not match expected pattern", res.Error)
+
+	body = `{"status":"starting"}`
+	assert.Equal(t, "body does not contain expected text", c.probe(srv.URL).Error)
+
+	_, err := New(nil, Config{BodyRegexp: "("})
+	assert.Error(t, err)
+}
+
+// TestProbe_HostAndMethod проверяет заголовок Host, путь и метод проверки.
+func TestProbe_HostAndMethod(t *testing.T) {
+	var host, method, path string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, method, path = r.Host, r.Method, r.URL.Path
+	}))
+	defer srv.Close()
+
+	c := newTestChecker(t, []string{srv.URL}, Config{Path: "/ready", Method: http.MethodGet, Host: "api.internal"})
+	assert.True(t, c.probe(srv.URL).Healthy)
+	assert.Equal(t, "api.internal", host)
+	assert.Equal(t, http.MethodGet, method)
+	assert.Equal(t, "/ready", path)
+}
+
+// TestProbe_Timeout проверяет, что медленный бэкенд считается недоступным.
+func TestProbe_Timeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	c := newTestChecker(t, []string{srv.URL}, Config{Timeout: 20 * time.Millisecond})
+	res := c.probe(srv.URL)
+	assert.False(t, res.Healthy)
+	assert.NotEmpty(t, res.Error)
+}
+
+// TestRecord_RiseFall проверяет пороги rise/fall и изменение версии только при смене состояния.
+func TestRecord_RiseFall(t *testing.T) {
+	c := newTestChecker(t, []string{"A"}, Config{Rise: 2, Fall: 3})
+	up, down := Result{Healthy: true}, Result{}
+
+	c.record("A", down)
+	c.record("A", down)
+	c.record("A", up) // успех прерывает серию неудач
+	c.record("A", down)
+	c.record("A", down)
+	assert.True(t, c.Healthy("A"))
+	assert.Equal(t, uint64(0), c.Version())
+
+	c.record("A", down)
+	assert.False(t, c.Healthy("A"))
+	assert.Equal(t, uint64(1), c.Version())
+
+	c.record("A", up)
+	assert.False(t, c.Healthy("A"))
+	c.record("A", up)
+	assert.True(t, c.Healthy("A"))
+	assert.Equal(t, uint64(2), c.Version())
+
+	assert.True(t, c.Healthy("unknown"), "unknown servers are healthy")
+}
+
+// TestChecker_Loop проверяет общий цикл проверок и результат последней проверки.
+func TestChecker_Loop(t *testing.T) {
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer up.Close()
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer down.Close()
+
+	c, err := New([]string{up.URL, down.URL}, Config{Interval: 10 * time.Millisecond, Jitter: 5 * time.Millisecond})
+	require.NoError(t, err)
+	defer c.Stop()
+
+	_, ok := c.Last(up.URL)
+	assert.False(t, ok, "no result before first round")
+	assert.True(t, c.Healthy(down.URL), "servers are healthy before first round")
+
+	assert.Eventually(t, func() bool {
+		return !c.Healthy(down.URL)
+	}, time.Second, 5*time.Millisecond)
+	assert.True(t, c.Healthy(up.URL))
+
+	res, ok := c.Last(down.URL)
+	require.True(t, ok)
+	assert.Equal(t, http.StatusInternalServerError, res.Status)
+	assert.False(t, res.Healthy)
+}
+
+// TestParseStatusRanges проверяет разбор диапазонов статусов.
+func TestParseStatusRanges(t *testing.T) {
+	ranges, err := ParseStatusRanges([]string{"200-299", " 404 "})
+	require.NoError(t, err)
+	assert.Equal(t, []StatusRange{{Min: 200, Max: 299}, {Min: 404, Max: 404}}, ranges)
+
+	for _, bad := range []string{"abc", "300-200", "200-x"} {
+		_, err := ParseStatusRanges([]string{bad})
+		assert.Error(t, err, bad)
+	}
+}
+
+// TestChecker_AddRemove проверяет изменение списка проверяемых серверов.
+func TestChecker_AddRemove(t *testing.T) {
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer down.Close()
+
+	c := newTestChecker(t, nil, Config{})
+	c.Add(down.URL)
+	c.Add(down.URL)
+	c.checkAll()
+	assert.False(t, c.Healthy(down.URL))
+
+	c.Remove(down.URL)
+	assert.True(t, c.Healthy(down.URL), "removed server is unknown")
+	_, ok := c.Last(down.URL)
+	assert.False(t, ok)
+}
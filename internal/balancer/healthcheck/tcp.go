@@ -0,0 +1,54 @@
+package healthcheck
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/url"
+)
+
+// probeTCP проверяет, что сервер принимает TCP-соединения.
+func (c *Checker) probeTCP(ctx context.Context, server string) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", hostPort(server))
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// probeTLS проверяет, что сервер принимает TCP-соединения и завершает TLS-рукопожатие.
+// Имя для SNI и проверки сертификата по умолчанию берётся из адреса сервера.
+func (c *Checker) probeTLS(ctx context.Context, server string) error {
+	d := tls.Dialer{Config: newTLSConfig(c.cfg)}
+	conn, err := d.DialContext(ctx, "tcp", hostPort(server))
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// newTLSConfig возвращает настройки TLS для проверок tls и grpc поверх https.
+func newTLSConfig(cfg Config) *tls.Config {
+	return &tls.Config{
+		ServerName:         cfg.TLSServerName,
+		InsecureSkipVerify: cfg.TLSSkipVerify,
+	}
+}
+
+// hostPort извлекает host:port из адреса сервера. Если порт не указан,
+// используется стандартный порт схемы. Адрес без схемы считается host:port.
+func hostPort(server string) string {
+	u, err := url.Parse(server)
+	if err != nil || u.Host == "" {
+		return server
+	}
+	if u.Port() != "" {
+		return u.Host
+	}
+	port := "80"
+	if u.Scheme == "https" || u.Scheme == "tls" {
+		port = "443"
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
@@ -0,0 +1,78 @@
+package healthcheck
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// listen запускает TCP-листенер, принимающий и сразу закрывающий соединения.
+func listen(t *testing.T) net.Listener {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			_ = conn.Close()
+		}
+	}()
+	return ln
+}
+
+// TestProbe_TCP проверяет TCP-проверку и выбор протокола для отдельного сервера.
+func TestProbe_TCP(t *testing.T) {
+	ln := listen(t)
+	tcpServer := "tcp://" + ln.Addr().String()
+
+	c := newTestChecker(t, []string{tcpServer}, Config{ServerTypes: map[string]string{tcpServer: TypeTCP}})
+	res := c.probe(tcpServer)
+	assert.True(t, res.Healthy, res.Error)
+	assert.Zero(t, res.Status)
+
+	require.NoError(t, ln.Close())
+	assert.False(t, c.probe(tcpServer).Healthy)
+}
+
+// TestProbe_TLS проверяет TLS-рукопожатие и проверку сертификата.
+func TestProbe_TLS(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	strict := newTestChecker(t, []string{srv.URL}, Config{Type: TypeTLS})
+	res := strict.probe(srv.URL)
+	assert.False(t, res.Healthy, "self-signed certificate must fail verification")
+	assert.Contains(t, res.Error, "certificate")
+
+	insecure := newTestChecker(t, []string{srv.URL}, Config{Type: TypeTLS, TLSSkipVerify: true})
+	assert.True(t, insecure.probe(srv.URL).Healthy)
+
+	// Листенер без TLS не завершает рукопожатие
+	ln := listen(t)
+	defer func() { _ = ln.Close() }()
+	plain := newTestChecker(t, nil, Config{Type: TypeTLS, TLSSkipVerify: true, Timeout: 100 * time.Millisecond})
+	assert.False(t, plain.probe("https://"+ln.Addr().String()).Healthy)
+}
+
+// TestHostPort проверяет извлечение адреса и порт по умолчанию.
+func TestHostPort(t *testing.T) {
+	assert.Equal(t, "10.0.0.1:9000", hostPort("http://10.0.0.1:9000"))
+	assert.Equal(t, "example.com:80", hostPort("http://example.com"))
+	assert.Equal(t, "example.com:443", hostPort("https://example.com/"))
+	assert.Equal(t, "db:5432", hostPort("tcp://db:5432"))
+}
+
+// TestNew_UnknownType проверяет отказ для неизвестного протокола.
+func TestNew_UnknownType(t *testing.T) {
+	_, err := New(nil, Config{Type: "udp"})
+	assert.Error(t, err)
+	_, err = New([]string{"A"}, Config{ServerTypes: map[string]string{"A": "icmp"}})
+	assert.Error(t, err)
+}
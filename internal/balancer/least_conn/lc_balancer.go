@@ -2,62 +2,97 @@ package least_conn
 
 import (
 	"github.com/coffee-realist/balancer/internal/balancer"
-	"sync"
-	"sync/atomic"
+	"net/http"
+	"time"
 )
 
+// failureCooldown — время, на которое сервер с ошибкой транспорта исключается из выбора.
+// Без него недоступный бэкенд, быстро отвечающий ошибкой, всегда имел бы
+// минимум соединений и забирал бы весь трафик.
+const failureCooldown = time.Second
+
 type LeastConnBalancer interface {
 	balancer.Balancer
 	balancer.ConnAware
 }
 
 // lcBalancer выбирает сервер с наименьшим числом активных соединений.
+// Счётчики соединений и состав пула хранятся во встроенном balancer.Pool.
 type lcBalancer struct {
-	servers []string
-	counts  map[string]*int64
-	mu      sync.RWMutex
+	*balancer.Pool
+	now    func() time.Time
+	health balancer.HealthSource // Внешний источник здоровья
 }
 
 // NewLeastConnBalancer создаёт Least-Conn балансировщик.
 func NewLeastConnBalancer(servers []string) LeastConnBalancer {
-	counts := make(map[string]*int64, len(servers))
-	for _, s := range servers {
-		var zero int64
-		counts[s] = &zero
-	}
 	return &lcBalancer{
-		servers: append([]string(nil), servers...),
-		counts:  counts,
+		Pool: balancer.NewPool(servers),
+		now:  time.Now,
 	}
 }
 
 // Next возвращает сервер с минимальным числом активных соединений.
 func (l *lcBalancer) Next() string {
-	l.mu.RLock()
-	defer l.mu.RUnlock()
-
-	var best string
-	var mn int64 = -1
-	for _, s := range l.servers {
-		cnt := atomic.LoadInt64(l.counts[s])
-		if mn < 0 || cnt < mn {
-			mn = cnt
-			best = s
+	srv, _ := l.Pick(nil)
+	return srv
+}
+
+// Pick возвращает сервер с минимальным числом активных соединений на единицу веса, запрос не учитывается.
+// Недоступные и выводимые из ротации серверы пропускаются, серверы с недавней ошибкой транспорта — пока есть другие.
+func (l *lcBalancer) Pick(_ *http.Request) (string, error) {
+	members := l.Snapshot()
+	if len(members) == 0 {
+		return "", balancer.ErrNoBackends
+	}
+
+	since := l.now().Add(-failureCooldown)
+	var best, fallback *balancer.Member
+	for _, m := range members {
+		if !m.Available() || !l.health.Healthy(m.Server) {
+			continue
+		}
+		if fallback == nil || lessLoaded(m, fallback) {
+			fallback = m
+		}
+		if m.FailedSince(since) {
+			continue
+		}
+		if best == nil || lessLoaded(m, best) {
+			best = m
 		}
 	}
-	return best
+	switch {
+	case best != nil:
+		return best.Server, nil
+	case fallback != nil:
+		// Все здоровые серверы недавно отвечали ошибкой — выбираем среди них
+		return fallback.Server, nil
+	default:
+		return "", balancer.ErrNoHealthyBackends
+	}
 }
 
-// Increase увеличивает счётчик для conn-aware стратегий.
-func (l *lcBalancer) Increase(server string) {
-	if ptr, ok := l.counts[server]; ok {
-		atomic.AddInt64(ptr, 1)
-	}
+// lessLoaded сравнивает нагрузку a и b с учётом весов: active_a/w_a < active_b/w_b.
+func lessLoaded(a, b *balancer.Member) bool {
+	return a.Active()*int64(b.Weight()) < b.Active()*int64(a.Weight())
 }
 
-// Decrease уменьшает счётчик.
-func (l *lcBalancer) Decrease(server string) {
-	if ptr, ok := l.counts[server]; ok {
-		atomic.AddInt64(ptr, -1)
+// SetHealth задаёт внешний источник здоровья бэкендов.
+func (l *lcBalancer) SetHealth(h balancer.Health) {
+	l.health.Set(h)
+}
+
+// Done запоминает ошибку транспорта, чтобы временно не выбирать сервер;
+// успешный ответ снимает ограничение, отменённая клиентом попытка не учитывается.
+func (l *lcBalancer) Done(server string, _ time.Duration, status int, err error) {
+	m, ok := l.Get(server)
+	if !ok || (status == 0 && err == nil) {
+		return
+	}
+	if err != nil {
+		m.SetFailed(l.now())
+		return
 	}
+	m.SetFailed(time.Time{})
 }
@@ -1,10 +1,13 @@
 package least_conn
 
 import (
+	"errors"
+	"net/http"
 	"sync"
-	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/coffee-realist/balancer/internal/balancer"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -53,6 +56,33 @@ func TestLeastConnBalancer_MultipleIncreases(t *testing.T) {
 	}
 }
 
+// TestLeastConnBalancer_Membership проверяет учёт весов и вывод сервера из ротации.
+func TestLeastConnBalancer_Membership(t *testing.T) {
+	lc := setupLC([]string{"A"}).(*lcBalancer)
+	assert.NoError(t, lc.Add("B", 3))
+
+	// B с весом 3 выдерживает втрое больше соединений, чем A
+	for i := 0; i < 8; i++ {
+		lc.Increase(lc.Next())
+	}
+	assert.Equal(t, int64(2), activeOf(lc, "A"))
+	assert.Equal(t, int64(6), activeOf(lc, "B"))
+
+	assert.NoError(t, lc.Drain("B"))
+	assert.Equal(t, "A", lc.Next())
+	for i := 0; i < 6; i++ {
+		lc.Decrease("B")
+	}
+	_, ok := lc.Get("B")
+	assert.False(t, ok, "drained server is removed after last request")
+}
+
+// activeOf возвращает число активных соединений сервера.
+func activeOf(lc LeastConnBalancer, server string) int64 {
+	m, _ := lc.(*lcBalancer).Get(server)
+	return m.Active()
+}
+
 // TestLeastConnBalancer_Concurrency тестирует многозадачность с несколькими горутинами
 func TestLeastConnBalancer_Concurrency(t *testing.T) {
 	servers := []string{"foo", "bar", "baz"}
@@ -70,9 +100,54 @@ func TestLeastConnBalancer_Concurrency(t *testing.T) {
 	wg.Wait()
 
 	// Проверяем, что после всех операций нагрузка на все серверы вернулась в исходное состояние
-	assert.Equal(t, int64(0), atomic.LoadInt64(lc.(*lcBalancer).counts["foo"]))
-	assert.Equal(t, int64(0), atomic.LoadInt64(lc.(*lcBalancer).counts["bar"]))
-	assert.Equal(t, int64(0), atomic.LoadInt64(lc.(*lcBalancer).counts["baz"]))
+	assert.Equal(t, int64(0), activeOf(lc, "foo"))
+	assert.Equal(t, int64(0), activeOf(lc, "bar"))
+	assert.Equal(t, int64(0), activeOf(lc, "baz"))
+}
+
+// TestLeastConnBalancer_DoneCooldown проверяет, что сервер с ошибкой транспорта
+// временно не выбирается, несмотря на минимум соединений.
+func TestLeastConnBalancer_DoneCooldown(t *testing.T) {
+	lc := setupLC([]string{"A", "B"}).(*lcBalancer)
+	now := time.Unix(100, 0)
+	lc.now = func() time.Time { return now }
+
+	lc.Increase("B")
+	lc.Done("A", time.Millisecond, 0, errors.New("connection refused"))
+	assert.Equal(t, "B", lc.Next(), "failed server is skipped")
+
+	// Если ошибки у всех, выбор идёт среди всех серверов
+	lc.Done("B", time.Millisecond, 0, errors.New("connection refused"))
+	assert.Equal(t, "A", lc.Next())
+
+	// По истечении паузы сервер возвращается в выбор
+	now = now.Add(failureCooldown + time.Millisecond)
+	lc.Done("B", time.Millisecond, 0, errors.New("connection refused"))
+	assert.Equal(t, "A", lc.Next())
+
+	// Успешный ответ снимает ограничение сразу
+	lc.Increase("A")
+	lc.Increase("A")
+	lc.Done("B", time.Millisecond, http.StatusOK, nil)
+	assert.Equal(t, "B", lc.Next())
+}
+
+// downSet — внешний источник здоровья, считающий недоступными перечисленные серверы.
+type downSet map[string]bool
+
+func (d downSet) Healthy(server string) bool { return !d[server] }
+
+// TestLeastConnBalancer_SkipsUnhealthy проверяет, что недоступный сервер не выбирается
+// даже при минимальном числе соединений.
+func TestLeastConnBalancer_SkipsUnhealthy(t *testing.T) {
+	lc := setupLC([]string{"A", "B"}).(*lcBalancer)
+	lc.Increase("B")
+	lc.SetHealth(downSet{"A": true})
+	assert.Equal(t, "B", lc.Next())
+
+	lc.SetHealth(downSet{"A": true, "B": true})
+	_, err := lc.Pick(nil)
+	assert.ErrorIs(t, err, balancer.ErrNoHealthyBackends)
 }
 
 // BenchmarkLeastConnBalancer_Next тестирует производительность метода Next при большом количестве запросов.
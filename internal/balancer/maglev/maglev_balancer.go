@@ -0,0 +1,226 @@
+package maglev
+
+import (
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/coffee-realist/balancer/internal/balancer"
+	"github.com/coffee-realist/balancer/internal/balancer/hashkey"
+)
+
+// DefaultTableSize — размер lookup-таблицы по умолчанию (простое число из статьи Maglev).
+const DefaultTableSize = 65537
+
+// minSlotsPerServer — наименьшее число ячеек таблицы на сервер. При меньшей таблице
+// распределение неравномерно, а часть серверов может не получить ни одной ячейки.
+const minSlotsPerServer = 100
+
+// MaglevBalancer реализует консистентное хеширование Maglev:
+// выбор сервера — одно обращение к таблице, а реплики балансировщика
+// с одинаковым набором серверов строят одинаковые таблицы без координации.
+type MaglevBalancer interface {
+	balancer.Balancer
+	balancer.RequestBalancer
+	balancer.RequestAware
+	balancer.Excluder
+}
+
+// table — неизменяемая lookup-таблица, построенная для набора здоровых серверов.
+type table struct {
+	servers []string // Здоровые серверы, на которые ссылаются записи
+	entries []int32  // Индекс сервера для каждой ячейки
+	version uint64   // Версия внешнего источника здоровья на момент сборки
+}
+
+// maglevBalancer хранит текущую таблицу и пересобирает её при изменении здоровья бэкендов.
+type maglevBalancer struct {
+	servers  []string              // Все бэкенды, отсортированные по имени
+	size     uint64                // Размер таблицы (простое число)
+	key      hashkey.Func          // Извлекатель ключа из запроса
+	lookup   atomic.Pointer[table] // Текущая таблица
+	seq      uint64                // Счетчик для запросов без ключа
+	muHealth sync.Mutex            // Защищает пересборку таблицы
+	health   balancer.HealthSource // Внешний источник здоровья (активные и пассивные проверки)
+}
+
+// NewMaglevBalancer создаёт Maglev-балансировщик. Состояние здоровья бэкендов
+// задаётся через SetHealth; таблица пересобирается при смене версии источника.
+// tableSize округляется вверх до простого числа, 0 означает DefaultTableSize.
+// Таблица меньше minSlotsPerServer ячеек на сервер увеличивается до этого размера.
+func NewMaglevBalancer(servers []string, key hashkey.Func, tableSize uint64) MaglevBalancer {
+	if tableSize == 0 {
+		tableSize = DefaultTableSize
+	}
+	// Порядок серверов не должен зависеть от конфигурации конкретной реплики
+	sorted := append([]string(nil), servers...)
+	sort.Strings(sorted)
+
+	b := &maglevBalancer{
+		servers: sorted,
+		size:    nextPrime(max(tableSize, uint64(len(servers))*minSlotsPerServer)),
+		key:     key,
+	}
+	b.lookup.Store(b.build(sorted))
+
+	return b
+}
+
+// Next выбирает сервер без учёта запроса: ключи генерируются по кругу.
+func (b *maglevBalancer) Next() string {
+	srv, _ := b.Pick(nil)
+	return srv
+}
+
+// NextFor выбирает сервер по ключу, извлечённому из запроса.
+func (b *maglevBalancer) NextFor(r *http.Request) string {
+	srv, _ := b.Pick(r)
+	return srv
+}
+
+// Pick возвращает сервер из ячейки таблицы, соответствующей хешу ключа.
+func (b *maglevBalancer) Pick(r *http.Request) (string, error) {
+	return b.pick(r, nil)
+}
+
+// PickExcluding берёт следующую за ячейкой ключа запись таблицы, сервер которой не из exclude,
+// поэтому запасной сервер для ключа тоже стабилен.
+func (b *maglevBalancer) PickExcluding(r *http.Request, exclude []string) (string, error) {
+	return b.pick(r, exclude)
+}
+
+// pick выбирает сервер по ячейке ключа, пропуская записи с серверами из exclude.
+func (b *maglevBalancer) pick(r *http.Request, exclude []string) (string, error) {
+	if len(b.servers) == 0 {
+		return "", balancer.ErrNoBackends
+	}
+	t := b.lookup.Load()
+	if t.version != b.health.Version() {
+		// Внешний источник сообщил об изменении — пересобираем таблицу
+		t = b.rebuild()
+	}
+	if len(t.servers) == 0 {
+		return "", balancer.ErrNoHealthyBackends
+	}
+
+	var key string
+	if r != nil && b.key != nil {
+		key = b.key(r)
+	}
+	if key == "" {
+		key = strconv.FormatUint(atomic.AddUint64(&b.seq, 1), 10)
+	}
+	slot := hashkey.Sum64(key) % b.size
+	if len(exclude) == 0 {
+		return t.servers[t.entries[slot]], nil
+	}
+	if !slices.ContainsFunc(t.servers, func(s string) bool { return !slices.Contains(exclude, s) }) {
+		return "", balancer.ErrNoAlternateBackend
+	}
+	for range b.size {
+		if srv := t.servers[t.entries[slot]]; !slices.Contains(exclude, srv) {
+			return srv, nil
+		}
+		slot = (slot + 1) % b.size
+	}
+	return "", balancer.ErrNoAlternateBackend
+}
+
+// build заполняет таблицу по алгоритму Maglev: серверы по очереди занимают
+// свободные ячейки в порядке своих перестановок (offset + j*skip) mod size.
+func (b *maglevBalancer) build(servers []string) *table {
+	t := &table{servers: servers}
+	n := len(servers)
+	if n == 0 {
+		return t
+	}
+
+	offsets := make([]uint64, n)
+	skips := make([]uint64, n)
+	next := make([]uint64, n)
+	for i, s := range servers {
+		offsets[i] = hashkey.Sum64(s) % b.size
+		skips[i] = hashkey.Sum64(s+"#skip")%(b.size-1) + 1
+	}
+
+	t.entries = make([]int32, b.size)
+	for i := range t.entries {
+		t.entries[i] = -1
+	}
+
+	var filled uint64
+	for {
+		for i := 0; i < n; i++ {
+			c := (offsets[i] + next[i]*skips[i]) % b.size
+			for t.entries[c] >= 0 {
+				next[i]++
+				c = (offsets[i] + next[i]*skips[i]) % b.size
+			}
+			t.entries[c] = int32(i)
+			next[i]++
+			filled++
+			if filled == b.size {
+				return t
+			}
+		}
+	}
+}
+
+// SetHealth задаёт внешний источник здоровья и сразу пересобирает таблицу.
+func (b *maglevBalancer) SetHealth(h balancer.Health) {
+	b.muHealth.Lock()
+	defer b.muHealth.Unlock()
+	b.health.Set(h)
+	b.rebuildLocked()
+}
+
+// rebuild пересобирает таблицу, если её ещё не пересобрал другой запрос.
+func (b *maglevBalancer) rebuild() *table {
+	b.muHealth.Lock()
+	defer b.muHealth.Unlock()
+	if t := b.lookup.Load(); t.version == b.health.Version() {
+		return t
+	}
+	return b.rebuildLocked()
+}
+
+// rebuildLocked собирает таблицу по серверам, здоровым по внешнему источнику;
+// вызывается под muHealth.
+func (b *maglevBalancer) rebuildLocked() *table {
+	version := b.health.Version()
+	healthy := make([]string, 0, len(b.servers))
+	for _, s := range b.servers {
+		if b.health.Healthy(s) {
+			healthy = append(healthy, s)
+		}
+	}
+	t := b.build(healthy)
+	t.version = version
+	b.lookup.Store(t)
+	return t
+}
+
+// nextPrime возвращает наименьшее простое число, не меньшее n.
+func nextPrime(n uint64) uint64 {
+	if n <= 2 {
+		return 2
+	}
+	if n%2 == 0 {
+		n++
+	}
+	for ; ; n += 2 {
+		prime := true
+		for d := uint64(3); d*d <= n; d += 2 {
+			if n%d == 0 {
+				prime = false
+				break
+			}
+		}
+		if prime {
+			return n
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package maglev
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/coffee-realist/balancer/internal/balancer"
+	"github.com/coffee-realist/balancer/internal/balancer/hashkey"
+	"github.com/coffee-realist/balancer/internal/balancer/healthcheck"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestBalancer создаёт Maglev с ключом из заголовка X-Key.
+func newTestBalancer(t testing.TB, servers []string, size uint64) *maglevBalancer {
+	key, err := hashkey.New(hashkey.SourceHeader, "X-Key")
+	require.NoError(t, err)
+	return NewMaglevBalancer(servers, key, size).(*maglevBalancer)
+}
+
+// requestWithKey создаёт запрос с ключом в заголовке X-Key.
+func requestWithKey(key string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "http://lb/", nil)
+	req.Header.Set("X-Key", key)
+	return req
+}
+
+// TestMaglev_TableIsBalanced проверяет, что ячейки таблицы распределены почти поровну.
+func TestMaglev_TableIsBalanced(t *testing.T) {
+	servers := []string{"A", "B", "C", "D", "E"}
+	b := newTestBalancer(t, servers, 0)
+	tbl := b.lookup.Load()
+	assert.Equal(t, uint64(DefaultTableSize), uint64(len(tbl.entries)))
+
+	counts := make(map[int32]int)
+	for _, e := range tbl.entries {
+		counts[e]++
+	}
+	avg := DefaultTableSize / len(servers)
+	for i := range servers {
+		assert.InDelta(t, avg, counts[int32(i)], float64(avg)/100, "server %s", servers[i])
+	}
+}
+
+// TestMaglev_ReplicasAgree проверяет, что реплики с разным порядком серверов в конфиге
+// выбирают один и тот же сервер для ключа.
+func TestMaglev_ReplicasAgree(t *testing.T) {
+	b1 := newTestBalancer(t, []string{"A", "B", "C", "D"}, 1009)
+	b2 := newTestBalancer(t, []string{"D", "C", "B", "A"}, 1009)
+
+	for i := 0; i < 500; i++ {
+		req := requestWithKey("k" + strconv.Itoa(i))
+		s1, err := b1.Pick(req)
+		require.NoError(t, err)
+		s2, err := b2.Pick(req)
+		require.NoError(t, err)
+		assert.Equal(t, s1, s2)
+	}
+}
+
+// TestMaglev_MinimalDisruption проверяет, что при падении сервера
+// переезжает лишь небольшая доля ключей остальных серверов.
+func TestMaglev_MinimalDisruption(t *testing.T) {
+	b := newTestBalancer(t, []string{"A", "B", "C", "D", "E"}, 0)
+	ext := &versionedDown{down: map[string]bool{}}
+	b.SetHealth(ext)
+
+	const keys = 2000
+	before := make([]string, keys)
+	for i := range before {
+		before[i] = b.NextFor(requestWithKey("k" + strconv.Itoa(i)))
+	}
+
+	ext.set("C", true)
+
+	moved := 0
+	for i := range before {
+		now := b.NextFor(requestWithKey("k" + strconv.Itoa(i)))
+		assert.NotEqual(t, "C", now)
+		if before[i] != "C" && before[i] != now {
+			moved++
+		}
+	}
+	assert.Less(t, moved, keys/20, "too many keys of healthy servers moved")
+
+	// После восстановления таблица возвращается к исходной
+	ext.set("C", false)
+	for i := range before {
+		assert.Equal(t, before[i], b.NextFor(requestWithKey("k"+strconv.Itoa(i))))
+	}
+}
+
+// TestMaglev_Errors проверяет ошибки при отсутствии серверов и здоровых серверов.
+func TestMaglev_Errors(t *testing.T) {
+	empty := newTestBalancer(t, nil, 7)
+	_, err := empty.Pick(nil)
+	assert.ErrorIs(t, err, balancer.ErrNoBackends)
+
+	b := newTestBalancer(t, []string{"A"}, 7)
+	b.SetHealth(&versionedDown{down: map[string]bool{"A": true}})
+	_, err = b.Pick(requestWithKey("k"))
+	assert.ErrorIs(t, err, balancer.ErrNoHealthyBackends)
+	assert.Equal(t, "", b.Next())
+}
+
+// TestMaglev_PickExcluding проверяет, что исключённый сервер заменяется другим, а при
+// исключении всех серверов возвращается ErrNoAlternateBackend.
+func TestMaglev_PickExcluding(t *testing.T) {
+	b := newTestBalancer(t, []string{"A", "B", "C"}, 0)
+	req := requestWithKey("user-1")
+	home, err := b.Pick(req)
+	require.NoError(t, err)
+
+	alt, err := b.PickExcluding(req, []string{home})
+	require.NoError(t, err)
+	assert.NotEqual(t, home, alt)
+	again, err := b.PickExcluding(req, []string{home})
+	require.NoError(t, err)
+	assert.Equal(t, alt, again, "alternate must be stable for the key")
+
+	_, err = b.PickExcluding(req, []string{"A", "B", "C"})
+	assert.ErrorIs(t, err, balancer.ErrNoAlternateBackend)
+}
+
+// TestMaglev_SmallTablePickExcluding проверяет, что таблица меньше числа серверов
+// увеличивается: каждый сервер получает ячейку, и PickExcluding находит единственный
+// неисключённый сервер, а не перебирает таблицу бесконечно.
+func TestMaglev_SmallTablePickExcluding(t *testing.T) {
+	servers := []string{"A", "B", "C", "D", "E"}
+	b := newTestBalancer(t, servers, 2)
+	assert.GreaterOrEqual(t, b.size, uint64(len(servers))*minSlotsPerServer)
+
+	for _, want := range servers {
+		var exclude []string
+		for _, s := range servers {
+			if s != want {
+				exclude = append(exclude, s)
+			}
+		}
+		got, err := b.PickExcluding(requestWithKey("user-1"), exclude)
+		require.NoError(t, err)
+		assert.Equal(t, want, got)
+	}
+}
+
+// TestMaglev_HealthCheckerRebuildsTable проверяет, что бэкенд, не прошедший
+// активную проверку, исключается из таблицы.
+func TestMaglev_HealthCheckerRebuildsTable(t *testing.T) {
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer up.Close()
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+
+	servers := []string{up.URL, down.URL}
+	hc, err := healthcheck.New(servers, healthcheck.Config{Interval: 20 * time.Millisecond})
+	require.NoError(t, err)
+	defer hc.Stop()
+	b := NewMaglevBalancer(servers, nil, 101)
+	b.(*maglevBalancer).SetHealth(hc)
+
+	assert.Eventually(t, func() bool {
+		// Таблица пересобирается при выборе после смены версии проверок
+		_ = b.Next()
+		return len(b.(*maglevBalancer).lookup.Load().servers) == 1
+	}, time.Second, 10*time.Millisecond)
+	for i := 0; i < 20; i++ {
+		assert.Equal(t, up.URL, b.Next())
+	}
+}
+
+// versionedDown — внешний источник здоровья с номером версии.
+type versionedDown struct {
+	down    map[string]bool
+	version uint64
+}
+
+func (v *versionedDown) Healthy(server string) bool { return !v.down[server] }
+func (v *versionedDown) Version() uint64            { return v.version }
+
+// set меняет состояние сервера и версию источника.
+func (v *versionedDown) set(server string, down bool) {
+	v.down[server] = down
+	v.version++
+}
+
+// TestMaglev_ExternalHealthVersion проверяет пересборку таблицы при смене версии внешнего источника.
+func TestMaglev_ExternalHealthVersion(t *testing.T) {
+	b := newTestBalancer(t, []string{"A", "B", "C"}, 101)
+	ext := &versionedDown{down: map[string]bool{}}
+	b.SetHealth(ext)
+	assert.Len(t, b.lookup.Load().servers, 3)
+
+	ext.set("B", true)
+	for i := 0; i < 50; i++ {
+		assert.NotEqual(t, "B", b.NextFor(requestWithKey("k"+strconv.Itoa(i))))
+	}
+	assert.Len(t, b.lookup.Load().servers, 2)
+}
+
+// TestNextPrime проверяет округление размера таблицы до простого числа.
+func TestNextPrime(t *testing.T) {
+	assert.Equal(t, uint64(2), nextPrime(1))
+	assert.Equal(t, uint64(101), nextPrime(100))
+	assert.Equal(t, uint64(65537), nextPrime(65537))
+}
+
+// BenchmarkMaglev_NextFor измеряет производительность выбора по ключу при 100 серверах.
+func BenchmarkMaglev_NextFor(b *testing.B) {
+	servers := make([]string, 100)
+	for i := range servers {
+		servers[i] = "s" + strconv.Itoa(i)
+	}
+	m := newTestBalancer(b, servers, 0)
+	req := requestWithKey("bench")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = m.NextFor(req)
+	}
+}
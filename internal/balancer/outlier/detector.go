@@ -0,0 +1,260 @@
+package outlier
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Значения по умолчанию (совпадают с outlier detection в Envoy).
+const (
+	DefaultConsecutiveErrors        = 5
+	DefaultInterval                 = 10 * time.Second
+	DefaultBaseEjectionTime         = 30 * time.Second
+	DefaultMaxEjectionPercent       = 10
+	DefaultSuccessRateMinHosts      = 5
+	DefaultSuccessRateRequestVolume = 100
+	DefaultSuccessRateStdevFactor   = 1.9
+)
+
+// Config описывает параметры обнаружения выбросов. Нулевые поля заменяются значениями по умолчанию.
+type Config struct {
+	ConsecutiveErrors        int           // Подряд идущих 5xx или ошибок шлюза до исключения
+	Interval                 time.Duration // Период анализа доли успехов и возврата серверов
+	BaseEjectionTime         time.Duration // Базовое время исключения, умножается на число исключений
+	MaxEjectionPercent       int           // Максимальная доля исключённых серверов, %
+	SuccessRateMinHosts      int           // Минимум серверов с достаточным трафиком для анализа доли успехов
+	SuccessRateRequestVolume int64         // Минимум запросов к серверу за интервал для анализа
+	SuccessRateStdevFactor   float64       // Исключается сервер с долей успехов ниже mean - factor*stdev
+}
+
+// host — состояние одного бэкенда.
+type host struct {
+	ejected     atomic.Bool // Исключён ли сервер (читается без блокировки)
+	consecutive int         // Подряд идущие ошибки
+	success     int64       // Успешные запросы за текущий интервал
+	total       int64       // Все запросы за текущий интервал
+	ejections   int         // Множитель времени исключения
+	until       time.Time   // Момент возврата в ротацию
+}
+
+// Detector исключает бэкенды по результатам реальных запросов:
+// после серии ошибок подряд или при доле успехов заметно ниже средней по пулу.
+// Реализует balancer.FeedbackAware (сбор результатов) и balancer.Health (состояние),
+// поэтому подходит для любой стратегии, поддерживающей balancer.HealthAware.
+type Detector struct {
+	cfg     Config
+	mu      sync.RWMutex
+	hosts   map[string]*host
+	version atomic.Uint64 // Увеличивается при каждом исключении и возврате
+	now     func() time.Time
+	stop    chan struct{}
+}
+
+// NewDetector создаёт детектор для набора серверов и запускает периодический анализ.
+func NewDetector(servers []string, cfg Config) *Detector {
+	if cfg.ConsecutiveErrors <= 0 {
+		cfg.ConsecutiveErrors = DefaultConsecutiveErrors
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultInterval
+	}
+	if cfg.BaseEjectionTime <= 0 {
+		cfg.BaseEjectionTime = DefaultBaseEjectionTime
+	}
+	if cfg.MaxEjectionPercent <= 0 {
+		cfg.MaxEjectionPercent = DefaultMaxEjectionPercent
+	}
+	if cfg.SuccessRateMinHosts <= 0 {
+		cfg.SuccessRateMinHosts = DefaultSuccessRateMinHosts
+	}
+	if cfg.SuccessRateRequestVolume <= 0 {
+		cfg.SuccessRateRequestVolume = DefaultSuccessRateRequestVolume
+	}
+	if cfg.SuccessRateStdevFactor <= 0 {
+		cfg.SuccessRateStdevFactor = DefaultSuccessRateStdevFactor
+	}
+
+	hosts := make(map[string]*host, len(servers))
+	for _, s := range servers {
+		hosts[s] = &host{}
+	}
+	d := &Detector{
+		cfg:   cfg,
+		hosts: hosts,
+		now:   time.Now,
+		stop:  make(chan struct{}),
+	}
+
+	// Запуск периодического анализа
+	go d.run()
+
+	return d
+}
+
+// Healthy сообщает, что сервер не исключён. Неизвестные серверы считаются здоровыми.
+func (d *Detector) Healthy(server string) bool {
+	d.mu.RLock()
+	h, ok := d.hosts[server]
+	d.mu.RUnlock()
+	return !ok || !h.ejected.Load()
+}
+
+// Version возвращает номер версии состояния, меняющийся при каждом исключении и возврате.
+func (d *Detector) Version() uint64 {
+	return d.version.Load()
+}
+
+// Done учитывает результат запроса. Ошибкой считаются ошибка транспорта и любой 5xx.
+// Попытки, отменённые клиентом (без статуса и ошибки или с context.Canceled), не учитываются:
+// массовый уход клиентов не должен исключать здоровые бэкенды.
+func (d *Detector) Done(server string, _ time.Duration, status int, err error) {
+	if status == 0 && (err == nil || errors.Is(err, context.Canceled)) {
+		return
+	}
+	failed := err != nil || status >= 500
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	h, ok := d.hosts[server]
+	if !ok {
+		return
+	}
+	h.total++
+	if !failed {
+		h.success++
+		h.consecutive = 0
+		return
+	}
+	h.consecutive++
+	if h.consecutive >= d.cfg.ConsecutiveErrors && !h.ejected.Load() {
+		d.eject(h, d.now())
+	}
+}
+
+// Add начинает учитывать сервер, добавленный в пул во время работы.
+func (d *Detector) Add(server string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.hosts[server]; !ok {
+		d.hosts[server] = &host{}
+	}
+}
+
+// Remove забывает сервер, удалённый из пула.
+func (d *Detector) Remove(server string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if h, ok := d.hosts[server]; ok && h.ejected.Load() {
+		// Состояние сервера меняется с исключённого на неизвестный (здоровый)
+		d.version.Add(1)
+	}
+	delete(d.hosts, server)
+}
+
+// Stop останавливает периодический анализ.
+func (d *Detector) Stop() {
+	close(d.stop)
+}
+
+// run выполняет анализ каждые cfg.Interval.
+func (d *Detector) run() {
+	ticker := time.NewTicker(d.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			d.tick()
+		case <-d.stop:
+			return
+		}
+	}
+}
+
+// tick возвращает серверы с истёкшим временем исключения, постепенно уменьшает
+// множитель у стабильных серверов и исключает серверы с низкой долей успехов.
+func (d *Detector) tick() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	now := d.now()
+
+	for _, h := range d.hosts {
+		switch {
+		case h.ejected.Load() && !now.Before(h.until):
+			h.ejected.Store(false)
+			h.consecutive = 0
+			d.version.Add(1)
+		case !h.ejected.Load() && h.ejections > 0:
+			h.ejections--
+		}
+	}
+
+	d.checkSuccessRate(now)
+
+	for _, h := range d.hosts {
+		h.success, h.total = 0, 0
+	}
+}
+
+// checkSuccessRate исключает серверы, доля успехов которых ниже mean - factor*stdev.
+// Анализ выполняется, только если достаточно серверов получили достаточно запросов.
+func (d *Detector) checkSuccessRate(now time.Time) {
+	rates := make(map[*host]float64)
+	for _, h := range d.hosts {
+		if !h.ejected.Load() && h.total >= d.cfg.SuccessRateRequestVolume {
+			rates[h] = float64(h.success) / float64(h.total)
+		}
+	}
+	if len(rates) < d.cfg.SuccessRateMinHosts {
+		return
+	}
+
+	var sum float64
+	for _, r := range rates {
+		sum += r
+	}
+	mean := sum / float64(len(rates))
+	var variance float64
+	for _, r := range rates {
+		variance += (r - mean) * (r - mean)
+	}
+	stdev := math.Sqrt(variance / float64(len(rates)))
+	threshold := mean - d.cfg.SuccessRateStdevFactor*stdev
+
+	for h, r := range rates {
+		if r < threshold {
+			d.eject(h, now)
+		}
+	}
+}
+
+// eject исключает сервер на BaseEjectionTime × число исключений,
+// если это не превысит MaxEjectionPercent пула; вызывается под mu.
+func (d *Detector) eject(h *host, now time.Time) {
+	ejected := 0
+	for _, other := range d.hosts {
+		if other.ejected.Load() {
+			ejected++
+		}
+	}
+	if ejected >= d.maxEjected() {
+		return
+	}
+
+	h.ejections++
+	h.until = now.Add(d.cfg.BaseEjectionTime * time.Duration(h.ejections))
+	h.ejected.Store(true)
+	h.consecutive = 0
+	d.version.Add(1)
+}
+
+// maxEjected возвращает допустимое число исключённых серверов. Как и в Envoy, хотя бы
+// один сервер может быть исключён даже при маленьком проценте, но хотя бы один
+// всегда остаётся в ротации — даже при max_ejection_percent: 100 и в пуле из одного сервера.
+func (d *Detector) maxEjected() int {
+	limit := max(len(d.hosts)*d.cfg.MaxEjectionPercent/100, 1)
+	return max(min(limit, len(d.hosts)-1), 0)
+}
@@ -0,0 +1,189 @@
+package outlier
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// setupDetector создаёт детектор с управляемым временем; периодический анализ
+// вызывается в тестах вручную через tick.
+func setupDetector(servers []string, cfg Config) (*Detector, *time.Time) {
+	cfg.Interval = time.Hour
+	d := NewDetector(servers, cfg)
+	now := time.Unix(1000, 0)
+	d.now = func() time.Time { return now }
+	return d, &now
+}
+
+// fail сообщает детектору n неудачных запросов к серверу.
+func fail(d *Detector, server string, n int) {
+	for i := 0; i < n; i++ {
+		d.Done(server, time.Millisecond, http.StatusBadGateway, nil)
+	}
+}
+
+// TestDetector_ConsecutiveErrors проверяет исключение после серии ошибок подряд.
+func TestDetector_ConsecutiveErrors(t *testing.T) {
+	d, _ := setupDetector([]string{"A", "B"}, Config{ConsecutiveErrors: 3, MaxEjectionPercent: 50})
+	defer d.Stop()
+
+	fail(d, "A", 2)
+	d.Done("A", time.Millisecond, http.StatusOK, nil) // успех обнуляет серию
+	fail(d, "A", 2)
+	assert.True(t, d.Healthy("A"))
+
+	d.Done("A", time.Millisecond, 0, errors.New("connection refused"))
+	assert.False(t, d.Healthy("A"), "third consecutive failure ejects")
+	assert.True(t, d.Healthy("B"))
+	assert.True(t, d.Healthy("unknown"), "unknown servers are healthy")
+	assert.Equal(t, uint64(1), d.Version())
+}
+
+// TestDetector_IgnoresCanceled проверяет, что отменённые клиентом запросы не считаются ошибками.
+func TestDetector_IgnoresCanceled(t *testing.T) {
+	d, _ := setupDetector([]string{"A", "B"}, Config{ConsecutiveErrors: 3, MaxEjectionPercent: 50})
+	defer d.Stop()
+
+	for range 5 {
+		d.Done("A", time.Millisecond, 0, context.Canceled)
+		d.Done("A", time.Millisecond, 0, nil)
+	}
+	assert.True(t, d.Healthy("A"))
+
+	// Отмена не прерывает серию настоящих ошибок
+	fail(d, "A", 2)
+	d.Done("A", time.Millisecond, 0, context.Canceled)
+	fail(d, "A", 1)
+	assert.False(t, d.Healthy("A"))
+}
+
+// TestDetector_AddRemove проверяет учёт серверов, добавленных и удалённых во время работы.
+func TestDetector_AddRemove(t *testing.T) {
+	d, _ := setupDetector([]string{"A"}, Config{ConsecutiveErrors: 1, MaxEjectionPercent: 50})
+	defer d.Stop()
+
+	d.Add("B")
+	fail(d, "B", 1)
+	assert.False(t, d.Healthy("B"), "added server is tracked")
+	assert.Equal(t, uint64(1), d.Version())
+
+	d.Remove("B")
+	assert.True(t, d.Healthy("B"), "removed server is unknown")
+	assert.Equal(t, uint64(2), d.Version(), "removing ejected server changes state")
+}
+
+// TestDetector_EjectionTimeGrows проверяет, что время исключения растёт с числом исключений.
+func TestDetector_EjectionTimeGrows(t *testing.T) {
+	d, now := setupDetector([]string{"A", "B"}, Config{
+		ConsecutiveErrors: 1, BaseEjectionTime: 10 * time.Second, MaxEjectionPercent: 50,
+	})
+	defer d.Stop()
+
+	fail(d, "A", 1)
+	*now = now.Add(9 * time.Second)
+	d.tick()
+	assert.False(t, d.Healthy("A"), "still ejected before base time")
+	*now = now.Add(time.Second)
+	d.tick()
+	assert.True(t, d.Healthy("A"), "returned after base time")
+
+	// Повторное исключение длится вдвое дольше
+	fail(d, "A", 1)
+	*now = now.Add(10 * time.Second)
+	d.tick()
+	assert.False(t, d.Healthy("A"))
+	*now = now.Add(10 * time.Second)
+	d.tick()
+	assert.True(t, d.Healthy("A"))
+}
+
+// TestDetector_MaxEjectionPercent проверяет, что весь пул не может быть исключён.
+func TestDetector_MaxEjectionPercent(t *testing.T) {
+	servers := []string{"A", "B", "C", "D"}
+	d, _ := setupDetector(servers, Config{ConsecutiveErrors: 1, MaxEjectionPercent: 50})
+	defer d.Stop()
+
+	for _, s := range servers {
+		fail(d, s, 1)
+	}
+	healthy := 0
+	for _, s := range servers {
+		if d.Healthy(s) {
+			healthy++
+		}
+	}
+	assert.Equal(t, 2, healthy, "at most half of the pool is ejected")
+
+	// Хотя бы один сервер исключается даже при маленьком проценте
+	small, _ := setupDetector(servers, Config{ConsecutiveErrors: 1, MaxEjectionPercent: 1})
+	defer small.Stop()
+	fail(small, "A", 1)
+	assert.False(t, small.Healthy("A"))
+
+	// Даже при 100% один сервер остаётся в ротации
+	all, _ := setupDetector(servers, Config{ConsecutiveErrors: 1, MaxEjectionPercent: 100})
+	defer all.Stop()
+	for _, s := range servers {
+		fail(all, s, 1)
+	}
+	assert.True(t, all.Healthy("D"), "last server is never ejected")
+
+	// Единственный сервер пула не исключается
+	single, _ := setupDetector([]string{"A"}, Config{ConsecutiveErrors: 1, MaxEjectionPercent: 100})
+	defer single.Stop()
+	fail(single, "A", 1)
+	assert.True(t, single.Healthy("A"))
+}
+
+// TestDetector_SuccessRate проверяет исключение сервера с долей успехов заметно ниже средней.
+func TestDetector_SuccessRate(t *testing.T) {
+	servers := []string{"A", "B", "C", "D", "E"}
+	d, _ := setupDetector(servers, Config{
+		ConsecutiveErrors: 1000, MaxEjectionPercent: 50,
+		SuccessRateMinHosts: 5, SuccessRateRequestVolume: 100,
+	})
+	defer d.Stop()
+
+	for _, s := range servers {
+		for i := 0; i < 100; i++ {
+			// E отвечает ошибкой на каждый второй запрос, остальные — на каждый сотый
+			status := http.StatusOK
+			if (s == "E" && i%2 == 0) || i == 0 {
+				status = http.StatusInternalServerError
+			}
+			d.Done(s, time.Millisecond, status, nil)
+		}
+	}
+	d.tick()
+	assert.False(t, d.Healthy("E"))
+	for _, s := range servers[:4] {
+		assert.True(t, d.Healthy(s), "server %s", s)
+	}
+
+	// Без достаточного трафика анализ не выполняется
+	fail(d, "A", 50)
+	d.tick()
+	assert.True(t, d.Healthy("A"))
+}
+
+// BenchmarkDetector_DoneHealthy измеряет стоимость учёта результата и проверки здоровья.
+func BenchmarkDetector_DoneHealthy(b *testing.B) {
+	servers := make([]string, 10)
+	for i := range servers {
+		servers[i] = "s" + strconv.Itoa(i)
+	}
+	d := NewDetector(servers, Config{})
+	defer d.Stop()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		srv := servers[i%len(servers)]
+		d.Done(srv, time.Millisecond, http.StatusOK, nil)
+		_ = d.Healthy(srv)
+	}
+}
@@ -5,163 +5,122 @@ import (
 	"math/rand"
 	"net/http"
 	"sync"
-	"sync/atomic"
 	"time"
 )
 
+// failureCooldown — время, на которое сервер с ошибкой транспорта исключается из выбора
+// (пассивная проверка здоровья до следующего отчёта активных проверок).
+const failureCooldown = time.Second
+
 // IP2CBalancer реализует балансировщик с алгоритмом Power of Two Choices,
-// совмещая выбор сервера, управление соединениями и контроль здоровья бэкендов.
+// совмещая выбор сервера и управление соединениями.
 type IP2CBalancer interface {
 	balancer.Balancer
 	balancer.ConnAware
-	balancer.Stoppable
 }
 
 // p2cBalancer использует алгоритм "Power of Two Choices" для выбора наименее нагруженного сервера
 // из двух случайно выбранных кандидатов, учитывая текущее состояние здоровья бэкендов.
+// Счётчики соединений и состав пула хранятся во встроенном balancer.Pool.
 type p2cBalancer struct {
-	servers    []string          // Список всех доступных бэкендов
-	counts     map[string]*int64 // Атомарные счетчики активных соединений для каждого сервера
-	rand       *rand.Rand        // Генератор случайных чисел для выбора кандидатов
-	health     map[string]bool   // Текущее состояние здоровья серверов
-	muHealth   sync.RWMutex      // RWMutex для синхронизации доступа к health map
-	hcInterval time.Duration     // Интервал проверки здоровья бэкендов
-	hcClient   *http.Client      // HTTP клиент для health checks
-	stopHealth chan struct{}     // Канал для остановки health checks
+	*balancer.Pool
+	rand   *rand.Rand            // Генератор случайных чисел для выбора кандидатов
+	muRand sync.Mutex            // rand.Rand не потокобезопасен
+	health balancer.HealthSource // Внешний источник здоровья (активные и пассивные проверки)
+	now    func() time.Time
 }
 
-// NewP2CBalancer создает новый экземпляр балансировщика с фоновыми проверками здоровья.
-// hcInterval определяет частоту проверок работоспособности бэкендов.
-func NewP2CBalancer(servers []string, hcInterval time.Duration) IP2CBalancer {
-	// Инициализация счетчиков соединений
-	c := make(map[string]*int64, len(servers))
-	for _, s := range servers {
-		var zero int64 = 0
-		c[s] = &zero
-	}
-
+// NewP2CBalancer создает новый экземпляр балансировщика.
+// Состояние здоровья бэкендов задаётся через SetHealth (например, healthcheck.Checker).
+func NewP2CBalancer(servers []string) IP2CBalancer {
 	// Инициализация генератора случайных чисел с уникальным сидом
 	r := rand.New(rand.NewSource(time.Now().UnixNano()))
 
-	h := make(map[string]bool, len(servers))
-	for _, server := range servers {
-		h[server] = true
+	return &p2cBalancer{
+		Pool: balancer.NewPool(servers),
+		rand: r,
+		now:  time.Now,
 	}
-
-	b := &p2cBalancer{
-		servers:    servers,
-		counts:     c,
-		rand:       r,
-		health:     h,
-		hcInterval: hcInterval,
-		hcClient:   &http.Client{Timeout: 1 * time.Second},
-		stopHealth: make(chan struct{}),
-	}
-
-	// Запуск фоновых проверок здоровья
-	go b.startHealthChecks()
-
-	return b
 }
 
 // Next реализует алгоритм выбора сервера:
 // 1. Выбирает два случайных здоровых бэкенда
 // 2. Возвращает сервер с наименьшим количеством активных соединений
 func (b *p2cBalancer) Next() string {
+	srv, _ := b.Pick(nil)
+	return srv
+}
+
+// Pick выбирает сервер так же, как Next, но различает отсутствие серверов
+// (ErrNoBackends) и отсутствие здоровых серверов (ErrNoHealthyBackends).
+func (b *p2cBalancer) Pick(_ *http.Request) (string, error) {
+	if len(b.Snapshot()) == 0 {
+		return "", balancer.ErrNoBackends
+	}
 	healthy := b.getHealthyServers()
 	n := len(healthy)
 	if n == 0 {
-		return ""
+		return "", balancer.ErrNoHealthyBackends
 	}
 	if n == 1 {
-		return healthy[0]
+		return healthy[0].Server, nil
 	}
 
 	// Выбор двух случайных кандидатов
+	b.muRand.Lock()
 	i1, i2 := b.rand.Intn(n), b.rand.Intn(n)
 	if i1 == i2 {
 		i2 = b.rand.Intn(n)
 	}
+	b.muRand.Unlock()
 	s1, s2 := healthy[i1], healthy[i2]
 
-	// Сравнение нагрузки с использованием атомарных операций
-	if atomic.LoadInt64(b.counts[s1]) <= atomic.LoadInt64(b.counts[s2]) {
-		return s1
+	// Сравнение нагрузки на единицу веса
+	if s1.Active()*int64(s2.Weight()) <= s2.Active()*int64(s1.Weight()) {
+		return s1.Server, nil
 	}
-	return s2
+	return s2.Server, nil
 }
 
-// getHealthyServers возвращает список доступных серверов с учетом текущего состояния здоровья
-func (b *p2cBalancer) getHealthyServers() []string {
-	b.muHealth.RLock()
-	defer b.muHealth.RUnlock()
-	healthy := make([]string, 0, len(b.servers))
-	for _, s := range b.servers {
-		if b.health[s] {
-			healthy = append(healthy, s)
+// getHealthyServers возвращает список доступных серверов с учетом внешнего источника здоровья
+// и вывода из ротации. Серверы с недавней ошибкой транспорта пропускаются, пока есть другие.
+func (b *p2cBalancer) getHealthyServers() []*balancer.Member {
+	since := b.now().Add(-failureCooldown)
+	members := b.Snapshot()
+	healthy := make([]*balancer.Member, 0, len(members))
+	var failed []*balancer.Member
+	for _, m := range members {
+		if !m.Available() || !b.health.Healthy(m.Server) {
+			continue
 		}
-	}
-
-	return healthy
-}
-
-// startHealthChecks запускает периодические проверки здоровья бэкендов
-func (b *p2cBalancer) startHealthChecks() {
-	ticker := time.NewTicker(b.hcInterval)
-	defer ticker.Stop()
-	for {
-		select {
-		case <-ticker.C:
-			// Параллельная проверка всех серверов
-			for _, srv := range b.servers {
-				go b.checkOne(srv)
-			}
-		case <-b.stopHealth:
-			return
+		if m.FailedSince(since) {
+			failed = append(failed, m)
+			continue
 		}
+		healthy = append(healthy, m)
 	}
-}
-
-// checkOne выполняет HTTP HEAD запрос для проверки здоровья сервера
-func (b *p2cBalancer) checkOne(server string) {
-	req, _ := http.NewRequest("HEAD", server+"/health", nil)
-	resp, err := b.hcClient.Do(req)
-	up := err == nil && resp.StatusCode < 500
-
-	// Обновление состояния с блокировкой записи
-	b.muHealth.Lock()
-	b.health[server] = up
-	b.muHealth.Unlock()
-
-	if resp != nil {
-		err := resp.Body.Close()
-		if err != nil {
-			return
-		}
+	if len(healthy) == 0 {
+		// Все здоровые серверы недавно отвечали ошибкой — выбираем среди них
+		return failed
 	}
+	return healthy
 }
 
-// Stop корректно завершает работу health checks
-func (b *p2cBalancer) Stop() {
-	close(b.stopHealth)
+// SetHealth задаёт внешний источник здоровья бэкендов.
+func (b *p2cBalancer) SetHealth(h balancer.Health) {
+	b.health.Set(h)
 }
 
-// Increase атомарно увеличивает счетчик активных соединений для сервера
-func (b *p2cBalancer) Increase(server string) {
-	if server == "" {
+// Done временно исключает сервер при ошибке транспорта (пассивная проверка здоровья);
+// успешный ответ снимает ограничение, отменённая клиентом попытка не учитывается.
+func (b *p2cBalancer) Done(server string, _ time.Duration, status int, err error) {
+	m, ok := b.Get(server)
+	if !ok || (status == 0 && err == nil) {
 		return
 	}
-	if ptr, ok := b.counts[server]; ok {
-		atomic.AddInt64(ptr, 1)
-	}
-}
-
-// Decrease атомарно уменьшает счетчик активных соединений для сервера
-func (b *p2cBalancer) Decrease(server string) {
-	if server == "" {
+	if err != nil {
+		m.SetFailed(b.now())
 		return
 	}
-	if ptr, ok := b.counts[server]; ok {
-		atomic.AddInt64(ptr, -1)
-	}
+	m.SetFailed(time.Time{})
 }
@@ -1,6 +1,7 @@
 package p2c
 
 import (
+	"errors"
 	"math/rand"
 	"net/http"
 	"net/http/httptest"
@@ -8,10 +9,13 @@ import (
 	"time"
 
 	"github.com/brianvoe/gofakeit/v6"
+	"github.com/coffee-realist/balancer/internal/balancer"
+	"github.com/coffee-realist/balancer/internal/balancer/healthcheck"
 	"github.com/stretchr/testify/assert"
 )
 
-// setupHealthBalancer создает p2cBalancer с двумя тестовыми серверами и возвращает функцию очистки.
+// setupHealthBalancer создает p2cBalancer с двумя тестовыми серверами и активными проверками здоровья
+// и возвращает функцию очистки.
 func setupHealthBalancer(code1, code2 int, hcInterval time.Duration) (*p2cBalancer, func()) {
 	// Создаем два тестовых HTTP сервера для имитации работы health check.
 	s1 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -28,13 +32,19 @@ func setupHealthBalancer(code1, code2 int, hcInterval time.Duration) (*p2cBalanc
 		}
 		w.WriteHeader(http.StatusOK)
 	}))
-	// Создаем балансировщик p2c и возвращаем его вместе с функцией очистки.
-	b := NewP2CBalancer([]string{s1.URL, s2.URL}, hcInterval).(*p2cBalancer)
+	// Создаем балансировщик p2c с общим health checker и возвращаем его вместе с функцией очистки.
+	servers := []string{s1.URL, s2.URL}
+	hc, err := healthcheck.New(servers, healthcheck.Config{Interval: hcInterval})
+	if err != nil {
+		panic(err)
+	}
+	b := NewP2CBalancer(servers).(*p2cBalancer)
+	b.SetHealth(hc)
 	return b, func() {
-		// Закрываем сервера и останавливаем балансировщик при завершении теста.
+		// Закрываем сервера и останавливаем проверки при завершении теста.
+		hc.Stop()
 		s1.Close()
 		s2.Close()
-		b.Stop()
 	}
 }
 
@@ -43,7 +53,7 @@ func TestP2CBasicDistribution(t *testing.T) {
 	// Инициализация фейковых данных с фиксированным seed для тестирования.
 	gofakeit.Seed(42)
 	servers := []string{"A", "B", "C", "D"}
-	b := NewP2CBalancer(servers, 10*time.Second)
+	b := NewP2CBalancer(servers)
 
 	t.Run("ValidServerSelection", func(t *testing.T) {
 		// Проверяем, что Next возвращает валидный сервер.
@@ -102,23 +112,31 @@ func TestHealthChecks(t *testing.T) {
 		time.Sleep(75 * time.Millisecond)
 		healthy := b.getHealthyServers()
 		assert.Empty(t, healthy, "no servers should be healthy")
+
+		_, err := b.Pick(nil)
+		assert.ErrorIs(t, err, balancer.ErrNoHealthyBackends)
 	})
 }
 
+// TestPickNoServers проверяет ошибку при пустом списке серверов.
+func TestPickNoServers(t *testing.T) {
+	b := NewP2CBalancer(nil)
+	_, err := b.(*p2cBalancer).Pick(nil)
+	assert.ErrorIs(t, err, balancer.ErrNoBackends)
+	assert.Equal(t, "", b.Next())
+}
+
 // TestNextSkipsDownServers убеждается, что Next не возвращает недоступные серверы.
 func TestNextSkipsDownServers(t *testing.T) {
 	// Моделируем серверы, из которых только один доступен.
 	servers := []string{"X", "Y", "Z"}
 	r := rand.New(rand.NewSource(42))
 	b := &p2cBalancer{
-		servers: servers,
-		counts: map[string]*int64{
-			"X": new(int64), "Y": new(int64), "Z": new(int64),
-		},
-		health:     map[string]bool{"X": false, "Y": true, "Z": false},
-		rand:       r,
-		stopHealth: make(chan struct{}),
+		Pool: balancer.NewPool(servers),
+		rand: r,
+		now:  time.Now,
 	}
+	b.SetHealth(downSet{"X": true, "Z": true})
 	t.Run("AlwaysY", func(t *testing.T) {
 		// Проверяем, что всегда выбирается доступный сервер "Y".
 		for i := 0; i < 100; i++ {
@@ -127,12 +145,46 @@ func TestNextSkipsDownServers(t *testing.T) {
 	})
 }
 
+// downSet — внешний источник здоровья: перечисленные серверы недоступны.
+type downSet map[string]bool
+
+func (d downSet) Healthy(server string) bool { return !d[server] }
+
+// TestDoneMarksServerDown проверяет пассивную проверку здоровья: ошибка транспорта
+// исключает сервер на failureCooldown.
+func TestDoneMarksServerDown(t *testing.T) {
+	now := time.Unix(1000, 0)
+	b := NewP2CBalancer([]string{"A", "B"}).(*p2cBalancer)
+	b.now = func() time.Time { return now }
+
+	b.Done("A", time.Millisecond, http.StatusOK, nil)
+	assert.Len(t, b.getHealthyServers(), 2, "success keeps server up")
+
+	b.Done("A", time.Millisecond, 0, errors.New("connection refused"))
+	healthy := b.getHealthyServers()
+	assert.Len(t, healthy, 1)
+	assert.Equal(t, "B", healthy[0].Server)
+
+	now = now.Add(failureCooldown + time.Millisecond)
+	assert.Len(t, b.getHealthyServers(), 2, "server returns after cooldown")
+
+	b.Done("A", time.Millisecond, 0, errors.New("connection refused"))
+	b.Done("A", time.Millisecond, http.StatusOK, nil)
+	assert.Len(t, b.getHealthyServers(), 2, "success clears cooldown")
+
+	// Если ошибки у всех, выбор идёт среди всех серверов, как в least_conn
+	b.Done("A", time.Millisecond, 0, errors.New("connection refused"))
+	b.Done("B", time.Millisecond, 0, errors.New("connection refused"))
+	assert.Len(t, b.getHealthyServers(), 2)
+	_, err := b.Pick(nil)
+	assert.NoError(t, err)
+}
+
 // BenchmarkP2CBalancer измеряет производительность Next и сочетания Next/Increase/Decrease.
 func BenchmarkP2CBalancer(b *testing.B) {
 	// Настроим балансировщик для тестирования производительности.
 	servers := []string{"one", "two", "three"}
-	bl := NewP2CBalancer(servers, 500*time.Millisecond)
-	defer bl.Stop()
+	bl := NewP2CBalancer(servers)
 
 	b.Run("NextOnly", func(b *testing.B) {
 		// Измеряем производительность только для вызова Next.
@@ -0,0 +1,181 @@
+package peak_ewma
+
+import (
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/coffee-realist/balancer/internal/balancer"
+)
+
+const (
+	// DefaultDecay — постоянная времени затухания EWMA по умолчанию.
+	DefaultDecay = 10 * time.Second
+	// failurePenalty — время ответа, которое засчитывается неудачному запросу,
+	// чтобы быстро отвечающий ошибками бэкенд не выглядел самым быстрым.
+	failurePenalty = time.Second
+)
+
+// PeakEWMABalancer — вариант Power of Two Choices, который сравнивает кандидатов
+// по стоимости EWMA(время ответа) × (активные запросы + 1), как в Finagle/Linkerd.
+type PeakEWMABalancer interface {
+	balancer.Balancer
+	balancer.RequestBalancer
+	balancer.ConnAware
+	balancer.FeedbackAware
+}
+
+// stats хранит скользящую оценку времени ответа одного бэкенда.
+type stats struct {
+	mu      sync.Mutex
+	ewma    float64   // Оценка времени ответа, нс
+	last    time.Time // Время последнего обновления
+	pending int64     // Активные запросы (атомарно)
+}
+
+// ewmaBalancer выбирает из двух случайных серверов тот, у которого меньше стоимость.
+type ewmaBalancer struct {
+	servers []string
+	stats   map[string]*stats
+	decay   time.Duration
+	now     func() time.Time
+	health  balancer.HealthSource // Внешний источник здоровья
+}
+
+// NewPeakEWMABalancer создаёт Peak-EWMA балансировщик.
+// decay задаёт, как быстро забываются старые замеры; 0 означает DefaultDecay.
+func NewPeakEWMABalancer(servers []string, decay time.Duration) PeakEWMABalancer {
+	if decay <= 0 {
+		decay = DefaultDecay
+	}
+	st := make(map[string]*stats, len(servers))
+	for _, s := range servers {
+		st[s] = &stats{}
+	}
+	return &ewmaBalancer{
+		servers: append([]string(nil), servers...),
+		stats:   st,
+		decay:   decay,
+		now:     time.Now,
+	}
+}
+
+// Next выбирает сервер с меньшей стоимостью из двух случайных кандидатов.
+func (b *ewmaBalancer) Next() string {
+	srv, _ := b.Pick(nil)
+	return srv
+}
+
+// Pick выбирает сервер так же, как Next, запрос не учитывается.
+func (b *ewmaBalancer) Pick(_ *http.Request) (string, error) {
+	if len(b.servers) == 0 {
+		return "", balancer.ErrNoBackends
+	}
+	healthy := make([]string, 0, len(b.servers))
+	for _, s := range b.servers {
+		if b.health.Healthy(s) {
+			healthy = append(healthy, s)
+		}
+	}
+	n := len(healthy)
+	if n == 0 {
+		return "", balancer.ErrNoHealthyBackends
+	}
+	if n == 1 {
+		return healthy[0], nil
+	}
+
+	// Выбор двух различных случайных кандидатов
+	i1 := rand.IntN(n)
+	i2 := rand.IntN(n - 1)
+	if i2 >= i1 {
+		i2++
+	}
+	s1, s2 := healthy[i1], healthy[i2]
+
+	if b.cost(s1) <= b.cost(s2) {
+		return s1, nil
+	}
+	return s2, nil
+}
+
+// cost возвращает стоимость сервера с учётом затухания оценки с момента последнего замера.
+func (b *ewmaBalancer) cost(server string) float64 {
+	st := b.stats[server]
+	pending := atomic.LoadInt64(&st.pending)
+
+	st.mu.Lock()
+	ewma := st.decayed(b.now(), b.decay)
+	st.mu.Unlock()
+
+	// Сервер без замеров, но с активными запросами, считается медленным,
+	// иначе новый бэкенд получал бы весь трафик до первого ответа
+	if ewma == 0 && pending > 0 {
+		return float64(failurePenalty) * float64(pending)
+	}
+	return ewma * float64(pending+1)
+}
+
+// Done обновляет оценку времени ответа. Всплеск принимается сразу (peak),
+// а снижение сглаживается с весом, зависящим от времени с прошлого замера.
+// Отменённая клиентом попытка замером не считается.
+func (b *ewmaBalancer) Done(server string, latency time.Duration, status int, err error) {
+	st, ok := b.stats[server]
+	if !ok || (status == 0 && err == nil) {
+		return
+	}
+	if err != nil && latency < failurePenalty {
+		latency = failurePenalty
+	}
+	rtt := float64(latency)
+	now := b.now()
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if rtt > st.ewma {
+		st.ewma = rtt
+	} else {
+		w := weight(now.Sub(st.last), b.decay)
+		st.ewma = st.ewma*w + rtt*(1-w)
+	}
+	st.last = now
+}
+
+// decayed возвращает оценку, затухшую с момента последнего замера:
+// сервер со старым всплеском постепенно снова получает трафик.
+func (st *stats) decayed(now time.Time, decay time.Duration) float64 {
+	if st.last.IsZero() {
+		return st.ewma
+	}
+	return st.ewma * weight(now.Sub(st.last), decay)
+}
+
+// weight возвращает вес прежней оценки exp(-elapsed/decay).
+func weight(elapsed, decay time.Duration) float64 {
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	return math.Exp(-float64(elapsed) / float64(decay))
+}
+
+// SetHealth задаёт внешний источник здоровья бэкендов.
+func (b *ewmaBalancer) SetHealth(h balancer.Health) {
+	b.health.Set(h)
+}
+
+// Increase атомарно увеличивает счетчик активных запросов для сервера.
+func (b *ewmaBalancer) Increase(server string) {
+	if st, ok := b.stats[server]; ok {
+		atomic.AddInt64(&st.pending, 1)
+	}
+}
+
+// Decrease атомарно уменьшает счетчик активных запросов для сервера.
+func (b *ewmaBalancer) Decrease(server string) {
+	if st, ok := b.stats[server]; ok {
+		atomic.AddInt64(&st.pending, -1)
+	}
+}
@@ -0,0 +1,132 @@
+package peak_ewma
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coffee-realist/balancer/internal/balancer"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClock — управляемые часы для детерминированных тестов затухания.
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+// setupEWMA создаёт балансировщик с управляемыми часами.
+func setupEWMA(servers []string, decay time.Duration) (*ewmaBalancer, *fakeClock) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	b := NewPeakEWMABalancer(servers, decay).(*ewmaBalancer)
+	b.now = clock.now
+	return b, clock
+}
+
+// TestPeakEWMA_PrefersFasterServer проверяет, что из двух серверов выбирается более быстрый.
+func TestPeakEWMA_PrefersFasterServer(t *testing.T) {
+	b, _ := setupEWMA([]string{"fast", "slow"}, time.Second)
+	b.Done("fast", 10*time.Millisecond, http.StatusOK, nil)
+	b.Done("slow", 200*time.Millisecond, http.StatusOK, nil)
+
+	for i := 0; i < 100; i++ {
+		assert.Equal(t, "fast", b.Next())
+	}
+}
+
+// TestPeakEWMA_PendingRaisesCost проверяет, что стоимость растёт с числом активных запросов.
+func TestPeakEWMA_PendingRaisesCost(t *testing.T) {
+	b, _ := setupEWMA([]string{"A", "B"}, time.Second)
+	b.Done("A", 10*time.Millisecond, http.StatusOK, nil)
+	b.Done("B", 30*time.Millisecond, http.StatusOK, nil)
+
+	// 10ms × (3+1) = 40ms > 30ms × 1
+	for i := 0; i < 3; i++ {
+		b.Increase("A")
+	}
+	assert.Equal(t, "B", b.Next())
+
+	b.Decrease("A")
+	b.Decrease("A")
+	assert.Equal(t, "A", b.Next())
+}
+
+// TestPeakEWMA_PeakAndDecay проверяет мгновенную реакцию на всплеск и последующее затухание.
+func TestPeakEWMA_PeakAndDecay(t *testing.T) {
+	b, clock := setupEWMA([]string{"A"}, time.Second)
+
+	b.Done("A", 10*time.Millisecond, http.StatusOK, nil)
+	b.Done("A", 100*time.Millisecond, http.StatusOK, nil)
+	assert.InDelta(t, float64(100*time.Millisecond), b.cost("A"), 1, "peak must be taken immediately")
+
+	// Спустя постоянную затухания оценка падает в e раз
+	clock.advance(time.Second)
+	assert.InDelta(t, float64(100*time.Millisecond)/2.718281828, b.cost("A"), float64(time.Millisecond))
+
+	// Быстрый ответ после паузы сглаживается, а не заменяет оценку
+	b.Done("A", 10*time.Millisecond, http.StatusOK, nil)
+	assert.Greater(t, b.cost("A"), float64(10*time.Millisecond))
+}
+
+// TestPeakEWMA_Failures проверяет штраф за ошибки и за отсутствие замеров.
+func TestPeakEWMA_Failures(t *testing.T) {
+	b, _ := setupEWMA([]string{"A", "B"}, time.Second)
+	b.Done("A", time.Millisecond, 0, errors.New("connection refused"))
+	b.Done("B", 50*time.Millisecond, http.StatusOK, nil)
+	assert.Equal(t, "B", b.Next(), "fast failures must not look fast")
+
+	fresh, _ := setupEWMA([]string{"new", "old"}, time.Second)
+	fresh.Done("old", 50*time.Millisecond, http.StatusOK, nil)
+	fresh.Increase("new")
+	assert.Equal(t, "old", fresh.Next(), "unmeasured busy server must be penalized")
+}
+
+// TestPeakEWMA_Errors проверяет пустой список и неизвестные серверы.
+func TestPeakEWMA_Errors(t *testing.T) {
+	b, _ := setupEWMA(nil, 0)
+	_, err := b.Pick(nil)
+	assert.ErrorIs(t, err, balancer.ErrNoBackends)
+
+	// Неизвестный сервер игнорируется
+	b.Done("X", time.Second, http.StatusOK, nil)
+	b.Increase("X")
+	b.Decrease("X")
+}
+
+// TestPeakEWMA_Concurrency проверяет потокобезопасность выбора и обновления оценок.
+func TestPeakEWMA_Concurrency(t *testing.T) {
+	servers := []string{"A", "B", "C"}
+	b := NewPeakEWMABalancer(servers, time.Second)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			srv := b.Next()
+			b.Increase(srv)
+			b.Done(srv, time.Millisecond, http.StatusOK, nil)
+			b.Decrease(srv)
+		}()
+	}
+	wg.Wait()
+}
+
+// BenchmarkPeakEWMA_Next измеряет производительность Next с обновлением оценок при 100 серверах.
+func BenchmarkPeakEWMA_Next(b *testing.B) {
+	servers := make([]string, 100)
+	for i := range servers {
+		servers[i] = "s" + strconv.Itoa(i)
+	}
+	bl := NewPeakEWMABalancer(servers, time.Second)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		srv := bl.Next()
+		bl.Increase(srv)
+		bl.Done(srv, time.Millisecond, http.StatusOK, nil)
+		bl.Decrease(srv)
+	}
+}
@@ -0,0 +1,295 @@
+package balancer
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// adminStates — состояния в порядке индексов, хранимых в Member.state.
+var adminStates = [...]AdminState{StateEnabled, StateDraining, StateDisabled}
+
+// Member — сервер в составе Pool. Вес, состояние, счётчик запросов
+// и время последней ошибки меняются атомарно, без блокировки пула.
+type Member struct {
+	Server   string
+	weight   atomic.Int64
+	state    atomic.Int32 // Индекс в adminStates
+	removing atomic.Bool  // Удалить, когда завершатся начатые запросы
+	active   atomic.Int64
+	failedAt atomic.Int64 // UnixNano последней ошибки транспорта, 0 — ошибок нет
+}
+
+// Weight возвращает вес сервера.
+func (m *Member) Weight() int {
+	return int(m.weight.Load())
+}
+
+// State возвращает административное состояние сервера.
+func (m *Member) State() AdminState {
+	return adminStates[m.state.Load()]
+}
+
+// Available сообщает, что сервер можно выбирать: он не выведен из ротации.
+func (m *Member) Available() bool {
+	return m.State() == StateEnabled
+}
+
+// Removing сообщает, что сервер будет удалён после завершения начатых запросов.
+func (m *Member) Removing() bool {
+	return m.removing.Load()
+}
+
+// Active возвращает число запросов к серверу в обработке.
+func (m *Member) Active() int64 {
+	return m.active.Load()
+}
+
+// FailedSince сообщает, была ли ошибка транспорта позже момента t.
+func (m *Member) FailedSince(t time.Time) bool {
+	return m.failedAt.Load() > t.UnixNano()
+}
+
+// SetFailed запоминает момент ошибки транспорта; нулевое время снимает отметку.
+func (m *Member) SetFailed(t time.Time) {
+	if t.IsZero() {
+		m.failedAt.Store(0)
+		return
+	}
+	m.failedAt.Store(t.UnixNano())
+}
+
+// poolState — неизменяемый снимок состава пула.
+type poolState struct {
+	members  []*Member
+	index    map[string]*Member
+	schedule []*Member // Порядок взвешенного кругового обхода
+}
+
+// Pool — общий для стратегий изменяемый набор серверов. Выбор читает снимок
+// состава без блокировок, изменения состава публикуют новый снимок.
+// Pool реализует Membership и ConnAware, поэтому стратегии встраивают его целиком.
+type Pool struct {
+	mu    sync.Mutex // Сериализует изменения состава
+	state atomic.Pointer[poolState]
+}
+
+// NewPool создаёт пул из серверов с весом 1; повторы в списке пропускаются.
+func NewPool(servers []string) *Pool {
+	members := make([]*Member, 0, len(servers))
+	seen := make(map[string]bool, len(servers))
+	for _, s := range servers {
+		if seen[s] {
+			continue
+		}
+		seen[s] = true
+		members = append(members, newMember(s, 1))
+	}
+	p := &Pool{}
+	p.publish(members)
+	return p
+}
+
+// newMember создаёт сервер с заданным весом.
+func newMember(server string, weight int) *Member {
+	m := &Member{Server: server}
+	m.weight.Store(int64(weight))
+	return m
+}
+
+// Snapshot возвращает текущий состав пула. Срез нельзя изменять.
+func (p *Pool) Snapshot() []*Member {
+	return p.state.Load().members
+}
+
+// Schedule возвращает порядок взвешенного кругового обхода: каждый сервер
+// встречается в нём столько раз, каков его вес, вхождения распределены равномерно.
+func (p *Pool) Schedule() []*Member {
+	return p.state.Load().schedule
+}
+
+// Get возвращает сервер по адресу.
+func (p *Pool) Get(server string) (*Member, bool) {
+	m, ok := p.state.Load().index[server]
+	return m, ok
+}
+
+// Add добавляет сервер в конец пула.
+func (p *Pool) Add(server string, weight int) error {
+	if weight == 0 {
+		weight = 1
+	}
+	if weight < 0 || weight > MaxWeight {
+		return ErrInvalidWeight
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	st := p.state.Load()
+	if _, ok := st.index[server]; ok {
+		return ErrServerExists
+	}
+	members := make([]*Member, len(st.members), len(st.members)+1)
+	copy(members, st.members)
+	p.publish(append(members, newMember(server, weight)))
+	return nil
+}
+
+// Remove удаляет сервер из пула.
+func (p *Pool) Remove(server string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	m, ok := p.state.Load().index[server]
+	if !ok {
+		return ErrUnknownServer
+	}
+	p.removeLocked(m)
+	return nil
+}
+
+// SetWeight меняет вес сервера.
+func (p *Pool) SetWeight(server string, weight int) error {
+	if weight <= 0 || weight > MaxWeight {
+		return ErrInvalidWeight
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	st := p.state.Load()
+	m, ok := st.index[server]
+	if !ok {
+		return ErrUnknownServer
+	}
+	m.weight.Store(int64(weight))
+	// Порядок обхода зависит от весов — пересобираем снимок
+	p.publish(st.members)
+	return nil
+}
+
+// Drain выводит сервер из ротации и удаляет его, как только у него не останется
+// запросов в обработке; если их нет, сервер удаляется сразу.
+func (p *Pool) Drain(server string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	m, ok := p.state.Load().index[server]
+	if !ok {
+		return ErrUnknownServer
+	}
+	m.state.Store(stateIndex(StateDraining))
+	m.removing.Store(true)
+	if m.active.Load() == 0 {
+		p.removeLocked(m)
+	}
+	return nil
+}
+
+// SetState меняет административное состояние сервера. Включение сервера
+// отменяет его удаление, запрошенное через Drain; StateDraining задаётся только через Drain.
+func (p *Pool) SetState(server string, state AdminState) error {
+	idx := stateIndex(state)
+	if idx < 0 || state == StateDraining {
+		return ErrInvalidState
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	m, ok := p.state.Load().index[server]
+	if !ok {
+		return ErrUnknownServer
+	}
+	m.state.Store(idx)
+	if state == StateEnabled {
+		m.removing.Store(false)
+	}
+	return nil
+}
+
+// stateIndex возвращает индекс состояния в adminStates или -1.
+func stateIndex(state AdminState) int32 {
+	for i, s := range adminStates {
+		if s == state {
+			return int32(i)
+		}
+	}
+	return -1
+}
+
+// Members возвращает состав пула.
+func (p *Pool) Members() []MemberInfo {
+	members := p.Snapshot()
+	info := make([]MemberInfo, len(members))
+	for i, m := range members {
+		info[i] = MemberInfo{
+			Server:   m.Server,
+			Weight:   m.Weight(),
+			State:    m.State(),
+			Removing: m.Removing(),
+			Active:   m.Active(),
+		}
+	}
+	return info
+}
+
+// Increase увеличивает счётчик запросов сервера.
+func (p *Pool) Increase(server string) {
+	if m, ok := p.Get(server); ok {
+		m.active.Add(1)
+	}
+}
+
+// Decrease уменьшает счётчик запросов сервера и завершает вывод из ротации,
+// когда последний запрос к выводимому серверу закончился.
+func (p *Pool) Decrease(server string) {
+	m, ok := p.Get(server)
+	if !ok {
+		return
+	}
+	if m.active.Add(-1) == 0 && m.Removing() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		// Сервер мог быть удалён и добавлен заново, пока мы ждали блокировку
+		if cur, ok := p.state.Load().index[server]; ok && cur == m && m.Removing() && m.active.Load() == 0 {
+			p.removeLocked(m)
+		}
+	}
+}
+
+// removeLocked публикует снимок без сервера m; вызывается под p.mu.
+func (p *Pool) removeLocked(m *Member) {
+	st := p.state.Load()
+	members := make([]*Member, 0, len(st.members))
+	for _, cur := range st.members {
+		if cur != m {
+			members = append(members, cur)
+		}
+	}
+	p.publish(members)
+}
+
+// publish строит индекс и порядок обхода и публикует снимок.
+func (p *Pool) publish(members []*Member) {
+	index := make(map[string]*Member, len(members))
+	for _, m := range members {
+		index[m.Server] = m
+	}
+	p.state.Store(&poolState{
+		members:  members,
+		index:    index,
+		schedule: smoothSchedule(members),
+	})
+}
+
+// smoothSchedule строит порядок обхода алгоритмом smooth weighted round-robin (см. SmoothWeights):
+// сервер с весом w встречается w раз, и его вхождения не идут подряд без необходимости.
+// Длина порядка — сумма весов, она ограничена MaxWeight на сервер.
+func smoothSchedule(members []*Member) []*Member {
+	weights := make([]int, len(members))
+	total := 0
+	for i, m := range members {
+		weights[i] = m.Weight()
+		total += weights[i]
+	}
+	current := make(SmoothWeights, len(members))
+	schedule := make([]*Member, 0, total)
+	for range total {
+		schedule = append(schedule, members[current.Next(weights)])
+	}
+	return schedule
+}
@@ -0,0 +1,172 @@
+package balancer
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// servers возвращает адреса серверов снимка.
+func servers(members []*Member) []string {
+	out := make([]string, len(members))
+	for i, m := range members {
+		out[i] = m.Server
+	}
+	return out
+}
+
+// TestPool_AddRemove проверяет изменение состава и ошибки.
+func TestPool_AddRemove(t *testing.T) {
+	p := NewPool([]string{"A", "B", "A"})
+	assert.Equal(t, []string{"A", "B"}, servers(p.Snapshot()), "duplicates are skipped")
+
+	require.NoError(t, p.Add("C", 0))
+	assert.Equal(t, []string{"A", "B", "C"}, servers(p.Snapshot()))
+	assert.ErrorIs(t, p.Add("C", 1), ErrServerExists)
+	assert.ErrorIs(t, p.Add("D", -1), ErrInvalidWeight)
+	assert.ErrorIs(t, p.Add("D", MaxWeight+1), ErrInvalidWeight)
+
+	require.NoError(t, p.Remove("A"))
+	assert.Equal(t, []string{"B", "C"}, servers(p.Snapshot()))
+	assert.ErrorIs(t, p.Remove("A"), ErrUnknownServer)
+	_, ok := p.Get("A")
+	assert.False(t, ok)
+}
+
+// TestPool_Schedule проверяет взвешенный порядок обхода.
+func TestPool_Schedule(t *testing.T) {
+	p := NewPool([]string{"A", "B", "C"})
+	require.NoError(t, p.SetWeight("A", 3))
+	assert.ErrorIs(t, p.SetWeight("A", 0), ErrInvalidWeight)
+	assert.ErrorIs(t, p.SetWeight("A", MaxWeight+1), ErrInvalidWeight)
+	assert.ErrorIs(t, p.SetWeight("X", 1), ErrUnknownServer)
+
+	// Smooth WRR не ставит вхождения A подряд, пока есть другие серверы
+	assert.Equal(t, "A B A C A", strings.Join(servers(p.Schedule()), " "))
+}
+
+// TestSmoothWeights проверяет шаг smooth WRR и пропуск серверов с нулевым весом.
+func TestSmoothWeights(t *testing.T) {
+	current := make(SmoothWeights, 3)
+	var got []int
+	for range 7 {
+		got = append(got, current.Next([]int{5, 1, 1}))
+	}
+	assert.Equal(t, []int{0, 0, 1, 0, 2, 0, 0}, got)
+
+	current.Reset()
+	assert.Equal(t, 1, current.Next([]int{0, 1, 0}))
+	assert.Equal(t, []int{0, 0, 0}, []int(current), "excluded servers do not accumulate weight")
+	assert.Equal(t, -1, current.Next([]int{0, 0, 0}))
+}
+
+// TestPool_Drain проверяет, что выводимый сервер удаляется после завершения запросов.
+func TestPool_Drain(t *testing.T) {
+	p := NewPool([]string{"A", "B"})
+	p.Increase("A")
+	p.Increase("A")
+
+	require.NoError(t, p.Drain("A"))
+	m, ok := p.Get("A")
+	require.True(t, ok, "server stays while requests are in flight")
+	assert.False(t, m.Available())
+	assert.Equal(t, []MemberInfo{
+		{Server: "A", Weight: 1, State: StateDraining, Removing: true, Active: 2},
+		{Server: "B", Weight: 1, State: StateEnabled},
+	}, p.Members())
+
+	p.Decrease("A")
+	_, ok = p.Get("A")
+	assert.True(t, ok)
+	p.Decrease("A")
+	_, ok = p.Get("A")
+	assert.False(t, ok, "last request removes drained server")
+
+	// Сервер без запросов удаляется сразу
+	require.NoError(t, p.Drain("B"))
+	assert.Empty(t, p.Snapshot())
+	assert.ErrorIs(t, p.Drain("B"), ErrUnknownServer)
+}
+
+// TestPool_DrainReAdded проверяет, что завершение запросов к старому экземпляру
+// не удаляет сервер, добавленный заново с тем же адресом.
+func TestPool_DrainReAdded(t *testing.T) {
+	p := NewPool([]string{"A"})
+	p.Increase("A")
+	require.NoError(t, p.Drain("A"))
+	require.NoError(t, p.Remove("A"))
+	require.NoError(t, p.Add("A", 1))
+
+	p.Decrease("A")
+	m, ok := p.Get("A")
+	require.True(t, ok)
+	assert.True(t, m.Available())
+}
+
+// TestPool_SetState проверяет административные состояния и отмену удаления.
+func TestPool_SetState(t *testing.T) {
+	p := NewPool([]string{"A"})
+	m, _ := p.Get("A")
+
+	require.NoError(t, p.SetState("A", StateDisabled))
+	assert.Equal(t, StateDisabled, m.State())
+	assert.False(t, m.Available())
+	assert.ErrorIs(t, p.SetState("A", "broken"), ErrInvalidState)
+	assert.ErrorIs(t, p.SetState("X", StateEnabled), ErrUnknownServer)
+
+	// Включение отменяет удаление после вывода из ротации
+	p.Increase("A")
+	require.NoError(t, p.Drain("A"))
+	require.NoError(t, p.SetState("A", StateEnabled))
+	p.Decrease("A")
+	_, ok := p.Get("A")
+	assert.True(t, ok)
+	assert.True(t, m.Available())
+	assert.False(t, m.Removing())
+
+	// Вывод с удалением — только через Drain
+	assert.ErrorIs(t, p.SetState("A", StateDraining), ErrInvalidState)
+}
+
+// TestPool_Failed проверяет отметку ошибки транспорта.
+func TestPool_Failed(t *testing.T) {
+	p := NewPool([]string{"A"})
+	m, _ := p.Get("A")
+	now := time.Unix(1000, 0)
+
+	assert.False(t, m.FailedSince(now.Add(-time.Second)))
+	m.SetFailed(now)
+	assert.True(t, m.FailedSince(now.Add(-time.Second)))
+	assert.False(t, m.FailedSince(now))
+	m.SetFailed(time.Time{})
+	assert.False(t, m.FailedSince(now.Add(-time.Second)))
+}
+
+// TestPool_Concurrent проверяет согласованность при одновременных изменениях и запросах.
+func TestPool_Concurrent(t *testing.T) {
+	p := NewPool([]string{"A", "B"})
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			p.Increase("A")
+			_ = p.Schedule()
+			p.Decrease("A")
+		}()
+		go func() {
+			defer wg.Done()
+			_ = p.Add("C", 2)
+			_ = p.Remove("C")
+		}()
+	}
+	wg.Wait()
+
+	m, ok := p.Get("A")
+	require.True(t, ok)
+	assert.Equal(t, int64(0), m.Active())
+}
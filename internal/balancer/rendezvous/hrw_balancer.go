@@ -0,0 +1,171 @@
+package rendezvous
+
+import (
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/coffee-realist/balancer/internal/balancer"
+	"github.com/coffee-realist/balancer/internal/balancer/hashkey"
+)
+
+// defaultWeight используется для серверов, вес которых не задан в конфигурации.
+const defaultWeight = 1
+
+// ErrInvalidWeight возвращается при попытке задать вес вне диапазона от 1 до balancer.MaxWeight.
+var ErrInvalidWeight = balancer.ErrInvalidWeight
+
+// RendezvousBalancer реализует взвешенное rendezvous-хеширование (highest random weight):
+// ключ закрепляется за сервером с наибольшим счётом, а остальные серверы
+// в порядке убывания счёта образуют список запасных.
+type RendezvousBalancer interface {
+	balancer.Balancer
+	balancer.RequestBalancer
+	balancer.RequestAware
+	balancer.Ranker
+	// SetWeight меняет вес сервера, изменение учитывается со следующего выбора.
+	SetWeight(server string, weight int) error
+}
+
+// hrwBalancer хранит веса и состояние здоровья бэкендов.
+type hrwBalancer struct {
+	servers []string              // Список всех бэкендов
+	weights map[string]int        // Веса серверов
+	mu      sync.RWMutex          // Защищает weights
+	key     hashkey.Func          // Извлекатель ключа из запроса
+	seq     uint64                // Счетчик для запросов без ключа
+	health  balancer.HealthSource // Внешний источник здоровья (активные и пассивные проверки)
+}
+
+// candidate — сервер с его счётом для конкретного ключа.
+type candidate struct {
+	server string
+	score  float64
+}
+
+// NewRendezvousBalancer создаёт HRW-балансировщик. Состояние здоровья бэкендов задаётся через SetHealth.
+// Серверы без веса (или с неположительным весом) получают вес 1, вес больше
+// balancer.MaxWeight уменьшается до него.
+func NewRendezvousBalancer(servers []string, weights map[string]int, key hashkey.Func) RendezvousBalancer {
+	w := make(map[string]int, len(servers))
+	for _, s := range servers {
+		w[s] = min(weights[s], balancer.MaxWeight)
+		if w[s] <= 0 {
+			w[s] = defaultWeight
+		}
+	}
+
+	return &hrwBalancer{
+		servers: append([]string(nil), servers...),
+		weights: w,
+		key:     key,
+	}
+}
+
+// Next выбирает сервер без учёта запроса: ключи генерируются по кругу.
+func (b *hrwBalancer) Next() string {
+	srv, _ := b.Pick(nil)
+	return srv
+}
+
+// NextFor выбирает сервер по ключу, извлечённому из запроса.
+func (b *hrwBalancer) NextFor(r *http.Request) string {
+	srv, _ := b.Pick(r)
+	return srv
+}
+
+// Pick возвращает здоровый сервер с наибольшим счётом для ключа запроса.
+func (b *hrwBalancer) Pick(r *http.Request) (string, error) {
+	key := b.keyOf(r)
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if len(b.servers) == 0 {
+		return "", balancer.ErrNoBackends
+	}
+
+	best := candidate{score: math.Inf(-1)}
+	for _, s := range b.servers {
+		if !b.health.Healthy(s) {
+			continue
+		}
+		if sc := score(key, s, b.weights[s]); best.server == "" || sc > best.score {
+			best = candidate{server: s, score: sc}
+		}
+	}
+	if best.server == "" {
+		return "", balancer.ErrNoHealthyBackends
+	}
+	return best.server, nil
+}
+
+// Rank возвращает все здоровые серверы в порядке убывания счёта для ключа запроса.
+// Первый элемент совпадает с результатом Pick.
+func (b *hrwBalancer) Rank(r *http.Request) ([]string, error) {
+	key := b.keyOf(r)
+
+	b.mu.RLock()
+	if len(b.servers) == 0 {
+		b.mu.RUnlock()
+		return nil, balancer.ErrNoBackends
+	}
+	cands := make([]candidate, 0, len(b.servers))
+	for _, s := range b.servers {
+		if b.health.Healthy(s) {
+			cands = append(cands, candidate{server: s, score: score(key, s, b.weights[s])})
+		}
+	}
+	b.mu.RUnlock()
+
+	if len(cands) == 0 {
+		return nil, balancer.ErrNoHealthyBackends
+	}
+	sort.Slice(cands, func(i, j int) bool { return cands[i].score > cands[j].score })
+	ranked := make([]string, len(cands))
+	for i, c := range cands {
+		ranked[i] = c.server
+	}
+	return ranked, nil
+}
+
+// SetHealth задаёт внешний источник здоровья бэкендов.
+func (b *hrwBalancer) SetHealth(h balancer.Health) {
+	b.health.Set(h)
+}
+
+// SetWeight меняет вес сервера.
+func (b *hrwBalancer) SetWeight(server string, weight int) error {
+	if weight <= 0 || weight > balancer.MaxWeight {
+		return ErrInvalidWeight
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.weights[server]; !ok {
+		return balancer.ErrUnknownServer
+	}
+	b.weights[server] = weight
+	return nil
+}
+
+// keyOf извлекает ключ из запроса; для запросов без ключа ключи генерируются по кругу.
+func (b *hrwBalancer) keyOf(r *http.Request) string {
+	var key string
+	if r != nil && b.key != nil {
+		key = b.key(r)
+	}
+	if key == "" {
+		key = strconv.FormatUint(atomic.AddUint64(&b.seq, 1), 10)
+	}
+	return key
+}
+
+// score вычисляет взвешенный счёт -w / ln(u), где u — хеш пары (ключ, сервер),
+// отображённый в интервал (0, 1). Доля ключей сервера пропорциональна его весу.
+func score(key, server string, weight int) float64 {
+	h := hashkey.Sum64(key + "|" + server)
+	u := (float64(h>>11) + 0.5) / (1 << 53)
+	return -float64(weight) / math.Log(u)
+}
@@ -0,0 +1,148 @@
+package rendezvous
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/coffee-realist/balancer/internal/balancer"
+	"github.com/coffee-realist/balancer/internal/balancer/hashkey"
+	"github.com/coffee-realist/balancer/internal/balancer/healthcheck"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestBalancer создаёт HRW с ключом из заголовка X-Key.
+func newTestBalancer(t testing.TB, servers []string, weights map[string]int) *hrwBalancer {
+	key, err := hashkey.New(hashkey.SourceHeader, "X-Key")
+	require.NoError(t, err)
+	return NewRendezvousBalancer(servers, weights, key).(*hrwBalancer)
+}
+
+// downSet — внешний источник здоровья: перечисленные серверы недоступны.
+type downSet map[string]bool
+
+func (d downSet) Healthy(server string) bool { return !d[server] }
+
+// requestWithKey создаёт запрос с ключом в заголовке X-Key.
+func requestWithKey(key string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "http://lb/", nil)
+	req.Header.Set("X-Key", key)
+	return req
+}
+
+// TestRendezvous_AffinityAndRank проверяет закрепление ключа и порядок запасных серверов.
+func TestRendezvous_AffinityAndRank(t *testing.T) {
+	servers := []string{"A", "B", "C", "D"}
+	b := newTestBalancer(t, servers, nil)
+
+	for i := 0; i < 100; i++ {
+		req := requestWithKey("user-" + strconv.Itoa(i))
+		first, err := b.Pick(req)
+		require.NoError(t, err)
+		assert.Equal(t, first, b.NextFor(req), "key must stay on one server")
+
+		ranked, err := b.Rank(req)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, servers, ranked)
+		assert.Equal(t, first, ranked[0], "Rank must start with Pick result")
+	}
+}
+
+// TestRendezvous_FailoverMovesOnlyOwnedKeys проверяет, что при падении сервера
+// его ключи уходят на второй по счёту сервер, а остальные ключи не двигаются.
+func TestRendezvous_FailoverMovesOnlyOwnedKeys(t *testing.T) {
+	b := newTestBalancer(t, []string{"A", "B", "C", "D"}, nil)
+
+	ranks := make([][]string, 500)
+	for i := range ranks {
+		ranks[i], _ = b.Rank(requestWithKey("k" + strconv.Itoa(i)))
+	}
+
+	b.SetHealth(downSet{"B": true})
+
+	for i, ranked := range ranks {
+		got := b.NextFor(requestWithKey("k" + strconv.Itoa(i)))
+		if ranked[0] == "B" {
+			assert.Equal(t, ranked[1], got)
+		} else {
+			assert.Equal(t, ranked[0], got)
+		}
+	}
+}
+
+// TestRendezvous_Weights проверяет, что доля ключей пропорциональна весу.
+func TestRendezvous_Weights(t *testing.T) {
+	b := newTestBalancer(t, []string{"A", "B"}, map[string]int{"A": 3})
+
+	counts := make(map[string]int)
+	for i := 0; i < 4000; i++ {
+		counts[b.NextFor(requestWithKey("k"+strconv.Itoa(i)))]++
+	}
+	assert.InDelta(t, 3000, counts["A"], 150)
+	assert.InDelta(t, 1000, counts["B"], 150)
+
+	require.NoError(t, b.SetWeight("B", 3))
+	counts = make(map[string]int)
+	for i := 0; i < 4000; i++ {
+		counts[b.NextFor(requestWithKey("k"+strconv.Itoa(i)))]++
+	}
+	assert.InDelta(t, 2000, counts["A"], 150)
+
+	assert.ErrorIs(t, b.SetWeight("X", 1), balancer.ErrUnknownServer)
+	assert.ErrorIs(t, b.SetWeight("A", 0), ErrInvalidWeight)
+	assert.ErrorIs(t, b.SetWeight("A", balancer.MaxWeight+1), ErrInvalidWeight)
+}
+
+// TestRendezvous_Errors проверяет ошибки при отсутствии серверов и здоровых серверов.
+func TestRendezvous_Errors(t *testing.T) {
+	_, err := newTestBalancer(t, nil, nil).Pick(nil)
+	assert.ErrorIs(t, err, balancer.ErrNoBackends)
+
+	b := newTestBalancer(t, []string{"A"}, nil)
+	b.SetHealth(downSet{"A": true})
+	_, err = b.Pick(nil)
+	assert.ErrorIs(t, err, balancer.ErrNoHealthyBackends)
+	_, err = b.Rank(nil)
+	assert.ErrorIs(t, err, balancer.ErrNoHealthyBackends)
+}
+
+// TestRendezvous_HealthChecker проверяет исключение бэкенда, не прошедшего активную проверку.
+func TestRendezvous_HealthChecker(t *testing.T) {
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer up.Close()
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer down.Close()
+
+	servers := []string{up.URL, down.URL}
+	hc, err := healthcheck.New(servers, healthcheck.Config{Interval: 20 * time.Millisecond})
+	require.NoError(t, err)
+	defer hc.Stop()
+	b := NewRendezvousBalancer(servers, nil, nil)
+	b.(*hrwBalancer).SetHealth(hc)
+
+	assert.Eventually(t, func() bool {
+		ranked, err := b.Rank(nil)
+		return err == nil && len(ranked) == 1 && ranked[0] == up.URL
+	}, time.Second, 10*time.Millisecond)
+}
+
+// BenchmarkRendezvous_NextFor измеряет производительность выбора по ключу при 20 серверах.
+func BenchmarkRendezvous_NextFor(b *testing.B) {
+	servers := make([]string, 20)
+	for i := range servers {
+		servers[i] = "s" + strconv.Itoa(i)
+	}
+	hrw := newTestBalancer(b, servers, nil)
+	req := requestWithKey("bench")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = hrw.NextFor(req)
+	}
+}
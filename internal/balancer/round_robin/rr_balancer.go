@@ -2,6 +2,7 @@ package round_robin
 
 import (
 	"github.com/coffee-realist/balancer/internal/balancer"
+	"net/http"
 	"sync/atomic"
 )
 
@@ -9,22 +10,44 @@ type RoundRobinBalancer interface {
 	balancer.Balancer
 }
 
+// rrBalancer обходит серверы по кругу; состав пула меняется через встроенный balancer.Pool.
 type rrBalancer struct {
-	servers []string
-	idx     uint64
+	*balancer.Pool
+	idx    uint64
+	health balancer.HealthSource // Внешний источник здоровья
 }
 
 // NewRoundRobinBalancer создаёт RoundRobin по списку адресов.
 func NewRoundRobinBalancer(servers []string) RoundRobinBalancer {
-	return &rrBalancer{servers: append([]string(nil), servers...)}
+	return &rrBalancer{Pool: balancer.NewPool(servers)}
 }
 
 // Next возвращает следующий сервер по кругу.
 func (r *rrBalancer) Next() string {
-	n := len(r.servers)
+	srv, _ := r.Pick(nil)
+	return srv
+}
+
+// Pick возвращает следующий сервер по кругу, запрос не учитывается.
+// Серверы с весом больше 1 выбираются соответственно чаще.
+// Недоступные и выводимые из ротации серверы пропускаются.
+func (r *rrBalancer) Pick(_ *http.Request) (string, error) {
+	schedule := r.Schedule()
+	n := len(schedule)
 	if n == 0 {
-		return ""
+		return "", balancer.ErrNoBackends
 	}
 	i := atomic.AddUint64(&r.idx, 1)
-	return r.servers[i%uint64(n)]
+	for k := 0; k < n; k++ {
+		m := schedule[(i+uint64(k))%uint64(n)]
+		if m.Available() && r.health.Healthy(m.Server) {
+			return m.Server, nil
+		}
+	}
+	return "", balancer.ErrNoHealthyBackends
+}
+
+// SetHealth задаёт внешний источник здоровья бэкендов.
+func (r *rrBalancer) SetHealth(h balancer.Health) {
+	r.health.Set(h)
 }
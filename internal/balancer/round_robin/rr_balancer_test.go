@@ -4,7 +4,9 @@ import (
 	"strconv"
 	"testing"
 
+	"github.com/coffee-realist/balancer/internal/balancer"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // TestRoundRobinBalancer_Basic проверяет базовую корректность работы алгоритма Round Robin.
@@ -31,6 +33,47 @@ func TestRoundRobinBalancer_Basic(t *testing.T) {
 	})
 }
 
+// downSet — внешний источник здоровья, считающий недоступными перечисленные серверы.
+type downSet map[string]bool
+
+func (d downSet) Healthy(server string) bool { return !d[server] }
+
+// TestRoundRobinBalancer_SkipsUnhealthy проверяет пропуск серверов, недоступных по внешнему источнику.
+func TestRoundRobinBalancer_SkipsUnhealthy(t *testing.T) {
+	rr := NewRoundRobinBalancer([]string{"A", "B", "C"}).(*rrBalancer)
+	rr.SetHealth(downSet{"B": true})
+
+	for i := 0; i < 10; i++ {
+		assert.NotEqual(t, "B", rr.Next())
+	}
+
+	rr.SetHealth(downSet{"A": true, "B": true, "C": true})
+	_, err := rr.Pick(nil)
+	assert.ErrorIs(t, err, balancer.ErrNoHealthyBackends)
+}
+
+// TestRoundRobinBalancer_Membership проверяет добавление, веса и вывод серверов из ротации.
+func TestRoundRobinBalancer_Membership(t *testing.T) {
+	rr := NewRoundRobinBalancer([]string{"A", "B"}).(*rrBalancer)
+	require.NoError(t, rr.Add("C", 2))
+
+	counts := make(map[string]int)
+	for i := 0; i < 400; i++ {
+		counts[rr.Next()]++
+	}
+	assert.Equal(t, map[string]int{"A": 100, "B": 100, "C": 200}, counts)
+
+	// Выводимый сервер не выбирается, но остаётся до завершения запроса
+	rr.Increase("C")
+	require.NoError(t, rr.Drain("C"))
+	for i := 0; i < 10; i++ {
+		assert.NotEqual(t, "C", rr.Next())
+	}
+	assert.Len(t, rr.Members(), 3)
+	rr.Decrease("C")
+	assert.Len(t, rr.Members(), 2)
+}
+
 // BenchmarkRoundRobinBalancer_Next измеряет производительность метода Next при 100 серверах.
 func BenchmarkRoundRobinBalancer_Next(b *testing.B) {
 	servers := make([]string, 100)
@@ -0,0 +1,32 @@
+package balancer
+
+// SmoothWeights — накопленные веса алгоритма smooth weighted round-robin (как в nginx),
+// по одному на сервер. Общий для Pool и стратегии weighted_rr.
+type SmoothWeights []int
+
+// Next выполняет один шаг алгоритма и возвращает индекс выбранного сервера или -1:
+// 1. Каждому серверу к накопленному весу добавляется его вес weights[i]
+// 2. Выигравший сервер уменьшает накопленный вес на сумму всех весов
+// Сервер с нулевым весом в шаге не участвует и вес не накапливает.
+func (c SmoothWeights) Next(weights []int) int {
+	best, total := -1, 0
+	for i, w := range weights {
+		if w <= 0 {
+			continue
+		}
+		c[i] += w
+		total += w
+		if best < 0 || c[i] > c[best] {
+			best = i
+		}
+	}
+	if best >= 0 {
+		c[best] -= total
+	}
+	return best
+}
+
+// Reset обнуляет накопленные веса, чтобы новое распределение начиналось с чистого цикла.
+func (c SmoothWeights) Reset() {
+	clear(c)
+}
@@ -0,0 +1,73 @@
+package stats
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Stats — накопленные показатели запросов к серверу.
+type Stats struct {
+	Requests   int64         `json:"requests"`
+	Errors     int64         `json:"errors"`
+	ErrorRate  float64       `json:"error_rate"`  // Доля ошибок среди всех запросов
+	AvgLatency time.Duration `json:"avg_latency"` // Среднее время до заголовков ответа
+}
+
+// counters — атомарные счётчики одного сервера.
+type counters struct {
+	requests atomic.Int64
+	errors   atomic.Int64
+	latency  atomic.Int64 // Сумма задержек, нс
+}
+
+// Collector считает запросы и ошибки по серверам. Реализует balancer.FeedbackAware,
+// поэтому подключается к прокси через AddFeedback. Ошибкой считаются ошибка
+// транспорта и любой 5xx, как и в outlier.Detector; попытки, отменённые клиентом,
+// не учитываются.
+type Collector struct {
+	servers sync.Map // string -> *counters
+}
+
+// NewCollector создаёт пустой Collector; серверы появляются при первом запросе.
+func NewCollector() *Collector {
+	return &Collector{}
+}
+
+// Done учитывает результат запроса к серверу.
+func (c *Collector) Done(server string, latency time.Duration, status int, err error) {
+	if status == 0 && err == nil {
+		// Попытка отменена клиентом (см. balancer.FeedbackAware)
+		return
+	}
+	v, ok := c.servers.Load(server)
+	if !ok {
+		v, _ = c.servers.LoadOrStore(server, &counters{})
+	}
+	cnt := v.(*counters)
+	cnt.requests.Add(1)
+	cnt.latency.Add(int64(latency))
+	if err != nil || status >= 500 {
+		cnt.errors.Add(1)
+	}
+}
+
+// Get возвращает показатели сервера; для сервера без запросов — нулевые.
+func (c *Collector) Get(server string) Stats {
+	v, ok := c.servers.Load(server)
+	if !ok {
+		return Stats{}
+	}
+	cnt := v.(*counters)
+	st := Stats{Requests: cnt.requests.Load(), Errors: cnt.errors.Load()}
+	if st.Requests > 0 {
+		st.ErrorRate = float64(st.Errors) / float64(st.Requests)
+		st.AvgLatency = time.Duration(cnt.latency.Load() / st.Requests)
+	}
+	return st
+}
+
+// Remove удаляет показатели сервера, выведенного из состава.
+func (c *Collector) Remove(server string) {
+	c.servers.Delete(server)
+}
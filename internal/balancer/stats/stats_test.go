@@ -0,0 +1,55 @@
+package stats
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCollector проверяет подсчёт запросов, ошибок и средней задержки.
+func TestCollector(t *testing.T) {
+	c := NewCollector()
+	c.Done("A", 10*time.Millisecond, http.StatusOK, nil)
+	c.Done("A", 20*time.Millisecond, http.StatusNotFound, nil)
+	c.Done("A", 30*time.Millisecond, http.StatusBadGateway, nil)
+	c.Done("A", 40*time.Millisecond, 0, errors.New("connection refused"))
+
+	assert.Equal(t, Stats{
+		Requests:   4,
+		Errors:     2,
+		ErrorRate:  0.5,
+		AvgLatency: 25 * time.Millisecond,
+	}, c.Get("A"))
+	assert.Equal(t, Stats{}, c.Get("B"))
+}
+
+// TestCollector_CanceledAndRemove проверяет, что отменённые клиентом попытки не учитываются,
+// а показатели удалённого сервера сбрасываются.
+func TestCollector_CanceledAndRemove(t *testing.T) {
+	c := NewCollector()
+	c.Done("A", time.Millisecond, 0, nil)
+	assert.Equal(t, Stats{}, c.Get("A"))
+
+	c.Done("A", time.Millisecond, http.StatusOK, nil)
+	c.Remove("A")
+	assert.Equal(t, Stats{}, c.Get("A"))
+}
+
+// TestCollector_Concurrent проверяет подсчёт при параллельных запросах.
+func TestCollector_Concurrent(t *testing.T) {
+	c := NewCollector()
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.Done("A", time.Millisecond, http.StatusOK, nil)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int64(100), c.Get("A").Requests)
+}
@@ -0,0 +1,107 @@
+package weighted_rr
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/coffee-realist/balancer/internal/balancer"
+)
+
+// defaultWeight используется для серверов, вес которых не задан в конфигурации.
+const defaultWeight = 1
+
+// ErrInvalidWeight возвращается при попытке задать вес вне диапазона от 1 до balancer.MaxWeight.
+var ErrInvalidWeight = balancer.ErrInvalidWeight
+
+// WeightedRoundRobinBalancer — round-robin с весами и возможностью менять их на лету.
+type WeightedRoundRobinBalancer interface {
+	balancer.Balancer
+	// SetWeight меняет вес сервера, изменение учитывается со следующего выбора.
+	SetWeight(server string, weight int) error
+}
+
+// wrrBalancer реализует плавный взвешенный round-robin (как в nginx, см. balancer.SmoothWeights):
+// серверы чередуются пропорционально весам, без серий подряд на один хост.
+type wrrBalancer struct {
+	mu       sync.Mutex
+	servers  []string
+	weights  []int                  // Заданные веса в порядке servers
+	index    map[string]int         // Позиция сервера в servers
+	current  balancer.SmoothWeights // Накопленные веса
+	eligible []int                  // Веса доступных серверов для очередного выбора
+	health   balancer.HealthSource  // Внешний источник здоровья
+}
+
+// NewWeightedRoundRobinBalancer создаёт взвешенный RoundRobin.
+// Серверы без веса (или с неположительным весом) получают вес 1, вес больше
+// balancer.MaxWeight уменьшается до него.
+func NewWeightedRoundRobinBalancer(servers []string, weights map[string]int) WeightedRoundRobinBalancer {
+	b := &wrrBalancer{
+		servers:  append([]string(nil), servers...),
+		weights:  make([]int, len(servers)),
+		index:    make(map[string]int, len(servers)),
+		current:  make(balancer.SmoothWeights, len(servers)),
+		eligible: make([]int, len(servers)),
+	}
+	for i, s := range servers {
+		w := weights[s]
+		if w <= 0 {
+			w = defaultWeight
+		}
+		b.weights[i] = min(w, balancer.MaxWeight)
+		b.index[s] = i
+	}
+	return b
+}
+
+// Next выбирает сервер с максимальным накопленным весом.
+func (b *wrrBalancer) Next() string {
+	srv, _ := b.Pick(nil)
+	return srv
+}
+
+// Pick выбирает сервер так же, как Next, запрос не учитывается.
+func (b *wrrBalancer) Pick(_ *http.Request) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.servers) == 0 {
+		return "", balancer.ErrNoBackends
+	}
+
+	// Недоступные серверы не участвуют в цикле и не накапливают вес
+	for i, s := range b.servers {
+		b.eligible[i] = 0
+		if b.health.Healthy(s) {
+			b.eligible[i] = b.weights[i]
+		}
+	}
+	best := b.current.Next(b.eligible)
+	if best < 0 {
+		return "", balancer.ErrNoHealthyBackends
+	}
+	return b.servers[best], nil
+}
+
+// SetWeight меняет вес сервера. Накопленные веса сбрасываются,
+// чтобы новое распределение начиналось с чистого цикла.
+func (b *wrrBalancer) SetWeight(server string, weight int) error {
+	if weight <= 0 || weight > balancer.MaxWeight {
+		return ErrInvalidWeight
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	i, ok := b.index[server]
+	if !ok {
+		return balancer.ErrUnknownServer
+	}
+	b.weights[i] = weight
+	b.current.Reset()
+	return nil
+}
+
+// SetHealth задаёт внешний источник здоровья бэкендов.
+func (b *wrrBalancer) SetHealth(h balancer.Health) {
+	b.health.Set(h)
+}
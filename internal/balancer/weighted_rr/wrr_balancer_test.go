@@ -0,0 +1,96 @@
+package weighted_rr
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/coffee-realist/balancer/internal/balancer"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestWeightedRoundRobin_SmoothSequence проверяет порядок выбора для классического примера nginx (5:1:1).
+func TestWeightedRoundRobin_SmoothSequence(t *testing.T) {
+	b := NewWeightedRoundRobinBalancer([]string{"A", "B", "C"}, map[string]int{"A": 5, "B": 1, "C": 1})
+
+	got := make([]string, 0, 7)
+	for i := 0; i < 7; i++ {
+		got = append(got, b.Next())
+	}
+	// Плавное чередование: тяжёлый сервер не получает серию из пяти запросов подряд
+	assert.Equal(t, []string{"A", "A", "B", "A", "C", "A", "A"}, got)
+}
+
+// TestWeightedRoundRobin_Distribution проверяет пропорциональность распределения и значения по умолчанию.
+func TestWeightedRoundRobin_Distribution(t *testing.T) {
+	t.Run("proportional to weights", func(t *testing.T) {
+		b := NewWeightedRoundRobinBalancer([]string{"A", "B", "C"}, map[string]int{"A": 3, "B": 2})
+		counts := make(map[string]int)
+		for i := 0; i < 600; i++ {
+			counts[b.Next()]++
+		}
+		// C не задан в весах и получает вес 1
+		assert.Equal(t, 300, counts["A"])
+		assert.Equal(t, 200, counts["B"])
+		assert.Equal(t, 100, counts["C"])
+	})
+
+	t.Run("empty list returns empty string", func(t *testing.T) {
+		empty := NewWeightedRoundRobinBalancer(nil, nil)
+		assert.Equal(t, "", empty.Next())
+	})
+}
+
+// TestWeightedRoundRobin_SetWeight проверяет, что изменение веса применяется без пересоздания балансировщика.
+func TestWeightedRoundRobin_SetWeight(t *testing.T) {
+	b := NewWeightedRoundRobinBalancer([]string{"A", "B"}, nil)
+
+	assert.NoError(t, b.SetWeight("B", 3))
+	counts := make(map[string]int)
+	for i := 0; i < 400; i++ {
+		counts[b.Next()]++
+	}
+	assert.Equal(t, 100, counts["A"])
+	assert.Equal(t, 300, counts["B"])
+
+	assert.ErrorIs(t, b.SetWeight("X", 2), balancer.ErrUnknownServer)
+	assert.ErrorIs(t, b.SetWeight("A", 0), ErrInvalidWeight)
+	assert.ErrorIs(t, b.SetWeight("A", balancer.MaxWeight+1), ErrInvalidWeight)
+}
+
+// TestWeightedRoundRobin_Concurrency проверяет потокобезопасность Next и SetWeight.
+func TestWeightedRoundRobin_Concurrency(t *testing.T) {
+	servers := []string{"foo", "bar", "baz"}
+	b := NewWeightedRoundRobinBalancer(servers, map[string]int{"foo": 2})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				assert.Contains(t, servers, b.Next())
+			}
+		}()
+		go func(w int) {
+			defer wg.Done()
+			assert.NoError(t, b.SetWeight("bar", w))
+		}(i%5 + 1)
+	}
+	wg.Wait()
+}
+
+// BenchmarkWeightedRoundRobin_Next измеряет производительность метода Next при 100 серверах.
+func BenchmarkWeightedRoundRobin_Next(b *testing.B) {
+	servers := make([]string, 100)
+	weights := make(map[string]int, 100)
+	for i := 0; i < 100; i++ {
+		servers[i] = "s" + strconv.Itoa(i)
+		weights[servers[i]] = i%4 + 1
+	}
+	wrr := NewWeightedRoundRobinBalancer(servers, weights)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = wrr.Next()
+	}
+}
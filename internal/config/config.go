@@ -17,14 +17,228 @@ type AdaptiveConfig struct {
 	HighThreshold int64 `yaml:"high_threshold"`
 }
 
+// EWMAConfig описывает параметры Peak-EWMA балансировщика.
+type EWMAConfig struct {
+	Decay time.Duration `yaml:"decay"` // Постоянная времени затухания оценки
+}
+
+// OutlierConfig описывает пассивную проверку здоровья по реальному трафику.
+// Нулевые значения заменяются значениями по умолчанию.
+type OutlierConfig struct {
+	Enabled                  bool          `yaml:"enabled"`
+	ConsecutiveErrors        int           `yaml:"consecutive_errors"`          // 5xx/ошибок шлюза подряд до исключения
+	Interval                 time.Duration `yaml:"interval"`                    // Период анализа и возврата серверов
+	BaseEjectionTime         time.Duration `yaml:"base_ejection_time"`          // Умножается на число исключений
+	MaxEjectionPercent       int           `yaml:"max_ejection_percent"`        // Максимальная доля исключённых серверов
+	SuccessRateMinHosts      int           `yaml:"success_rate_min_hosts"`      // Минимум серверов для анализа доли успехов
+	SuccessRateRequestVolume int64         `yaml:"success_rate_request_volume"` // Минимум запросов к серверу за интервал
+	SuccessRateStdevFactor   float64       `yaml:"success_rate_stdev_factor"`   // Порог: mean - factor*stdev
+}
+
+// HealthCheckConfig описывает активные проверки здоровья бэкендов, общие для всех стратегий.
+// Нулевые значения заменяются значениями по умолчанию.
+type HealthCheckConfig struct {
+	Enabled          *bool             `yaml:"enabled"`           // По умолчанию включены для всех стратегий
+	Type             string            `yaml:"type"`              // http | tcp | tls | grpc, по умолчанию http
+	ServerTypes      map[string]string `yaml:"server_types"`      // Протокол для отдельных серверов
+	GRPCService      string            `yaml:"grpc_service"`      // Сервис для grpc.health.v1.Health/Check
+	TLSServerName    string            `yaml:"tls_server_name"`   // SNI для tls и grpc поверх https
+	TLSSkipVerify    bool              `yaml:"tls_skip_verify"`   // Не проверять сертификат бэкенда
+	Path             string            `yaml:"path"`              // Путь проверки, по умолчанию /health
+	Method           string            `yaml:"method"`            // HEAD, либо GET при проверке тела
+	ExpectedStatuses []string          `yaml:"expected_statuses"` // Диапазоны вида "200-299" или "204"
+	BodyContains     string            `yaml:"body_contains"`     // Подстрока в теле ответа
+	BodyRegexp       string            `yaml:"body_regexp"`       // Регулярное выражение для тела ответа
+	Host             string            `yaml:"host"`              // Заголовок Host
+	Timeout          time.Duration     `yaml:"timeout"`
+	Interval         time.Duration     `yaml:"interval"` // По умолчанию health_check_interval
+	Jitter           time.Duration     `yaml:"jitter"`   // Случайная добавка к интервалу
+	Rise             int               `yaml:"rise"`     // Успешных проверок подряд для возврата
+	Fall             int               `yaml:"fall"`     // Неудачных проверок подряд для исключения
+}
+
+// HashConfig описывает параметры хеширующих стратегий.
+type HashConfig struct {
+	KeySource    string  `yaml:"key_source"`    // header | cookie | query | path | ip
+	KeyName      string  `yaml:"key_name"`      // Имя заголовка, cookie или query-параметра
+	VirtualNodes int     `yaml:"virtual_nodes"` // Виртуальных узлов на сервер
+	LoadFactor   float64 `yaml:"load_factor"`   // Допустимое превышение средней нагрузки
+	TableSize    uint64  `yaml:"table_size"`    // Размер lookup-таблицы Maglev (простое число)
+}
+
+// LogConfig описывает журнал; уровень можно менять во время работы через /admin/log-level.
+type LogConfig struct {
+	Level  string `yaml:"level"`  // debug | info | warn | error, по умолчанию info
+	Format string `yaml:"format"` // text | json, по умолчанию text
+}
+
+// AccessLogConfig описывает журнал доступа публичного адреса.
+type AccessLogConfig struct {
+	Enabled        bool          `yaml:"enabled"`
+	Format         string        `yaml:"format"`          // combined | json | template, по умолчанию combined
+	Template       string        `yaml:"template"`        // Шаблон text/template для format: template
+	Output         string        `yaml:"output"`          // stdout | file | syslog, по умолчанию stdout
+	File           string        `yaml:"file"`            // Путь к файлу для output: file
+	MaxSizeMB      int           `yaml:"max_size_mb"`     // Ротация по размеру файла
+	RotateInterval time.Duration `yaml:"rotate_interval"` // Ротация по времени
+	MaxBackups     int           `yaml:"max_backups"`     // Сколько ротированных файлов хранить
+	SyslogNetwork  string        `yaml:"syslog_network"`  // udp | tcp | unixgram; пусто — локальный syslog
+	SyslogAddress  string        `yaml:"syslog_address"`
+	SyslogTag      string        `yaml:"syslog_tag"` // По умолчанию balancer
+	QueueSize      int           `yaml:"queue_size"` // Очередь записей; при переполнении записи отбрасываются
+}
+
+// RetryPolicyConfig описывает повтор неудачного запроса на другом бэкенде.
+type RetryPolicyConfig struct {
+	MaxAttempts int           `yaml:"max_attempts"` // Всего попыток, включая первую; по умолчанию 3
+	On          []string      `yaml:"on"`           // connect-failure | timeout | статус ("502"); по умолчанию connect-failure
+	Backoff     time.Duration `yaml:"backoff"`      // Базовая задержка, удваивается с каждой попыткой
+	MaxBackoff  time.Duration `yaml:"max_backoff"`  // Предел задержки
+}
+
+// RetryRouteConfig — политика повторов для путей с префиксом; незаданные поля берутся из общей.
+type RetryRouteConfig struct {
+	PathPrefix        string `yaml:"path_prefix"`
+	RetryPolicyConfig `yaml:",inline"`
+}
+
+// RetryBudgetConfig ограничивает долю повторов от числа запросов.
+type RetryBudgetConfig struct {
+	Ratio      float64       `yaml:"ratio"`       // По умолчанию 0.2; отрицательное значение отключает бюджет
+	MinRetries int           `yaml:"min_retries"` // Повторов за окно при любой нагрузке, по умолчанию 10
+	Window     time.Duration `yaml:"window"`      // По умолчанию 10s
+}
+
+// RetryConfig описывает повторы запросов: только идемпотентные методы или запросы с Idempotency-Key.
+type RetryConfig struct {
+	RetryPolicyConfig `yaml:",inline"`
+	MaxBodyBytes      int64              `yaml:"max_body_bytes"` // Буфер тела для повторной отправки, по умолчанию 64 КиБ
+	Budget            RetryBudgetConfig  `yaml:"budget"`
+	Routes            []RetryRouteConfig `yaml:"routes"`
+}
+
+// HedgePolicyConfig описывает, когда медленный запрос дублируется на другой бэкенд.
+type HedgePolicyConfig struct {
+	Delay      time.Duration `yaml:"delay"`      // Ожидание ответа; при percentile — пока замеров мало
+	Percentile float64       `yaml:"percentile"` // Ожидание по перцентилю времени ответа маршрута, например 95
+	MinDelay   time.Duration `yaml:"min_delay"`  // Нижняя граница ожидания по перцентилю
+}
+
+// HedgeRouteConfig — политика хеджирования для путей с префиксом; незаданные поля берутся из общей.
+type HedgeRouteConfig struct {
+	PathPrefix        string `yaml:"path_prefix"`
+	Disabled          bool   `yaml:"disabled"` // Не хеджировать запросы с этим префиксом
+	HedgePolicyConfig `yaml:",inline"`
+}
+
+// HedgeBudgetConfig ограничивает долю дублирующих запросов от числа запросов.
+type HedgeBudgetConfig struct {
+	Ratio     float64       `yaml:"ratio"`      // По умолчанию 0.1; отрицательное значение отключает бюджет
+	MinHedges int           `yaml:"min_hedges"` // Дублирующих запросов за окно при любой нагрузке, по умолчанию 10
+	Window    time.Duration `yaml:"window"`     // По умолчанию 10s
+}
+
+// HedgeConfig описывает хеджирование: GET и HEAD без тела, не получившие ответа за задержку,
+// дублируются на другой бэкенд, клиенту уходит первый ответ.
+type HedgeConfig struct {
+	Enabled           bool `yaml:"enabled"`
+	HedgePolicyConfig `yaml:",inline"`
+	Budget            HedgeBudgetConfig  `yaml:"budget"`
+	Routes            []HedgeRouteConfig `yaml:"routes"`
+}
+
+// TransportConfig описывает соединения с бэкендами; незаданные поля получают значения по умолчанию.
+type TransportConfig struct {
+	DialTimeout           time.Duration `yaml:"dial_timeout"`            // По умолчанию 5s
+	TLSHandshakeTimeout   time.Duration `yaml:"tls_handshake_timeout"`   // По умолчанию 5s
+	ResponseHeaderTimeout time.Duration `yaml:"response_header_timeout"` // Ожидание заголовков ответа; по умолчанию без ограничения
+	RequestTimeout        time.Duration `yaml:"request_timeout"`         // Весь обмен с бэкендом; по умолчанию без ограничения
+	KeepAlive             time.Duration `yaml:"keep_alive"`              // TCP keep-alive, по умолчанию 30s; отрицательное — выключен
+	IdleConnTimeout       time.Duration `yaml:"idle_conn_timeout"`       // По умолчанию 90s
+	MaxIdleConnsPerHost   int           `yaml:"max_idle_conns_per_host"` // По умолчанию 64
+	MaxConnsPerHost       int           `yaml:"max_conns_per_host"`      // По умолчанию без ограничения
+}
+
+// UpstreamPoolConfig — настройки соединений для группы серверов; незаданные поля берутся из общих.
+type UpstreamPoolConfig struct {
+	Name            string   `yaml:"name"`
+	Servers         []string `yaml:"servers"`
+	TransportConfig `yaml:",inline"`
+}
+
+// UpstreamConfig описывает соединения с бэкендами: общие настройки и пулы серверов со своими.
+type UpstreamConfig struct {
+	TransportConfig `yaml:",inline"`
+	Pools           []UpstreamPoolConfig `yaml:"pools"`
+}
+
+// ServerTimeoutsConfig — таймауты входящих соединений публичного и управляющего адресов.
+type ServerTimeoutsConfig struct {
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"` // Чтение заголовков запроса, по умолчанию 10s
+	ReadTimeout       time.Duration `yaml:"read_timeout"`        // Чтение всего запроса; по умолчанию без ограничения
+	WriteTimeout      time.Duration `yaml:"write_timeout"`       // Запись ответа; по умолчанию без ограничения
+	IdleTimeout       time.Duration `yaml:"idle_timeout"`        // Простой keep-alive соединения, по умолчанию 120s
+	MaxHeaderBytes    int           `yaml:"max_header_bytes"`    // По умолчанию 1 МиБ
+}
+
+// ForwardingConfig описывает заголовки пересылки, которые получают бэкенды.
+type ForwardingConfig struct {
+	XForwarded     string   `yaml:"x_forwarded"`     // append | replace | off; по умолчанию append
+	Forwarded      string   `yaml:"forwarded"`       // RFC 7239: append | replace | off; по умолчанию off
+	XRealIP        bool     `yaml:"x_real_ip"`       // Передавать X-Real-IP с адресом клиента
+	TrustedProxies []string `yaml:"trusted_proxies"` // CIDR или адреса прокси, чьи заголовки пересылки принимаются
+}
+
+// TracingConfig описывает трассировку запросов и экспорт span по OTLP/HTTP.
+type TracingConfig struct {
+	Enabled      bool              `yaml:"enabled"`
+	ServiceName  string            `yaml:"service_name"`  // Атрибут service.name, по умолчанию balancer
+	OTLPEndpoint string            `yaml:"otlp_endpoint"` // Адрес коллектора, например http://localhost:4318
+	Headers      map[string]string `yaml:"headers"`       // Дополнительные заголовки запросов экспорта
+	Timeout      time.Duration     `yaml:"timeout"`       // Таймаут одного запроса экспорта
+}
+
+// AdminAuthConfig описывает аутентификацию управляющего API. Способы проверяются по очереди;
+// если ни один не задан, API доступен без аутентификации.
+type AdminAuthConfig struct {
+	TokenFile       string            `yaml:"token_file"`        // Файл статических токенов: "<токен> [read|write]" на строку
+	HMACSecretFile  string            `yaml:"hmac_secret_file"`  // Файл с секретом подписанных токенов
+	ClientCertRoles map[string]string `yaml:"client_cert_roles"` // CN клиентского сертификата -> роль (mTLS)
+}
+
+// AdminTLSConfig описывает TLS для admin_listen. ClientCAFile включает проверку клиентских сертификатов.
+type AdminTLSConfig struct {
+	CertFile     string `yaml:"cert_file"`
+	KeyFile      string `yaml:"key_file"`
+	ClientCAFile string `yaml:"client_ca_file"`
+}
+
+// Config — конфигурация балансировщика. Если задан AdminListen, на listen_port
+// обслуживается только проксирование, а управление — на отдельном адресе.
 type Config struct {
-	ListenPort          string            `yaml:"listen_port"`
-	Servers             []string          `yaml:"servers"`
-	Algorithm           string            `yaml:"algorithm"`
-	HealthCheckInterval time.Duration     `yaml:"health_check_interval"`
-	RateLimiter         RateLimiterConfig `yaml:"rate_limiter"`
-	DBPath              string            `yaml:"db_path"`
-	Adaptive            AdaptiveConfig    `yaml:"adaptive"`
+	ListenPort          string               `yaml:"listen_port"`
+	AdminListen         string               `yaml:"admin_listen"` // Адрес управляющего API; пустой — API на listen_port
+	AdminAuth           AdminAuthConfig      `yaml:"admin_auth"`
+	AdminTLS            AdminTLSConfig       `yaml:"admin_tls"`
+	Servers             []string             `yaml:"servers"`
+	Weights             map[string]int       `yaml:"weights"`
+	Algorithm           string               `yaml:"algorithm"`
+	HealthCheckInterval time.Duration        `yaml:"health_check_interval"`
+	HealthCheck         HealthCheckConfig    `yaml:"health_check"`
+	RateLimiter         RateLimiterConfig    `yaml:"rate_limiter"`
+	DBPath              string               `yaml:"db_path"`
+	Adaptive            AdaptiveConfig       `yaml:"adaptive"`
+	Hash                HashConfig           `yaml:"hash"`
+	EWMA                EWMAConfig           `yaml:"ewma"`
+	Outlier             OutlierConfig        `yaml:"outlier"`
+	Log                 LogConfig            `yaml:"log"`
+	AccessLog           AccessLogConfig      `yaml:"access_log"`
+	Tracing             TracingConfig        `yaml:"tracing"`
+	Retry               RetryConfig          `yaml:"retry"`
+	Hedge               HedgeConfig          `yaml:"hedge"`
+	Upstream            UpstreamConfig       `yaml:"upstream"`
+	Forwarding          ForwardingConfig     `yaml:"forwarding"`
+	ServerTimeouts      ServerTimeoutsConfig `yaml:"server_timeouts"`
 }
 
 func LoadConfig(path string) (*Config, error) {
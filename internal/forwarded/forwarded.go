@@ -0,0 +1,258 @@
+// Package forwarded определяет исходного клиента запроса по заголовкам пересылки
+// (X-Forwarded-For/-Proto/-Host, RFC 7239 Forwarded), принимая их только
+// от доверенных прокси, и формирует эти заголовки для бэкендов.
+package forwarded
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// Заголовки пересылки.
+const (
+	HeaderXForwardedFor   = "X-Forwarded-For"
+	HeaderXForwardedProto = "X-Forwarded-Proto"
+	HeaderXForwardedHost  = "X-Forwarded-Host"
+	HeaderForwarded       = "Forwarded"
+	HeaderXRealIP         = "X-Real-IP"
+)
+
+// Headers — все заголовки пересылки; от недоверенных клиентов они не принимаются.
+var Headers = []string{HeaderXForwardedFor, HeaderXForwardedProto, HeaderXForwardedHost, HeaderForwarded, HeaderXRealIP}
+
+// TrustedProxies — сети прокси, заголовкам пересылки от которых можно доверять.
+type TrustedProxies []netip.Prefix
+
+// ParseTrustedProxies разбирает список сетей в нотации CIDR или отдельных адресов.
+func ParseTrustedProxies(list []string) (TrustedProxies, error) {
+	out := make(TrustedProxies, 0, len(list))
+	for _, s := range list {
+		if !strings.Contains(s, "/") {
+			addr, err := netip.ParseAddr(s)
+			if err != nil {
+				return nil, fmt.Errorf("trusted proxy %q: %w", s, err)
+			}
+			addr = addr.Unmap()
+			out = append(out, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return nil, fmt.Errorf("trusted proxy %q: %w", s, err)
+		}
+		out = append(out, prefix.Masked())
+	}
+	return out, nil
+}
+
+// Contains сообщает, входит ли адрес в одну из доверенных сетей.
+func (t TrustedProxies) Contains(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, p := range t {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Client — исходный запрос клиента: адрес, протокол и хост до доверенных прокси.
+type Client struct {
+	IP      string // IP-адрес клиента
+	Proto   string // http или https
+	Host    string // Хост, к которому обращался клиент
+	Trusted bool   // Соединение пришло от доверенного прокси, его заголовки пересылки приняты
+}
+
+// Resolve определяет клиента запроса. Заголовки пересылки учитываются, только если
+// соединение пришло от доверенного прокси: цепочка X-Forwarded-For (или for= из Forwarded)
+// просматривается справа налево до первого адреса вне доверенных сетей.
+func Resolve(r *http.Request, trusted TrustedProxies) Client {
+	c := Client{IP: PeerIP(r), Proto: scheme(r), Host: r.Host}
+	peer, err := netip.ParseAddr(c.IP)
+	if err != nil || !trusted.Contains(peer) {
+		return c
+	}
+	c.Trusted = true
+
+	chain := splitList(r.Header.Values(HeaderXForwardedFor))
+	var elems []map[string]string
+	if len(chain) == 0 {
+		elems = parseForwarded(r.Header.Values(HeaderForwarded))
+		for _, e := range elems {
+			chain = append(chain, e["for"])
+		}
+	}
+	for i := len(chain) - 1; i >= 0; i-- {
+		addr, ok := parseNode(chain[i])
+		if !ok {
+			break // Неразборчивый адрес: дальше по цепочке доверять нельзя
+		}
+		c.IP = addr.String()
+		if !trusted.Contains(addr) {
+			break
+		}
+	}
+
+	proto, host := firstValue(r.Header, HeaderXForwardedProto), firstValue(r.Header, HeaderXForwardedHost)
+	if len(elems) > 0 {
+		proto, host = elems[0]["proto"], elems[0]["host"]
+	}
+	if proto = strings.ToLower(proto); proto == "http" || proto == "https" {
+		c.Proto = proto
+	}
+	if host != "" {
+		c.Host = host
+	}
+	return c
+}
+
+// PeerIP возвращает IP-адрес непосредственного соединения без порта.
+func PeerIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return addr.Unmap().String()
+	}
+	return host
+}
+
+// scheme возвращает протокол соединения клиента с балансировщиком.
+func scheme(r *http.Request) string {
+	if r.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// FormatNode форматирует адрес для параметра for= заголовка Forwarded: IPv6 — в кавычках
+// и квадратных скобках, как требует RFC 7239.
+func FormatNode(ip string) string {
+	if addr, err := netip.ParseAddr(ip); err == nil && addr.Is6() {
+		return `"[` + addr.String() + `]"`
+	}
+	return quote(ip)
+}
+
+// Element формирует элемент заголовка Forwarded.
+func Element(forIP, host, proto string) string {
+	var b strings.Builder
+	b.WriteString("for=")
+	b.WriteString(FormatNode(forIP))
+	if host != "" {
+		b.WriteString(";host=")
+		b.WriteString(quote(host))
+	}
+	if proto != "" {
+		b.WriteString(";proto=")
+		b.WriteString(proto)
+	}
+	return b.String()
+}
+
+// quote заключает значение в кавычки, если оно не является token по RFC 7230.
+func quote(v string) string {
+	for i := 0; i < len(v); i++ {
+		c := v[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		case strings.IndexByte("!#$%&'*+-.^_`|~", c) >= 0:
+		default:
+			return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(v) + `"`
+		}
+	}
+	return v
+}
+
+// parseNode разбирает адрес из X-Forwarded-For или for= заголовка Forwarded:
+// IPv4, IPv6 в скобках или без, возможно с портом.
+func parseNode(s string) (netip.Addr, bool) {
+	s = strings.TrimSpace(strings.Trim(strings.TrimSpace(s), `"`))
+	if addr, err := netip.ParseAddr(s); err == nil {
+		return addr.Unmap(), true
+	}
+	if ap, err := netip.ParseAddrPort(s); err == nil {
+		return ap.Addr().Unmap(), true
+	}
+	if strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]") {
+		if addr, err := netip.ParseAddr(s[1 : len(s)-1]); err == nil {
+			return addr.Unmap(), true
+		}
+	}
+	return netip.Addr{}, false
+}
+
+// parseForwarded разбирает элементы заголовков Forwarded в пары параметр-значение
+// с параметрами в нижнем регистре.
+func parseForwarded(values []string) []map[string]string {
+	var elems []map[string]string
+	for _, elem := range splitList(values) {
+		e := make(map[string]string)
+		for _, pair := range strings.Split(elem, ";") {
+			k, v, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if !ok {
+				continue
+			}
+			e[strings.ToLower(k)] = strings.Trim(v, `"`)
+		}
+		elems = append(elems, e)
+	}
+	return elems
+}
+
+// splitList разбивает значения заголовка-списка на элементы без пробелов по краям.
+func splitList(values []string) []string {
+	var out []string
+	for _, v := range values {
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				out = append(out, item)
+			}
+		}
+	}
+	return out
+}
+
+// firstValue возвращает первый элемент заголовка-списка.
+func firstValue(h http.Header, name string) string {
+	if items := splitList(h.Values(name)); len(items) > 0 {
+		return items[0]
+	}
+	return ""
+}
+
+type ctxKey struct{}
+
+// NewContext возвращает контекст со сведениями о клиенте.
+func NewContext(ctx context.Context, c Client) context.Context {
+	return context.WithValue(ctx, ctxKey{}, c)
+}
+
+// FromContext возвращает сведения о клиенте, сохранённые Middleware.
+func FromContext(ctx context.Context) (Client, bool) {
+	c, ok := ctx.Value(ctxKey{}).(Client)
+	return c, ok
+}
+
+// FromRequest возвращает сведения о клиенте из контекста запроса, а без Middleware —
+// по непосредственному соединению без доверия заголовкам пересылки.
+func FromRequest(r *http.Request) Client {
+	if c, ok := FromContext(r.Context()); ok {
+		return c
+	}
+	return Resolve(r, nil)
+}
+
+// Middleware определяет клиента каждого запроса с учётом доверенных прокси
+// и сохраняет сведения о нём в контексте (см. FromContext).
+func Middleware(next http.Handler, trusted TrustedProxies) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), Resolve(r, trusted))))
+	})
+}
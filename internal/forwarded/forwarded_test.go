@@ -0,0 +1,102 @@
+package forwarded
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mustTrusted(t *testing.T, list ...string) TrustedProxies {
+	trusted, err := ParseTrustedProxies(list)
+	require.NoError(t, err)
+	return trusted
+}
+
+// TestParseTrustedProxies проверяет разбор сетей и отдельных адресов.
+func TestParseTrustedProxies(t *testing.T) {
+	trusted := mustTrusted(t, "10.0.0.0/8", "192.0.2.1", "2001:db8::/32")
+	assert.True(t, trusted.Contains(netip.MustParseAddr("10.1.2.3")))
+	assert.True(t, trusted.Contains(netip.MustParseAddr("::ffff:192.0.2.1")), "IPv4-mapped address")
+	assert.True(t, trusted.Contains(netip.MustParseAddr("2001:db8::5")))
+	assert.False(t, trusted.Contains(netip.MustParseAddr("192.0.2.2")))
+
+	for _, bad := range []string{"10.0.0.0/33", "proxy.local", ""} {
+		_, err := ParseTrustedProxies([]string{bad})
+		assert.Error(t, err, bad)
+	}
+}
+
+// TestResolve проверяет определение клиента по заголовкам пересылки доверенных прокси.
+func TestResolve(t *testing.T) {
+	newRequest := func(remote string, headers ...string) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "http://lb.example/", nil)
+		r.RemoteAddr = remote
+		for i := 0; i < len(headers); i += 2 {
+			r.Header.Add(headers[i], headers[i+1])
+		}
+		return r
+	}
+	trusted := mustTrusted(t, "10.0.0.0/8")
+
+	t.Run("untrusted peer headers are ignored", func(t *testing.T) {
+		r := newRequest("203.0.113.7:5000", HeaderXForwardedFor, "1.1.1.1", HeaderXForwardedProto, "https")
+		assert.Equal(t, Client{IP: "203.0.113.7", Proto: "http", Host: "lb.example"}, Resolve(r, trusted))
+	})
+
+	t.Run("chain is walked to first untrusted address", func(t *testing.T) {
+		r := newRequest("10.0.0.1:5000",
+			HeaderXForwardedFor, "6.6.6.6, 198.51.100.4",
+			HeaderXForwardedFor, "10.0.0.9",
+			HeaderXForwardedProto, "https",
+			HeaderXForwardedHost, "shop.example")
+		assert.Equal(t, Client{IP: "198.51.100.4", Proto: "https", Host: "shop.example", Trusted: true}, Resolve(r, trusted))
+	})
+
+	t.Run("all trusted gives leftmost address", func(t *testing.T) {
+		r := newRequest("10.0.0.1:5000", HeaderXForwardedFor, "10.0.0.3, 10.0.0.2")
+		assert.Equal(t, "10.0.0.3", Resolve(r, trusted).IP)
+	})
+
+	t.Run("malformed entry stops the walk", func(t *testing.T) {
+		r := newRequest("10.0.0.1:5000", HeaderXForwardedFor, "198.51.100.4, unknown, 10.0.0.2")
+		assert.Equal(t, "10.0.0.2", Resolve(r, trusted).IP)
+	})
+
+	t.Run("Forwarded is used without X-Forwarded-For", func(t *testing.T) {
+		r := newRequest("10.0.0.1:5000", HeaderForwarded, `for="[2001:db8::7]:4711";proto=https;host=shop.example, for=10.0.0.2`)
+		assert.Equal(t, Client{IP: "2001:db8::7", Proto: "https", Host: "shop.example", Trusted: true}, Resolve(r, trusted))
+	})
+
+	t.Run("TLS connection without headers", func(t *testing.T) {
+		r := newRequest("203.0.113.7:5000")
+		r.TLS = &tls.ConnectionState{}
+		assert.Equal(t, "https", Resolve(r, nil).Proto)
+	})
+}
+
+// TestElement проверяет форматирование элемента Forwarded.
+func TestElement(t *testing.T) {
+	assert.Equal(t, "for=192.0.2.60;host=lb.example;proto=http", Element("192.0.2.60", "lb.example", "http"))
+	assert.Equal(t, `for="[2001:db8::1]";host="lb.example:8080";proto=https`, Element("2001:db8::1", "lb.example:8080", "https"))
+}
+
+// TestMiddleware проверяет сохранение сведений о клиенте в контексте.
+func TestMiddleware(t *testing.T) {
+	var got Client
+	h := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = FromRequest(r)
+	}), mustTrusted(t, "192.0.2.0/24"))
+
+	r := httptest.NewRequest(http.MethodGet, "http://lb.example/", nil)
+	r.Header.Set(HeaderXForwardedFor, "198.51.100.4")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	assert.Equal(t, "198.51.100.4", got.IP)
+
+	// Без Middleware заголовкам не доверяют
+	assert.Equal(t, "192.0.2.1", FromRequest(r).IP)
+}
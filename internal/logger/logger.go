@@ -1,24 +1,128 @@
 package logger
 
 import (
-	"log"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
 )
 
+// Logger — журнал с уровнями поверх log/slog. Атрибуты, добавленные через With
+// (ID запроса, ключ клиента, бэкенд, задержка), выводятся отдельными полями записи.
 type Logger interface {
+	Debugf(format string, args ...interface{})
 	Infof(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
 	Errorf(format string, args ...interface{})
+	// With возвращает журнал, добавляющий к каждой записи пары ключ-значение args.
+	With(args ...interface{}) Logger
 }
 
-type stdLogger struct{}
+// Форматы вывода.
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Config описывает журнал. Нулевые поля заменяются значениями по умолчанию.
+type Config struct {
+	Level  string    // debug | info | warn | error, по умолчанию info
+	Format string    // text | json, по умолчанию text
+	Output io.Writer // По умолчанию os.Stderr
+}
 
+type slogLogger struct {
+	l *slog.Logger
+}
+
+// New создаёт текстовый журнал уровня info в os.Stderr.
 func New() Logger {
-	return &stdLogger{}
+	l, _, _ := NewFromConfig(Config{})
+	return l
+}
+
+// NewFromConfig создаёт журнал по конфигурации и возвращает его уровень,
+// который можно менять во время работы (см. api.RegisterLogLevel).
+func NewFromConfig(cfg Config) (Logger, *slog.LevelVar, error) {
+	level := new(slog.LevelVar)
+	if cfg.Level != "" {
+		if err := level.UnmarshalText([]byte(cfg.Level)); err != nil {
+			return nil, nil, fmt.Errorf("invalid log level %q", cfg.Level)
+		}
+	}
+	out := cfg.Output
+	if out == nil {
+		out = os.Stderr
+	}
+
+	opts := &slog.HandlerOptions{Level: level}
+	var h slog.Handler
+	switch cfg.Format {
+	case "", FormatText:
+		h = slog.NewTextHandler(out, opts)
+	case FormatJSON:
+		h = slog.NewJSONHandler(out, opts)
+	default:
+		return nil, nil, fmt.Errorf("invalid log format %q", cfg.Format)
+	}
+	return &slogLogger{l: slog.New(h)}, level, nil
+}
+
+// Nop возвращает журнал, отбрасывающий все записи.
+func Nop() Logger {
+	return &slogLogger{l: slog.New(slog.DiscardHandler)}
+}
+
+func (s *slogLogger) Debugf(format string, args ...interface{}) {
+	s.logf(slog.LevelDebug, format, args)
+}
+
+func (s *slogLogger) Infof(format string, args ...interface{}) {
+	s.logf(slog.LevelInfo, format, args)
+}
+
+func (s *slogLogger) Warnf(format string, args ...interface{}) {
+	s.logf(slog.LevelWarn, format, args)
+}
+
+func (s *slogLogger) Errorf(format string, args ...interface{}) {
+	s.logf(slog.LevelError, format, args)
+}
+
+func (s *slogLogger) With(args ...interface{}) Logger {
+	return &slogLogger{l: s.l.With(args...)}
+}
+
+// logf форматирует сообщение, только если запись уровня level будет выведена.
+func (s *slogLogger) logf(level slog.Level, format string, args []interface{}) {
+	ctx := context.Background()
+	if !s.l.Enabled(ctx, level) {
+		return
+	}
+	s.l.Log(ctx, level, fmt.Sprintf(format, args...))
+}
+
+type ctxKey struct{}
+
+// NewContext возвращает контекст с журналом запроса.
+func NewContext(ctx context.Context, l Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, l)
 }
 
-func (l *stdLogger) Infof(format string, args ...interface{}) {
-	log.Printf("[INFO] "+format, args...)
+// FromContext возвращает журнал запроса из контекста или fallback, если его нет.
+func FromContext(ctx context.Context, fallback Logger) Logger {
+	if l, ok := ctx.Value(ctxKey{}).(Logger); ok {
+		return l
+	}
+	return fallback
 }
 
-func (l *stdLogger) Errorf(format string, args ...interface{}) {
-	log.Printf("[ERROR] "+format, args...)
+// Fingerprint возвращает первые 12 шестнадцатеричных символов SHA-256 секрета (например, API-ключа).
+// По отпечатку записи журнала можно связать с клиентом, не раскрывая сам секрет.
+func Fingerprint(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:6])
 }
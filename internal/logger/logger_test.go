@@ -0,0 +1,71 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestNewFromConfig_JSON проверяет JSON-вывод с атрибутами и смену уровня во время работы.
+func TestNewFromConfig_JSON(t *testing.T) {
+	var buf bytes.Buffer
+	log, level, err := NewFromConfig(Config{Level: "info", Format: FormatJSON, Output: &buf})
+	require.NoError(t, err)
+
+	log.Debugf("hidden %d", 1)
+	assert.Empty(t, buf.String(), "debug is below info")
+
+	log.With("backend", "http://a:80", "status", 502).Errorf("backend error: %s", "refused")
+	var rec map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &rec))
+	assert.Equal(t, "ERROR", rec["level"])
+	assert.Equal(t, "backend error: refused", rec["msg"])
+	assert.Equal(t, "http://a:80", rec["backend"])
+	assert.Equal(t, 502.0, rec["status"])
+
+	buf.Reset()
+	level.Set(slog.LevelDebug)
+	log.Debugf("visible")
+	assert.Contains(t, buf.String(), `"msg":"visible"`)
+}
+
+// TestNewFromConfig_Text проверяет текстовый вывод и ошибки конфигурации.
+func TestNewFromConfig_Text(t *testing.T) {
+	var buf bytes.Buffer
+	log, _, err := NewFromConfig(Config{Level: "warn", Output: &buf})
+	require.NoError(t, err)
+	log.Infof("hidden")
+	log.With("client_key", "k1").Warnf("rate limit exceeded")
+	out := buf.String()
+	assert.Equal(t, 1, strings.Count(out, "\n"))
+	assert.Contains(t, out, `level=WARN msg="rate limit exceeded" client_key=k1`)
+
+	_, _, err = NewFromConfig(Config{Level: "verbose"})
+	assert.Error(t, err)
+	_, _, err = NewFromConfig(Config{Format: "xml"})
+	assert.Error(t, err)
+}
+
+// TestContext проверяет передачу журнала запроса через контекст.
+func TestContext(t *testing.T) {
+	fallback := Nop()
+	assert.Equal(t, fallback, FromContext(context.Background(), fallback))
+
+	reqLog := New().With("request_id", "r1")
+	assert.Equal(t, reqLog, FromContext(NewContext(context.Background(), reqLog), fallback))
+}
+
+// TestFingerprint проверяет, что отпечаток короткий, стабильный и не содержит секрета.
+func TestFingerprint(t *testing.T) {
+	fp := Fingerprint("client-1")
+	assert.Len(t, fp, 12)
+	assert.Equal(t, fp, Fingerprint("client-1"))
+	assert.NotEqual(t, fp, Fingerprint("client-2"))
+	assert.NotContains(t, fp, "client-1")
+}
@@ -0,0 +1,353 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultBuckets — границы гистограммы задержек в секундах (как в клиенте Prometheus).
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Sample — значение метрики с набором значений меток, вычисляемое при сборе.
+type Sample struct {
+	Labels []string
+	Value  float64
+}
+
+// family — метрика с описанием, выводимая в формате Prometheus.
+type family interface {
+	write(w io.Writer) error
+}
+
+// Registry — набор метрик, выводимых в текстовом формате Prometheus (version 0.0.4).
+// Реализует http.Handler для эндпоинта /metrics.
+type Registry struct {
+	mu       sync.Mutex
+	families []family
+}
+
+// NewRegistry создаёт пустой Registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// register добавляет метрику в вывод.
+func (r *Registry) register(f family) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.families = append(r.families, f)
+}
+
+// NewCounterVec регистрирует счётчик с метками.
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{desc: desc{name: name, help: help, typ: "counter", labels: labels}, series: map[string]*Counter{}}
+	r.register(c)
+	return c
+}
+
+// NewHistogramVec регистрирует гистограмму с метками; buckets — верхние границы по возрастанию.
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	h := &HistogramVec{
+		desc:    desc{name: name, help: help, typ: "histogram", labels: labels},
+		buckets: buckets,
+		series:  map[string]*Histogram{},
+	}
+	r.register(h)
+	return h
+}
+
+// NewGaugeFunc регистрирует gauge, значения которого вычисляет fn при каждом сборе.
+func (r *Registry) NewGaugeFunc(name, help string, labels []string, fn func() []Sample) {
+	r.register(&funcFamily{desc: desc{name: name, help: help, typ: "gauge", labels: labels}, fn: fn})
+}
+
+// NewCounterFunc регистрирует счётчик, значения которого вычисляет fn при каждом сборе
+// (для компонентов, которые сами ведут атомарные счётчики).
+func (r *Registry) NewCounterFunc(name, help string, labels []string, fn func() []Sample) {
+	r.register(&funcFamily{desc: desc{name: name, help: help, typ: "counter", labels: labels}, fn: fn})
+}
+
+// Write выводит все метрики в текстовом формате Prometheus.
+func (r *Registry) Write(w io.Writer) error {
+	r.mu.Lock()
+	families := slices.Clone(r.families)
+	r.mu.Unlock()
+	for _, f := range families {
+		if err := f.write(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ServeHTTP отдаёт метрики для сбора Prometheus.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_ = r.Write(w)
+}
+
+// desc — имя, описание, тип и имена меток метрики.
+type desc struct {
+	name   string
+	help   string
+	typ    string
+	labels []string
+}
+
+// writeHeader выводит строки HELP и TYPE.
+func (d desc) writeHeader(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", d.name, escapeHelp(d.help), d.name, d.typ)
+	return err
+}
+
+// writeSample выводит строку значения; extra — дополнительная метка (le для гистограмм).
+func (d desc) writeSample(w io.Writer, suffix string, values []string, extraName, extraValue string, v float64) error {
+	var b strings.Builder
+	b.WriteString(d.name)
+	b.WriteString(suffix)
+	if len(values) > 0 || extraName != "" {
+		b.WriteByte('{')
+		for i, name := range d.labels {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			writeLabel(&b, name, values[i])
+		}
+		if extraName != "" {
+			if len(d.labels) > 0 {
+				b.WriteByte(',')
+			}
+			writeLabel(&b, extraName, extraValue)
+		}
+		b.WriteByte('}')
+	}
+	b.WriteByte(' ')
+	b.WriteString(formatFloat(v))
+	b.WriteByte('\n')
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeLabel выводит пару name="value" с экранированием значения.
+func writeLabel(b *strings.Builder, name, value string) {
+	b.WriteString(name)
+	b.WriteString(`="`)
+	b.WriteString(labelEscaper.Replace(value))
+	b.WriteByte('"')
+}
+
+var (
+	labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+// escapeHelp экранирует текст описания метрики.
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
+
+// formatFloat форматирует значение так, как его ожидает Prometheus.
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// seriesKey склеивает значения меток в ключ серии.
+func seriesKey(values []string) string {
+	return strings.Join(values, "\xff")
+}
+
+// checkLabels паникует при несовпадении числа значений меток: это ошибка программы.
+func (d desc) checkLabels(values []string) {
+	if len(values) != len(d.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", d.name, len(d.labels), len(values)))
+	}
+}
+
+// Counter — монотонно растущий счётчик.
+type Counter struct {
+	values []string
+	n      atomic.Uint64
+}
+
+// Inc увеличивает счётчик на единицу.
+func (c *Counter) Inc() {
+	c.n.Add(1)
+}
+
+// Add увеличивает счётчик на n.
+func (c *Counter) Add(n uint64) {
+	c.n.Add(n)
+}
+
+// CounterVec — счётчики, различающиеся значениями меток.
+type CounterVec struct {
+	desc
+	mu     sync.RWMutex
+	series map[string]*Counter
+}
+
+// With возвращает счётчик для значений меток в порядке их объявления.
+func (v *CounterVec) With(values ...string) *Counter {
+	v.checkLabels(values)
+	key := seriesKey(values)
+	v.mu.RLock()
+	c, ok := v.series[key]
+	v.mu.RUnlock()
+	if ok {
+		return c
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if c, ok = v.series[key]; !ok {
+		c = &Counter{values: slices.Clone(values)}
+		v.series[key] = c
+	}
+	return c
+}
+
+func (v *CounterVec) write(w io.Writer) error {
+	if err := v.writeHeader(w); err != nil {
+		return err
+	}
+	v.mu.RLock()
+	keys := sortedKeys(v.series)
+	series := make([]*Counter, len(keys))
+	for i, k := range keys {
+		series[i] = v.series[k]
+	}
+	v.mu.RUnlock()
+	for _, c := range series {
+		if err := v.writeSample(w, "", c.values, "", "", float64(c.n.Load())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Histogram — распределение наблюдаемых значений по корзинам.
+type Histogram struct {
+	values  []string
+	buckets []float64
+	counts  []atomic.Uint64 // Наблюдения по корзинам (не накопительно), последняя — +Inf
+	sum     atomic.Uint64   // math.Float64bits суммы
+}
+
+// Observe учитывает значение.
+func (h *Histogram) Observe(v float64) {
+	i, _ := slices.BinarySearch(h.buckets, v)
+	h.counts[i].Add(1)
+	for {
+		old := h.sum.Load()
+		if h.sum.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+v)) {
+			return
+		}
+	}
+}
+
+// HistogramVec — гистограммы, различающиеся значениями меток.
+type HistogramVec struct {
+	desc
+	buckets []float64
+	mu      sync.RWMutex
+	series  map[string]*Histogram
+}
+
+// With возвращает гистограмму для значений меток в порядке их объявления.
+func (v *HistogramVec) With(values ...string) *Histogram {
+	v.checkLabels(values)
+	key := seriesKey(values)
+	v.mu.RLock()
+	h, ok := v.series[key]
+	v.mu.RUnlock()
+	if ok {
+		return h
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if h, ok = v.series[key]; !ok {
+		h = &Histogram{
+			values:  slices.Clone(values),
+			buckets: v.buckets,
+			counts:  make([]atomic.Uint64, len(v.buckets)+1),
+		}
+		v.series[key] = h
+	}
+	return h
+}
+
+func (v *HistogramVec) write(w io.Writer) error {
+	if err := v.writeHeader(w); err != nil {
+		return err
+	}
+	v.mu.RLock()
+	keys := sortedKeys(v.series)
+	series := make([]*Histogram, len(keys))
+	for i, k := range keys {
+		series[i] = v.series[k]
+	}
+	v.mu.RUnlock()
+	for _, h := range series {
+		var cumulative uint64
+		for i, upper := range h.buckets {
+			cumulative += h.counts[i].Load()
+			if err := v.writeSample(w, "_bucket", h.values, "le", formatFloat(upper), float64(cumulative)); err != nil {
+				return err
+			}
+		}
+		cumulative += h.counts[len(h.buckets)].Load()
+		if err := v.writeSample(w, "_bucket", h.values, "le", "+Inf", float64(cumulative)); err != nil {
+			return err
+		}
+		if err := v.writeSample(w, "_sum", h.values, "", "", math.Float64frombits(h.sum.Load())); err != nil {
+			return err
+		}
+		if err := v.writeSample(w, "_count", h.values, "", "", float64(cumulative)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// funcFamily — метрика, значения которой вычисляются при сборе.
+type funcFamily struct {
+	desc
+	fn func() []Sample
+}
+
+func (f *funcFamily) write(w io.Writer) error {
+	if err := f.writeHeader(w); err != nil {
+		return err
+	}
+	for _, s := range f.fn() {
+		f.checkLabels(s.Labels)
+		if err := f.writeSample(w, "", s.Labels, "", "", s.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sortedKeys возвращает ключи серий по возрастанию, чтобы вывод был стабильным.
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
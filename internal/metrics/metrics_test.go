@@ -0,0 +1,83 @@
+package metrics
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRegistry_Exposition проверяет текстовый формат счётчиков, гистограмм и gauge.
+func TestRegistry_Exposition(t *testing.T) {
+	r := NewRegistry()
+	requests := r.NewCounterVec("requests_total", "Requests.", "backend", "status")
+	latency := r.NewHistogramVec("latency_seconds", "Latency.", []float64{0.1, 1}, "backend")
+	r.NewGaugeFunc("healthy", "Health\nstate.", []string{"backend"}, func() []Sample {
+		return []Sample{{Labels: []string{`a"b\c`}, Value: 1}}
+	})
+	r.NewCounterFunc("picks_total", "Picks.", nil, func() []Sample {
+		return []Sample{{Value: 3}}
+	})
+
+	requests.With("b", "200").Inc()
+	requests.With("a", "500").Add(2)
+	latency.With("a").Observe(0.05)
+	latency.With("a").Observe(0.5)
+	latency.With("a").Observe(5)
+
+	var b strings.Builder
+	require.NoError(t, r.Write(&b))
+	assert.Equal(t, `# HELP requests_total Requests.
+# TYPE requests_total counter
+requests_total{backend="a",status="500"} 2
+requests_total{backend="b",status="200"} 1
+# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{backend="a",le="0.1"} 1
+latency_seconds_bucket{backend="a",le="1"} 2
+latency_seconds_bucket{backend="a",le="+Inf"} 3
+latency_seconds_sum{backend="a"} 5.55
+latency_seconds_count{backend="a"} 3
+# HELP healthy Health\nstate.
+# TYPE healthy gauge
+healthy{backend="a\"b\\c"} 1
+# HELP picks_total Picks.
+# TYPE picks_total counter
+picks_total 3
+`, b.String())
+}
+
+// TestRegistry_Handler проверяет тип содержимого эндпоинта /metrics.
+func TestRegistry_Handler(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounterVec("up_total", "Up.").With().Inc()
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Body.String(), "up_total 1\n")
+}
+
+// TestHistogram_Concurrent проверяет наблюдения из нескольких горутин.
+func TestHistogram_Concurrent(t *testing.T) {
+	h := NewRegistry().NewHistogramVec("h", "H.", DefaultBuckets).With()
+	var wg sync.WaitGroup
+	for range 100 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			h.Observe(0.5)
+		}()
+	}
+	wg.Wait()
+	var total uint64
+	for i := range h.counts {
+		total += h.counts[i].Load()
+	}
+	assert.Equal(t, uint64(100), total)
+	assert.InDelta(t, 50.0, math.Float64frombits(h.sum.Load()), 1e-9)
+}
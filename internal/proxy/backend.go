@@ -0,0 +1,169 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/coffee-realist/balancer/internal/logger"
+	"github.com/coffee-realist/balancer/internal/tracing"
+)
+
+// backend — готовый обработчик запросов к одному бэкенду. Создаётся один раз на сервер,
+// а состояние конкретной попытки передаётся через контекст запроса (см. attemptState).
+type backend struct {
+	target     *url.URL
+	timeout    time.Duration     // Ограничение времени обмена из настроек пула, 0 — без ограничения
+	forwarding *ForwardingConfig // Заголовки пересылки, общие для всех бэкендов прокси
+	proxy      *httputil.ReverseProxy
+}
+
+// attemptState — состояние одной попытки, которое читают обработчики ReverseProxy.
+type attemptState struct {
+	res     attemptResult
+	client  context.Context // Контекст входящего запроса, без ограничения времени обмена с бэкендом
+	start   time.Time
+	log     logger.Logger
+	discard func(status int) bool
+	replay  bool // Сохранять отброшенный ответ, чтобы отдать его клиенту, если повтора не будет
+	keepErr func(err error) bool
+}
+
+// attemptKey — ключ attemptState в контексте запроса.
+type attemptKey struct{}
+
+// attemptFrom возвращает состояние попытки из контекста запроса к бэкенду.
+func attemptFrom(ctx context.Context) *attemptState {
+	return ctx.Value(attemptKey{}).(*attemptState)
+}
+
+// AddBackend заранее создаёт обработчик для сервера, чтобы первый запрос к нему
+// не тратил время на разбор адреса. Вызывается при добавлении сервера в состав.
+func (p *Proxy) AddBackend(server string) error {
+	be, err := p.newBackend(server)
+	if err != nil {
+		return err
+	}
+	p.backends.Store(server, be)
+	return nil
+}
+
+// RemoveBackend удаляет обработчик сервера; начатые запросы к нему завершаются как обычно.
+func (p *Proxy) RemoveBackend(server string) {
+	p.backends.Delete(server)
+}
+
+// backendFor возвращает обработчик сервера, создавая его при первом обращении.
+func (p *Proxy) backendFor(server string) (*backend, error) {
+	if be, ok := p.backends.Load(server); ok {
+		return be.(*backend), nil
+	}
+	be, err := p.newBackend(server)
+	if err != nil {
+		return nil, err
+	}
+	actual, _ := p.backends.LoadOrStore(server, be)
+	return actual.(*backend), nil
+}
+
+// newBackend создаёт обработчик запросов к серверу с транспортом его пула.
+func (p *Proxy) newBackend(server string) (*backend, error) {
+	target, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+	up := p.upstreamFor(server)
+	be := &backend{target: target, timeout: up.timeout, forwarding: &p.forwarding}
+	be.proxy = &httputil.ReverseProxy{
+		Rewrite:        be.rewrite,
+		Transport:      up.transport,
+		ModifyResponse: modifyResponse,
+		ErrorHandler:   handleError,
+		BufferPool:     copyBuffers,
+	}
+	return be, nil
+}
+
+// copyBufferSize — размер буфера копирования тела ответа, как у ReverseProxy по умолчанию.
+const copyBufferSize = 32 << 10
+
+// bufferPool переиспользует буферы копирования тела ответа между запросами.
+type bufferPool struct{ pool sync.Pool }
+
+// copyBuffers — общий для всех бэкендов пул буферов копирования.
+var copyBuffers = &bufferPool{pool: sync.Pool{New: func() any {
+	b := make([]byte, copyBufferSize)
+	return &b
+}}}
+
+func (bp *bufferPool) Get() []byte { return *bp.pool.Get().(*[]byte) }
+
+func (bp *bufferPool) Put(b []byte) { bp.pool.Put(&b) }
+
+// rewrite направляет запрос на бэкенд, формирует заголовки пересылки и передаёт контекст трассы.
+// Заголовок Host исходного запроса сохраняется.
+func (be *backend) rewrite(pr *httputil.ProxyRequest) {
+	pr.Out.URL.Scheme = be.target.Scheme
+	pr.Out.URL.Host = be.target.Host
+	be.forwarding.apply(pr)
+	tracing.Inject(pr.Out.Context(), pr.Out.Header)
+}
+
+// maxReplayBytes ограничивает тело отброшенного ответа, сохраняемого для клиента.
+const maxReplayBytes = 64 << 10
+
+// modifyResponse запоминает статус ответа и отбрасывает ответ, если так решил вызывающий.
+// При replay отброшенный ответ сохраняется (см. attemptResult.resp); ответ с телом
+// больше maxReplayBytes не отбрасывается, а передаётся клиенту.
+func modifyResponse(resp *http.Response) error {
+	a := attemptFrom(resp.Request.Context())
+	a.res.latency = time.Since(a.start)
+	a.res.status = resp.StatusCode
+	if !a.discard(a.res.status) {
+		return nil
+	}
+	if a.replay {
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxReplayBytes+1))
+		if err == nil && len(body) > maxReplayBytes {
+			resp.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+			return nil
+		}
+		if err == nil {
+			a.res.resp = &savedResponse{header: resp.Header.Clone(), body: body}
+		}
+	}
+	return errDiscarded
+}
+
+// handleError запоминает ошибку транспорта и, если вызывающий не решил иначе, отвечает клиенту.
+func handleError(w http.ResponseWriter, r *http.Request, err error) {
+	a := attemptFrom(r.Context())
+	if errors.Is(err, errDiscarded) {
+		a.log.With("latency", a.res.latency, "status", a.res.status).Debugf("backend response discarded")
+		a.res.written = false
+		return
+	}
+	a.res.latency = time.Since(a.start)
+	if a.client.Err() != nil {
+		// Клиент ушёл или попытку отменил выигравший хеджированный запрос — это не ошибка
+		// бэкенда, поэтому стратегиям она не сообщается (см. balancer.FeedbackAware)
+		a.log.With("latency", a.res.latency).Debugf("backend request canceled")
+	} else {
+		a.res.err = err
+		a.log.With("latency", a.res.latency).Errorf("backend error: %v", err)
+	}
+	if a.keepErr(err) {
+		a.res.written = false
+		return
+	}
+	writeFailure(w, r, attemptResult{err: err})
+}
@@ -0,0 +1,104 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httputil"
+	"strings"
+
+	"github.com/coffee-realist/balancer/internal/forwarded"
+)
+
+// HeaderMode — как прокси формирует заголовки пересылки для бэкенда.
+type HeaderMode string
+
+const (
+	// HeaderOff — заголовки не добавляются; полученные от доверенного прокси передаются как есть.
+	HeaderOff HeaderMode = "off"
+	// HeaderAppend — адрес соединения дописывается к цепочке, полученной от доверенного прокси.
+	HeaderAppend HeaderMode = "append"
+	// HeaderReplace — цепочка заменяется сведениями об исходном клиенте.
+	HeaderReplace HeaderMode = "replace"
+)
+
+// ForwardingConfig — заголовки пересылки, которые получает бэкенд. Заголовки от клиентов
+// вне доверенных прокси (см. forwarded.Middleware) отбрасываются при любом режиме.
+type ForwardingConfig struct {
+	XForwarded HeaderMode // X-Forwarded-For, X-Forwarded-Proto, X-Forwarded-Host; пустой — HeaderOff
+	Forwarded  HeaderMode // Forwarded по RFC 7239; пустой — HeaderOff
+	XRealIP    bool       // X-Real-IP с адресом исходного клиента
+}
+
+// DefaultForwarding — заголовки пересылки без настроек: цепочка X-Forwarded-*.
+var DefaultForwarding = ForwardingConfig{XForwarded: HeaderAppend, Forwarded: HeaderOff}
+
+// SetForwarding задаёт заголовки пересылки. Вызывается до начала обслуживания.
+func (p *Proxy) SetForwarding(cfg ForwardingConfig) {
+	p.forwarding = cfg
+}
+
+// apply формирует заголовки пересылки исходящего запроса. ReverseProxy с Rewrite
+// уже удалил из pr.Out заголовки Forwarded и X-Forwarded-*, X-Real-IP удаляется здесь.
+func (cfg *ForwardingConfig) apply(pr *httputil.ProxyRequest) {
+	in, out := pr.In.Header, pr.Out.Header
+	out.Del(forwarded.HeaderXRealIP)
+
+	c := forwarded.FromRequest(pr.In)
+	peer := forwarded.PeerIP(pr.In)
+	proto := "http"
+	if pr.In.TLS != nil {
+		proto = "https"
+	}
+
+	switch cfg.XForwarded {
+	case HeaderAppend:
+		chain, fwdProto, fwdHost := peer, proto, pr.In.Host
+		if c.Trusted {
+			if prior := in.Values(forwarded.HeaderXForwardedFor); len(prior) > 0 {
+				chain = strings.Join(prior, ", ") + ", " + peer
+			}
+			fwdProto, fwdHost = c.Proto, c.Host
+		}
+		out.Set(forwarded.HeaderXForwardedFor, chain)
+		out.Set(forwarded.HeaderXForwardedProto, fwdProto)
+		out.Set(forwarded.HeaderXForwardedHost, fwdHost)
+	case HeaderReplace:
+		out.Set(forwarded.HeaderXForwardedFor, c.IP)
+		out.Set(forwarded.HeaderXForwardedProto, c.Proto)
+		out.Set(forwarded.HeaderXForwardedHost, c.Host)
+	default:
+		if c.Trusted {
+			copyHeaders(out, in, forwarded.HeaderXForwardedFor, forwarded.HeaderXForwardedProto, forwarded.HeaderXForwardedHost)
+		}
+	}
+
+	switch cfg.Forwarded {
+	case HeaderAppend:
+		elem := forwarded.Element(peer, pr.In.Host, proto)
+		if prior := in.Values(forwarded.HeaderForwarded); c.Trusted && len(prior) > 0 {
+			elem = strings.Join(prior, ", ") + ", " + elem
+		}
+		out.Set(forwarded.HeaderForwarded, elem)
+	case HeaderReplace:
+		out.Set(forwarded.HeaderForwarded, forwarded.Element(c.IP, c.Host, c.Proto))
+	default:
+		if c.Trusted {
+			copyHeaders(out, in, forwarded.HeaderForwarded)
+		}
+	}
+
+	switch {
+	case cfg.XRealIP:
+		out.Set(forwarded.HeaderXRealIP, c.IP)
+	case c.Trusted:
+		copyHeaders(out, in, forwarded.HeaderXRealIP)
+	}
+}
+
+// copyHeaders копирует значения заголовков names из src в dst.
+func copyHeaders(dst, src http.Header, names ...string) {
+	for _, name := range names {
+		if v := src.Values(name); len(v) > 0 {
+			dst[name] = append([]string(nil), v...)
+		}
+	}
+}
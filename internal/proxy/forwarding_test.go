@@ -0,0 +1,86 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/coffee-realist/balancer/internal/forwarded"
+	"github.com/coffee-realist/balancer/internal/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestProxyForwardingHeaders проверяет заголовки пересылки, которые получает бэкенд,
+// для клиентов напрямую и через доверенный прокси.
+func TestProxyForwardingHeaders(t *testing.T) {
+	var got http.Header
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+	}))
+	t.Cleanup(backend.Close)
+	trusted, err := forwarded.ParseTrustedProxies([]string{"10.0.0.0/8"})
+	require.NoError(t, err)
+
+	// send отправляет запрос от remote с заголовками пересылки headers
+	send := func(cfg ForwardingConfig, remote string, headers map[string]string) {
+		p := NewProxy(&stubBalancer{server: backend.URL}, logger.Nop())
+		p.SetForwarding(cfg)
+		r := httptest.NewRequest(http.MethodGet, "http://shop.example/", nil)
+		r.RemoteAddr = remote
+		for k, v := range headers {
+			r.Header.Set(k, v)
+		}
+		got = nil
+		forwarded.Middleware(p.Handler(), trusted).ServeHTTP(httptest.NewRecorder(), r)
+		require.NotNil(t, got)
+	}
+	spoofed := map[string]string{
+		"X-Forwarded-For":   "6.6.6.6",
+		"X-Forwarded-Proto": "https",
+		"X-Forwarded-Host":  "evil.example",
+		"Forwarded":         "for=6.6.6.6",
+		"X-Real-IP":         "6.6.6.6",
+	}
+	viaProxy := map[string]string{
+		"X-Forwarded-For":   "198.51.100.4",
+		"X-Forwarded-Proto": "https",
+		"X-Forwarded-Host":  "shop.example",
+		"Forwarded":         "for=198.51.100.4;proto=https",
+	}
+
+	t.Run("default strips headers of untrusted client", func(t *testing.T) {
+		send(DefaultForwarding, "203.0.113.7:5000", spoofed)
+		assert.Equal(t, "203.0.113.7", got.Get("X-Forwarded-For"))
+		assert.Equal(t, "http", got.Get("X-Forwarded-Proto"))
+		assert.Equal(t, "shop.example", got.Get("X-Forwarded-Host"))
+		assert.Empty(t, got.Get("Forwarded"))
+		assert.Empty(t, got.Get("X-Real-IP"))
+	})
+
+	t.Run("append extends chain of trusted proxy", func(t *testing.T) {
+		send(ForwardingConfig{XForwarded: HeaderAppend, Forwarded: HeaderAppend}, "10.0.0.1:5000", viaProxy)
+		assert.Equal(t, "198.51.100.4, 10.0.0.1", got.Get("X-Forwarded-For"))
+		assert.Equal(t, "https", got.Get("X-Forwarded-Proto"))
+		assert.Equal(t, "for=198.51.100.4;proto=https, for=10.0.0.1;host=shop.example;proto=http", got.Get("Forwarded"))
+	})
+
+	t.Run("replace sends original client only", func(t *testing.T) {
+		send(ForwardingConfig{XForwarded: HeaderReplace, Forwarded: HeaderReplace, XRealIP: true}, "10.0.0.1:5000", viaProxy)
+		assert.Equal(t, "198.51.100.4", got.Get("X-Forwarded-For"))
+		assert.Equal(t, "https", got.Get("X-Forwarded-Proto"))
+		assert.Equal(t, "for=198.51.100.4;host=shop.example;proto=https", got.Get("Forwarded"))
+		assert.Equal(t, "198.51.100.4", got.Get("X-Real-IP"))
+	})
+
+	t.Run("off passes trusted headers unchanged", func(t *testing.T) {
+		send(ForwardingConfig{XForwarded: HeaderOff}, "10.0.0.1:5000", viaProxy)
+		assert.Equal(t, "198.51.100.4", got.Get("X-Forwarded-For"))
+		assert.Equal(t, "for=198.51.100.4;proto=https", got.Get("Forwarded"))
+
+		send(ForwardingConfig{XForwarded: HeaderOff}, "203.0.113.7:5000", spoofed)
+		for name := range spoofed {
+			assert.Empty(t, got.Get(name), name)
+		}
+	})
+}
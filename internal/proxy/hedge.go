@@ -0,0 +1,341 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/coffee-realist/balancer/internal/logger"
+)
+
+// HedgePolicy описывает, когда отправлять дублирующий запрос другому бэкенду.
+// Политика с нулевыми Delay и Percentile отключает хеджирование.
+type HedgePolicy struct {
+	Delay      time.Duration // Ожидание ответа перед дублирующим запросом
+	Percentile float64       // Если задан, ожидание — этот перцентиль времени ответа маршрута, а Delay — пока замеров мало
+	MinDelay   time.Duration // Нижняя граница ожидания по перцентилю
+}
+
+// enabled сообщает, включено ли хеджирование.
+func (h HedgePolicy) enabled() bool {
+	return h.Delay > 0 || h.Percentile > 0
+}
+
+// HedgeRoute задаёт политику для запросов, путь которых начинается с PathPrefix.
+type HedgeRoute struct {
+	PathPrefix string
+	Policy     HedgePolicy
+}
+
+// HedgeConfig — настройки хеджирования. Хеджируются только GET и HEAD без тела.
+type HedgeConfig struct {
+	Default HedgePolicy  // Политика для путей без отдельного маршрута
+	Routes  []HedgeRoute // Выбирается маршрут с самым длинным подходящим префиксом
+	Budget  *Budget      // Ограничение доли дублирующих запросов; nil — без ограничения
+}
+
+// hedging — настройки хеджирования с замерами времени ответа по маршрутам.
+type hedging struct {
+	HedgeConfig
+	latency map[string]*latencyTracker // По префиксу маршрута, "" — пути без маршрута
+
+	sent      atomic.Uint64 // Отправленные дублирующие запросы
+	won       atomic.Uint64 // Дублирующие запросы, ответившие первыми
+	exhausted atomic.Uint64 // Дублирующие запросы, не отправленные из-за бюджета
+}
+
+// SetHedge задаёт политику хеджирования. Вызывается до начала обслуживания.
+func (p *Proxy) SetHedge(cfg HedgeConfig) {
+	p.hedge.HedgeConfig = cfg
+	p.hedge.latency = map[string]*latencyTracker{"": {}}
+	for _, route := range cfg.Routes {
+		p.hedge.latency[route.PathPrefix] = &latencyTracker{}
+	}
+}
+
+// HedgeStats возвращает число отправленных дублирующих запросов, выигравших среди них
+// и не отправленных из-за исчерпания бюджета.
+func (p *Proxy) HedgeStats() (sent, won, budgetExhausted uint64) {
+	return p.hedge.sent.Load(), p.hedge.won.Load(), p.hedge.exhausted.Load()
+}
+
+// policyFor возвращает политику и префикс маршрута для пути запроса.
+func (h *hedging) policyFor(r *http.Request) (HedgePolicy, string) {
+	policy, route := h.Default, ""
+	for _, rt := range h.Routes {
+		if strings.HasPrefix(r.URL.Path, rt.PathPrefix) && len(rt.PathPrefix) > len(route) {
+			policy, route = rt.Policy, rt.PathPrefix
+		}
+	}
+	return policy, route
+}
+
+// delay возвращает ожидание перед дублирующим запросом; false, если его пока не из чего рассчитать.
+func (h *hedging) delay(policy HedgePolicy, route string) (time.Duration, bool) {
+	if policy.Percentile > 0 {
+		if d, ok := h.latency[route].percentile(policy.Percentile); ok {
+			return max(d, policy.MinDelay), true
+		}
+	}
+	return policy.Delay, policy.Delay > 0
+}
+
+// hedgeable сообщает, можно ли продублировать запрос: GET или HEAD без тела и не Upgrade.
+func hedgeable(r *http.Request) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	return (r.Body == nil || r.Body == http.NoBody) && r.Header.Get("Upgrade") == ""
+}
+
+// hedgeAttempt — итог одной из параллельных попыток.
+type hedgeAttempt struct {
+	i       int
+	res     attemptResult
+	aborted bool // Копирование ответа клиенту прервано (http.ErrAbortHandler)
+}
+
+// forwardHedged отправляет запрос на primary и, если ответа нет дольше задержки политики
+// (или primary сразу ответил ошибкой), — на следующий сервер из cands. Клиенту уходит первый
+// полученный ответ, остальные попытки отменяются. Как и forward, ответы, для которых discard
+// вернул true, и ошибка последней попытки, для которой keepErr вернул true, клиенту не пишутся,
+// а возвращаются вызывающему для повтора. Результаты попыток сообщаются через report.
+func (p *Proxy) forwardHedged(
+	w http.ResponseWriter,
+	r *http.Request,
+	cands *candidates,
+	primary string,
+	attempt int,
+	policy HedgePolicy,
+	route string,
+	discard func(status int) bool,
+	keepErr func(err error) bool,
+) attemptResult {
+	log := logger.FromContext(r.Context(), p.logger)
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	var (
+		servers  []string
+		cancels  []context.CancelFunc
+		winner   atomic.Int32 // Номер выигравшей попытки с 1, 0 — ответа пока нет
+		won      = make(chan struct{})
+		results  = make(chan hedgeAttempt, 2)
+		outcomes = make([]attemptResult, 0, 2)
+	)
+	launch := func(server string) {
+		i := len(servers)
+		actx, acancel := context.WithCancel(ctx)
+		servers, cancels = append(servers, server), append(cancels, acancel)
+		outcomes = append(outcomes, attemptResult{})
+
+		hw := &hedgeWriter{w: w, won: func() bool { return winner.Load() == int32(i+1) }}
+		claim := func(status int) bool {
+			if discard(status) {
+				return true // Ответ для повтора, как ошибка: пробуем другой сервер
+			}
+			if winner.CompareAndSwap(0, int32(i+1)) {
+				close(won)
+				return false
+			}
+			return true // Другая попытка уже ответила
+		}
+		go func() {
+			a := hedgeAttempt{i: i}
+			defer func() {
+				// ReverseProxy прерывает обработчик паникой, если не удалось передать ответ;
+				// в этой горутине её некому перехватить, поэтому она повторяется в обработчике.
+				if v := recover(); v != nil {
+					if v != http.ErrAbortHandler {
+						panic(v)
+					}
+					a.aborted = true
+				}
+				results <- a
+			}()
+			a.res = p.forward(hw, r.WithContext(actx), server, attempt+i, true, claim, func(error) bool { return true })
+		}()
+	}
+
+	// hedge отправляет дублирующий запрос, если бюджет и стратегия это позволяют.
+	hedge := func(reason string) {
+		if len(servers) > 1 || winner.Load() != 0 {
+			return
+		}
+		if !p.hedge.Budget.spend() {
+			p.hedge.exhausted.Add(1)
+			log.With("backend", primary).Debugf("hedge budget exhausted")
+			return
+		}
+		alt, err := cands.next()
+		if err != nil {
+			log.With("backend", primary).Debugf("no backend to hedge: %v", err)
+			return
+		}
+		p.hedge.sent.Add(1)
+		log.With("backend", primary, "hedge", alt, "reason", reason).Debugf("sending hedged request")
+		launch(alt)
+	}
+
+	launch(primary)
+	var timer <-chan time.Time
+	if d, ok := p.hedge.delay(policy, route); ok {
+		t := time.NewTimer(d)
+		defer t.Stop()
+		timer = t.C
+	}
+
+	aborted := false
+	for pending := 1; pending > 0; {
+		select {
+		case <-timer:
+			timer = nil
+			if winner.Load() == 0 {
+				before := len(servers)
+				hedge("slow")
+				pending += len(servers) - before
+			}
+		case <-won:
+			won = nil
+			// Первый ответ получен — остальные попытки больше не нужны
+			for i, c := range cancels {
+				if int32(i+1) != winner.Load() {
+					c()
+				}
+			}
+		case a := <-results:
+			pending--
+			outcomes[a.i] = a.res
+			aborted = aborted || a.aborted
+			if winner.Load() == 0 && pending == 0 {
+				// Единственная попытка не удалась: сразу пробуем другой сервер
+				timer = nil
+				before := len(servers)
+				hedge("error")
+				pending += len(servers) - before
+			}
+		}
+	}
+
+	// Проигравшие попытки сообщаются первыми, чтобы журнал доступа указал ответивший бэкенд
+	win := int(winner.Load()) - 1
+	for i, res := range outcomes {
+		if i == win {
+			continue
+		}
+		// Отменённая из-за чужого ответа попытка приходит без ошибки (см. handleError)
+		p.report(r, servers[i], res)
+	}
+	if win < 0 {
+		// Ни одна попытка не ответила: решение о повторе за вызывающим, как у forward
+		res := outcomes[len(outcomes)-1]
+		if res.status == 0 && !keepErr(res.err) {
+			writeFailure(w, r, res)
+			res.written = true
+		}
+		return res
+	}
+	p.report(r, servers[win], outcomes[win])
+	p.hedge.latency[route].record(outcomes[win].latency)
+	if win > 0 {
+		p.hedge.won.Add(1)
+	}
+	if aborted {
+		panic(http.ErrAbortHandler)
+	}
+	return outcomes[win]
+}
+
+// hedgeWriter передаёт ответ клиенту, только если его попытка выиграла. Заголовки
+// копятся в собственной карте, чтобы параллельные попытки не писали в общую.
+type hedgeWriter struct {
+	w      http.ResponseWriter
+	header http.Header
+	won    func() bool
+}
+
+func (hw *hedgeWriter) Header() http.Header {
+	if hw.header == nil {
+		hw.header = make(http.Header)
+	}
+	return hw.header
+}
+
+func (hw *hedgeWriter) WriteHeader(code int) {
+	if !hw.won() {
+		return
+	}
+	h := hw.w.Header()
+	for k, v := range hw.header {
+		h[k] = v
+	}
+	hw.w.WriteHeader(code)
+	if code < http.StatusOK {
+		// Заголовки информационного ответа не переходят в итоговый
+		for k := range hw.header {
+			h.Del(k)
+		}
+	}
+}
+
+func (hw *hedgeWriter) Write(b []byte) (int, error) {
+	if !hw.won() {
+		return len(b), nil
+	}
+	return hw.w.Write(b)
+}
+
+// FlushError сбрасывает буфер клиента для потоковых ответов.
+func (hw *hedgeWriter) FlushError() error {
+	if !hw.won() {
+		return nil
+	}
+	return http.NewResponseController(hw.w).Flush()
+}
+
+// latencySamples — сколько последних замеров хранится для расчёта перцентиля.
+const latencySamples = 512
+
+// latencyTracker хранит последние времена ответа маршрута.
+type latencyTracker struct {
+	mu      sync.Mutex
+	samples [latencySamples]time.Duration
+	n       int // Всего замеров
+
+	cachedAt int // n на момент расчёта cached
+	cachedP  float64
+	cached   time.Duration
+}
+
+// minLatencySamples — с какого числа замеров перцентиль считается надёжным.
+const minLatencySamples = 20
+
+// record добавляет замер.
+func (t *latencyTracker) record(d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.samples[t.n%latencySamples] = d
+	t.n++
+}
+
+// percentile возвращает перцентиль q (0–100) последних замеров; false, пока замеров мало.
+// Значение пересчитывается не чаще чем раз в 16 замеров.
+func (t *latencyTracker) percentile(q float64) (time.Duration, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.n < minLatencySamples {
+		return 0, false
+	}
+	if t.cachedAt > 0 && t.cachedP == q && t.n-t.cachedAt < 16 {
+		return t.cached, true
+	}
+	sorted := slices.Clone(t.samples[:min(t.n, latencySamples)])
+	slices.Sort(sorted)
+	idx := int(q / 100 * float64(len(sorted)-1))
+	t.cached, t.cachedP, t.cachedAt = sorted[min(max(idx, 0), len(sorted)-1)], q, t.n
+	return t.cached, true
+}
@@ -0,0 +1,188 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/coffee-realist/balancer/internal/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// slowBackend отвечает "slow" через delay и считает запросы, отменённые до ответа.
+type slowBackend struct {
+	*httptest.Server
+	canceled atomic.Int32
+}
+
+func newSlowBackend(t *testing.T, delay time.Duration) *slowBackend {
+	b := &slowBackend{}
+	b.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(delay):
+			w.Header().Set("X-Backend", "slow")
+			_, _ = io.WriteString(w, "slow")
+		case <-r.Context().Done():
+			b.canceled.Add(1)
+		}
+	}))
+	t.Cleanup(b.Close)
+	return b
+}
+
+// TestProxyHedging проверяет дублирование медленного запроса, отмену проигравшего и бюджет.
+func TestProxyHedging(t *testing.T) {
+	policy := HedgePolicy{Delay: 20 * time.Millisecond}
+
+	t.Run("slow backend is hedged and canceled", func(t *testing.T) {
+		slow, fast := newSlowBackend(t, 2*time.Second), newRecordingBackend(t, http.StatusOK)
+		sb := &stubFeedback{}
+		p := NewProxy(&stubRotation{servers: []string{slow.URL, fast.URL}}, logger.Nop())
+		p.AddFeedback(sb)
+		p.SetHedge(HedgeConfig{Default: policy})
+
+		rec := httptest.NewRecorder()
+		start := time.Now()
+		p.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://any/foo", nil))
+		assert.Less(t, time.Since(start), time.Second)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "OK", rec.Body.String())
+		assert.Empty(t, rec.Header().Get("X-Backend"))
+
+		sent, won, _ := p.HedgeStats()
+		assert.Equal(t, uint64(1), sent)
+		assert.Equal(t, uint64(1), won)
+		// Отменённый проигравший не считается ошибкой бэкенда
+		assert.Equal(t, []outcome{{}, {status: http.StatusOK}}, sb.observed)
+		assert.Eventually(t, func() bool { return slow.canceled.Load() == 1 }, time.Second, 5*time.Millisecond)
+	})
+
+	t.Run("fast backend is not hedged", func(t *testing.T) {
+		fast, other := newRecordingBackend(t, http.StatusOK), newRecordingBackend(t, http.StatusOK)
+		p := NewProxy(&stubRotation{servers: []string{fast.URL, other.URL}}, logger.Nop())
+		p.SetHedge(HedgeConfig{Default: HedgePolicy{Delay: time.Second}})
+
+		rec := httptest.NewRecorder()
+		p.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://any/foo", nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		sent, _, _ := p.HedgeStats()
+		assert.Zero(t, sent)
+		assert.Empty(t, other.received())
+	})
+
+	t.Run("connect failure is hedged without waiting", func(t *testing.T) {
+		down := httptest.NewServer(http.NotFoundHandler())
+		down.Close()
+		fast := newRecordingBackend(t, http.StatusOK)
+		p := NewProxy(&stubRotation{servers: []string{down.URL, fast.URL}}, logger.Nop())
+		p.SetRetry(RetryConfig{Default: RetryPolicy{MaxAttempts: 1}})
+		p.SetHedge(HedgeConfig{Default: HedgePolicy{Delay: time.Minute}})
+
+		rec := httptest.NewRecorder()
+		p.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://any/foo", nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Len(t, fast.received(), 1)
+	})
+
+	t.Run("request with body is not hedged", func(t *testing.T) {
+		slow, fast := newSlowBackend(t, 50*time.Millisecond), newRecordingBackend(t, http.StatusOK)
+		p := NewProxy(&stubRotation{servers: []string{slow.URL, fast.URL}}, logger.Nop())
+		p.SetHedge(HedgeConfig{Default: policy})
+
+		rec := httptest.NewRecorder()
+		p.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "http://any/foo", strings.NewReader("x")))
+		assert.Equal(t, "slow", rec.Body.String())
+		assert.Empty(t, fast.received())
+	})
+
+	t.Run("budget caps hedges", func(t *testing.T) {
+		slow, fast := newSlowBackend(t, 50*time.Millisecond), newRecordingBackend(t, http.StatusOK)
+		p := NewProxy(&stubRotation{servers: []string{slow.URL, fast.URL}}, logger.Nop())
+		p.SetHedge(HedgeConfig{Default: policy, Budget: NewBudget(0, 0, time.Minute)})
+
+		rec := httptest.NewRecorder()
+		p.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://any/foo", nil))
+		assert.Equal(t, "slow", rec.Body.String())
+		assert.Equal(t, "slow", rec.Header().Get("X-Backend"))
+		sent, _, exhausted := p.HedgeStats()
+		assert.Zero(t, sent)
+		assert.Equal(t, uint64(1), exhausted)
+	})
+
+	t.Run("hedged request follows retry policy", func(t *testing.T) {
+		a, b := newRecordingBackend(t, http.StatusServiceUnavailable), newRecordingBackend(t, http.StatusServiceUnavailable)
+		good := newRecordingBackend(t, http.StatusOK)
+		p := NewProxy(&stubRotation{servers: []string{a.URL, b.URL, good.URL}}, logger.Nop())
+		p.SetRetry(RetryConfig{Default: RetryPolicy{MaxAttempts: 2, Statuses: []int{http.StatusServiceUnavailable}}})
+		p.SetHedge(HedgeConfig{Default: HedgePolicy{Delay: time.Minute}})
+
+		rec := httptest.NewRecorder()
+		p.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://any/foo", nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Len(t, a.received(), 1)
+		assert.Len(t, b.received(), 1, "retryable status is hedged without waiting")
+		assert.Len(t, good.received(), 1)
+		retried, _ := p.RetryStats()
+		assert.Equal(t, uint64(1), retried)
+	})
+
+	t.Run("budget counts only hedgeable requests", func(t *testing.T) {
+		slow, fast := newSlowBackend(t, 50*time.Millisecond), newRecordingBackend(t, http.StatusOK)
+		rot := &stubRotation{servers: []string{slow.URL, fast.URL}}
+		p := NewProxy(rot, logger.Nop())
+		p.SetHedge(HedgeConfig{Default: policy, Budget: NewBudget(0.5, 0, time.Minute)})
+		handler := p.Handler()
+
+		for range 2 {
+			rot.i = 1
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "http://any/foo", strings.NewReader("x")))
+		}
+		rot.i = 0
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://any/foo", nil))
+		assert.Equal(t, "slow", rec.Body.String())
+		sent, _, exhausted := p.HedgeStats()
+		assert.Zero(t, sent)
+		assert.Equal(t, uint64(1), exhausted)
+	})
+
+	t.Run("route without policy is not hedged", func(t *testing.T) {
+		slow, fast := newSlowBackend(t, 50*time.Millisecond), newRecordingBackend(t, http.StatusOK)
+		p := NewProxy(&stubRotation{servers: []string{slow.URL, fast.URL}}, logger.Nop())
+		p.SetHedge(HedgeConfig{Default: policy, Routes: []HedgeRoute{{PathPrefix: "/stream"}}})
+
+		rec := httptest.NewRecorder()
+		p.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://any/stream/1", nil))
+		assert.Equal(t, "slow", rec.Body.String())
+		assert.Empty(t, fast.received())
+	})
+}
+
+// TestHedgeDelay проверяет задержку по перцентилю с запасной фиксированной и нижней границей.
+func TestHedgeDelay(t *testing.T) {
+	var p Proxy
+	p.SetHedge(HedgeConfig{Routes: []HedgeRoute{{PathPrefix: "/api"}}})
+	policy := HedgePolicy{Delay: time.Second, Percentile: 95, MinDelay: 5 * time.Millisecond}
+
+	d, ok := p.hedge.delay(policy, "/api")
+	require.True(t, ok)
+	assert.Equal(t, time.Second, d, "fixed delay until enough samples")
+
+	for i := 1; i <= 100; i++ {
+		p.hedge.latency["/api"].record(time.Duration(i) * time.Millisecond)
+	}
+	d, _ = p.hedge.delay(policy, "/api")
+	assert.Equal(t, 95*time.Millisecond, d)
+
+	policy.MinDelay = 200 * time.Millisecond
+	d, _ = p.hedge.delay(policy, "/api")
+	assert.Equal(t, 200*time.Millisecond, d)
+
+	_, ok = p.hedge.delay(HedgePolicy{Percentile: 95}, "")
+	assert.False(t, ok, "no samples and no fixed delay")
+}
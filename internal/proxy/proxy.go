@@ -1,70 +1,350 @@
 package proxy
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"maps"
 	"net/http"
-	"net/http/httputil"
-	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/coffee-realist/balancer/internal/balancer"
 	"github.com/coffee-realist/balancer/internal/logger"
+	"github.com/coffee-realist/balancer/internal/requestid"
+	"github.com/coffee-realist/balancer/internal/tracing"
 )
 
 // Proxy инкапсулирует проксирующую логику и использует балансировщик для выбора сервера.
 type Proxy struct {
-	balancer  balancer.Balancer // Интерфейс балансировщика
-	logger    logger.Logger     // Логгер для вывода служебной информации
-	transport http.RoundTripper // HTTP-транспорт для выполнения запросов (можно переопределить)
+	balancer   balancer.Balancer        // Интерфейс балансировщика
+	picker     balancer.RequestBalancer // Выбор сервера с учётом запроса
+	logger     logger.Logger            // Логгер для вывода служебной информации
+	upstream   upstream                 // Транспорт к бэкендам вне пулов
+	pools      map[string]upstream      // Транспорт к бэкендам пулов, см. SetUpstreams
+	backends   sync.Map                 // Адрес сервера -> *backend
+	feedback   []balancer.FeedbackAware // Получатели результатов запросов к бэкендам
+	observers  []Observer               // Получатели результатов вместе с исходным запросом
+	retry      RetryConfig              // Политика повторов на другом бэкенде
+	hedge      hedging                  // Хеджирование медленных запросов
+	forwarding ForwardingConfig         // Заголовки пересылки для бэкендов
+
+	retried         atomic.Uint64 // Выполненные повторы
+	budgetExhausted atomic.Uint64 // Повторы, отклонённые бюджетом
+}
+
+// Observer получает результат каждого обращения к бэкенду вместе с исходным запросом
+// (метрики, журналы). Вызывается после получателей balancer.FeedbackAware.
+type Observer interface {
+	Observe(r *http.Request, server string, latency time.Duration, status int, err error)
 }
 
 // NewProxy создает новый экземпляр Proxy с указанным балансировщиком и логгером.
 func NewProxy(b balancer.Balancer, log logger.Logger) *Proxy {
-	return &Proxy{
-		balancer:  b,
-		logger:    log,
-		transport: http.DefaultTransport,
+	p := &Proxy{
+		balancer:   b,
+		picker:     balancer.AsRequestBalancer(b),
+		logger:     log,
+		upstream:   upstream{transport: http.DefaultTransport},
+		retry:      RetryConfig{Default: DefaultRetryPolicy, MaxBodyBytes: DefaultMaxBodyBytes},
+		forwarding: DefaultForwarding,
+	}
+	// Сама стратегия получает результаты запросов первой
+	if fa, ok := b.(balancer.FeedbackAware); ok {
+		p.feedback = append(p.feedback, fa)
 	}
+	return p
+}
+
+// AddFeedback добавляет получателя результатов запросов к бэкендам
+// (пассивная проверка здоровья, статистика). Вызывается до начала обслуживания.
+func (p *Proxy) AddFeedback(f balancer.FeedbackAware) {
+	p.feedback = append(p.feedback, f)
+}
+
+// AddObserver добавляет получателя результатов обращений к бэкендам вместе с запросом.
+// Вызывается до начала обслуживания.
+func (p *Proxy) AddObserver(o Observer) {
+	p.observers = append(p.observers, o)
+}
+
+// SetRetry задаёт политику повторов. Нулевой MaxBodyBytes заменяется на DefaultMaxBodyBytes.
+// Вызывается до начала обслуживания.
+func (p *Proxy) SetRetry(cfg RetryConfig) {
+	if cfg.MaxBodyBytes <= 0 {
+		cfg.MaxBodyBytes = DefaultMaxBodyBytes
+	}
+	p.retry = cfg
+}
+
+// RetryStats возвращает число выполненных повторов и повторов, отклонённых бюджетом.
+func (p *Proxy) RetryStats() (retried, budgetExhausted uint64) {
+	return p.retried.Load(), p.budgetExhausted.Load()
 }
 
 // Handler возвращает http.Handler, который проксирует запросы на серверы, выбранные балансировщиком.
+// Неудачная попытка повторяется на другом сервере по политике повторов (см. SetRetry),
+// а медленный ответ может быть продублирован запросом к другому серверу (см. SetHedge);
+// при хеджировании политика повторов применяется к каждой хеджированной попытке.
 func (p *Proxy) Handler() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Выбор сервера через балансировщик
-		server := p.balancer.Next()
-		if server == "" {
-			http.Error(w, "no servers available", http.StatusServiceUnavailable)
-			return
+		log := logger.FromContext(r.Context(), p.logger)
+		policy := p.retry.policyFor(r)
+		p.retry.Budget.request()
+		// Бюджет хеджирования считается только по запросам, которые можно продублировать
+		hedge, hedgeRoute := p.hedge.policyFor(r)
+		hedged := hedge.enabled() && hedgeable(r)
+		if hedged {
+			p.hedge.Budget.request()
 		}
 
-		// Увеличиваем счетчик соединений, если балансировщик поддерживает ConnAware
-		if ca, ok := p.balancer.(balancer.ConnAware); ok {
-			ca.Increase(server)
-			defer ca.Decrease(server)
+		// Повторяются только идемпотентные запросы, тело которых помещается в буфер
+		retryable := policy.MaxAttempts > 1 && idempotent(r)
+		var body []byte
+		if retryable {
+			var err error
+			body, retryable, err = bufferBody(r, p.retry.MaxBodyBytes)
+			if err != nil {
+				log.Warnf("reading request body: %v", err)
+				requestid.Error(w, r, "invalid request body", http.StatusBadRequest)
+				return
+			}
 		}
 
-		p.logger.Infof("proxying %s %s -> %s", r.Method, r.URL.String(), server)
-
-		// Парсинг целевого URL
-		targetURL, err := url.Parse(server)
+		// Выбор сервера (и запасных, если стратегия их предоставляет)
+		_, span := tracing.Start(r.Context(), "balancer.pick", tracing.KindInternal)
+		cands := &candidates{p: p, r: r, rank: retryable}
+		server, err := cands.next()
+		span.RecordError(err)
+		span.End()
 		if err != nil {
-			p.logger.Errorf("invalid server URL %q: %v", server, err)
-			http.Error(w, "bad server URL", http.StatusInternalServerError)
+			log.Warnf("no server for request: %v", err)
+			requestid.Error(w, r, pickErrorMessage(err), http.StatusServiceUnavailable)
 			return
 		}
 
-		// Создание и настройка reverse proxy
-		proxy := &httputil.ReverseProxy{
-			Director: func(req *http.Request) {
-				req.URL.Scheme = targetURL.Scheme
-				req.URL.Host = targetURL.Host
-			},
-			Transport: p.transport,
-			ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
-				p.logger.Errorf("server %s error: %v", server, err)
-				http.Error(w, "bad gateway", http.StatusBadGateway)
-			},
-		}
+		for attempt := 1; ; attempt++ {
+			if body != nil {
+				r.Body, r.ContentLength = io.NopCloser(bytes.NewReader(body)), int64(len(body))
+			}
+			retry := retryable && attempt < policy.MaxAttempts && cands.more() && p.retry.Budget.available()
+			discard := func(status int) bool { return retry && policy.retryStatus(status) }
+			keepErr := func(err error) bool { return retry && policy.retryErr(err) && r.Context().Err() == nil }
+			var res attemptResult
+			if hedged {
+				// Каждая попытка хеджируется, повторы идут по той же политике
+				res = p.forwardHedged(w, r, cands, server, attempt, hedge, hedgeRoute, discard, keepErr)
+			} else {
+				res = p.forward(w, r, server, attempt, true, discard, keepErr)
+				p.report(r, server, res)
+			}
+			if res.written {
+				return
+			}
 
-		// Прокси обработка запроса
-		proxy.ServeHTTP(w, r)
+			next, err := cands.next()
+			if err == nil && !p.retry.Budget.spend() {
+				p.budgetExhausted.Add(1)
+				err = errBudgetExhausted
+			}
+			if err != nil {
+				log.With("backend", server).Warnf("not retrying: %v", err)
+				writeFailure(w, r, res)
+				return
+			}
+			p.retried.Add(1)
+			log.With("backend", server, "retry", next, "attempt", attempt+1).Warnf("retrying on next backend")
+			if !sleep(r.Context(), policy.backoff(attempt)) {
+				writeFailure(w, r, res)
+				return
+			}
+			server = next
+		}
 	})
 }
+
+// errBudgetExhausted — повтор отклонён бюджетом повторов.
+var errBudgetExhausted = errors.New("retry budget exhausted")
+
+// candidates выдаёт серверы для последовательных попыток: у стратегий-Ranker — по порядку
+// из ранжированного списка, у остальных — выбором сервера, отличного от предыдущего.
+type candidates struct {
+	p      *Proxy
+	r      *http.Request
+	rank   bool     // Запрашивать ранжированный список, если стратегия его поддерживает
+	ranked []string // Ранжированный список, nil — выбор через Pick
+	n      int      // Сколько серверов выдано
+	last   string
+}
+
+// next возвращает сервер для следующей попытки.
+func (c *candidates) next() (string, error) {
+	if c.n == 0 {
+		if rk, ok := c.p.balancer.(balancer.Ranker); ok && c.rank {
+			servers, err := rk.Rank(c.r)
+			if err != nil {
+				return "", err
+			}
+			c.ranked = servers
+		}
+	}
+	var (
+		server string
+		err    error
+	)
+	switch {
+	case c.ranked != nil:
+		if c.n >= len(c.ranked) {
+			return "", balancer.ErrNoAlternateBackend
+		}
+		server = c.ranked[c.n]
+	case c.n == 0:
+		server, err = c.p.picker.Pick(c.r)
+	default:
+		server, err = balancer.PickExcluding(c.p.balancer, c.r, []string{c.last})
+	}
+	if err != nil {
+		return "", err
+	}
+	c.last = server
+	c.n++
+	return server, nil
+}
+
+// more сообщает, могут ли найтись серверы для следующих попыток.
+func (c *candidates) more() bool {
+	return c.ranked == nil || c.n < len(c.ranked)
+}
+
+// attemptResult — итог обращения к бэкенду.
+type attemptResult struct {
+	written bool           // Ответ клиенту записан
+	status  int            // HTTP-статус ответа бэкенда, 0 — ответа не было
+	err     error          // Ошибка транспорта
+	latency time.Duration  // Время до получения заголовков ответа или ошибки
+	resp    *savedResponse // Отброшенный ответ бэкенда, если он сохранён
+}
+
+// savedResponse — заголовки и тело отброшенного ответа бэкенда.
+type savedResponse struct {
+	header http.Header
+	body   []byte
+}
+
+// errDiscarded — ответ бэкенда отброшен (повтор по статусу или проигравший хеджированный запрос).
+var errDiscarded = errors.New("response discarded")
+
+// forward проксирует запрос на server. Ответ с кодом, для которого discard вернул true,
+// и ошибка транспорта, для которой keepErr вернул true, клиенту не пишутся:
+// возвращается written == false, и решение о дальнейшем принимает вызывающий.
+// При replay отброшенный ответ возвращается в attemptResult.resp для writeFailure.
+// Результат сообщается получателям через report.
+func (p *Proxy) forward(
+	w http.ResponseWriter,
+	r *http.Request,
+	server string,
+	attempt int,
+	replay bool,
+	discard func(status int) bool,
+	keepErr func(err error) bool,
+) attemptResult {
+	// Увеличиваем счетчик соединений, если балансировщик поддерживает ConnAware
+	if ca, ok := p.balancer.(balancer.ConnAware); ok {
+		ca.Increase(server)
+		defer ca.Decrease(server)
+	}
+
+	// Журнал запроса (см. logger.NewContext) с адресом бэкенда
+	log := logger.FromContext(r.Context(), p.logger).With("backend", server)
+	be, err := p.backendFor(server)
+	if err != nil {
+		log.Errorf("invalid server URL: %v", err)
+		requestid.Error(w, r, "bad server URL", http.StatusInternalServerError)
+		return attemptResult{written: true, err: err}
+	}
+
+	// Ограничение времени обмена с бэкендом задаётся настройками его пула
+	ctx, cancel := withTimeout(r.Context(), be.timeout)
+	defer cancel()
+
+	// Span запроса к бэкенду; его контекст передаётся бэкенду в traceparent
+	ctx, span := tracing.Start(ctx, "upstream", tracing.KindClient)
+	span.SetAttr("server.address", server)
+	span.SetAttr("retry.attempt", attempt)
+	log.Debugf("proxying request")
+
+	a := &attemptState{
+		res:     attemptResult{written: true},
+		client:  r.Context(),
+		start:   time.Now(),
+		log:     log,
+		discard: discard,
+		replay:  replay,
+		keepErr: keepErr,
+	}
+	be.proxy.ServeHTTP(w, r.WithContext(context.WithValue(ctx, attemptKey{}, a)))
+
+	if a.res.status != 0 {
+		span.SetAttr("http.response.status_code", a.res.status)
+	}
+	span.RecordError(a.res.err)
+	span.End()
+	return a.res
+}
+
+// report сообщает результат обращения стратегии и остальным получателям.
+func (p *Proxy) report(r *http.Request, server string, res attemptResult) {
+	for _, f := range p.feedback {
+		f.Done(server, res.latency, res.status, res.err)
+	}
+	for _, o := range p.observers {
+		o.Observe(r, server, res.latency, res.status, res.err)
+	}
+}
+
+// writeFailure отвечает клиенту после последней неудачной попытки: сохранённым отброшенным
+// ответом бэкенда, статусом отброшенного ответа, если он был, 504 при таймауте бэкенда, иначе 502.
+func writeFailure(w http.ResponseWriter, r *http.Request, res attemptResult) {
+	switch {
+	case res.resp != nil:
+		maps.Copy(w.Header(), res.resp.header)
+		w.WriteHeader(res.status)
+		_, _ = w.Write(res.resp.body)
+	case res.status != 0:
+		requestid.Error(w, r, strings.ToLower(http.StatusText(res.status)), res.status)
+	case isTimeout(res.err):
+		requestid.Error(w, r, "gateway timeout", http.StatusGatewayTimeout)
+	default:
+		requestid.Error(w, r, "bad gateway", http.StatusBadGateway)
+	}
+}
+
+// sleep ждёт d или отмены ctx; false, если контекст отменён.
+func sleep(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// pickErrorMessage возвращает текст ответа клиенту для ошибки выбора сервера.
+func pickErrorMessage(err error) string {
+	switch {
+	case errors.Is(err, balancer.ErrNoHealthyBackends):
+		return "no healthy servers available"
+	case errors.Is(err, balancer.ErrNoBackends):
+		return "no servers available"
+	default:
+		return "service unavailable"
+	}
+}
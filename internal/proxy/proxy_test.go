@@ -1,14 +1,35 @@
 package proxy
 
 import (
+	"context"
 	"github.com/stretchr/testify/require"
 	"io"
+	"maps"
 	"net/http"
 	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/brianvoe/gofakeit/v6"
+	"github.com/coffee-realist/balancer/internal/balancer"
+	"github.com/coffee-realist/balancer/internal/balancer/adapter"
+	"github.com/coffee-realist/balancer/internal/balancer/consistent_hash"
+	"github.com/coffee-realist/balancer/internal/balancer/hashkey"
+	"github.com/coffee-realist/balancer/internal/balancer/least_conn"
+	"github.com/coffee-realist/balancer/internal/balancer/maglev"
+	"github.com/coffee-realist/balancer/internal/balancer/p2c"
+	"github.com/coffee-realist/balancer/internal/balancer/peak_ewma"
+	"github.com/coffee-realist/balancer/internal/balancer/rendezvous"
+	"github.com/coffee-realist/balancer/internal/balancer/round_robin"
+	"github.com/coffee-realist/balancer/internal/balancer/weighted_rr"
 	"github.com/coffee-realist/balancer/internal/logger"
+	"github.com/coffee-realist/balancer/internal/requestid"
+	"github.com/coffee-realist/balancer/internal/tracing"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -19,6 +40,43 @@ func (s *stubBalancer) Increase(string) {}
 func (s *stubBalancer) Decrease(string) {}
 func (s *stubBalancer) Stop()           {}
 
+// stubPicker возвращает фиксированную ошибку выбора.
+type stubPicker struct {
+	stubBalancer
+	err error
+}
+
+func (s *stubPicker) Pick(*http.Request) (string, error) { return "", s.err }
+
+// stubRanker возвращает фиксированный список серверов в порядке предпочтения.
+type stubRanker struct {
+	stubBalancer
+	ranked []string
+}
+
+func (s *stubRanker) Rank(*http.Request) ([]string, error) { return s.ranked, nil }
+
+// outcome — результат обращения к бэкенду, переданный в Done.
+type outcome struct {
+	status int
+	err    error
+}
+
+// stubFeedback запоминает отчёты о завершении запросов.
+type stubFeedback struct {
+	stubBalancer
+	mu       sync.Mutex
+	observed []outcome
+}
+
+func (s *stubFeedback) Done(_ string, latency time.Duration, status int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if latency > 0 {
+		s.observed = append(s.observed, outcome{status: status, err: err})
+	}
+}
+
 func setup(t *testing.T) (handler http.Handler, payload string, backendURL string) {
 	gofakeit.Seed(0)
 	payload = gofakeit.Sentence(10)
@@ -76,7 +134,126 @@ func TestProxyForwardsRequests(t *testing.T) {
 		body, _ := io.ReadAll(rec.Result().Body)
 		assert.Contains(t, string(body), "bad gateway")
 	})
+
+	t.Run("error body carries request id", func(t *testing.T) {
+		var upstreamID string
+		backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			upstreamID = r.Header.Get(requestid.Header)
+		}))
+		t.Cleanup(backend.Close)
+		handler := requestid.Middleware(NewProxy(&stubRanker{ranked: []string{backend.URL}}, logger.New()).Handler())
+
+		req := httptest.NewRequest(http.MethodGet, "http://any/foo", nil)
+		req.Header.Set(requestid.Header, "req-1")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+		assert.Equal(t, "req-1", upstreamID)
+
+		handler = requestid.Middleware(NewProxy(&stubBalancer{server: "http://127.0.0.1:1"}, logger.New()).Handler())
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusBadGateway, rec.Code)
+		assert.JSONEq(t, `{"error":"bad gateway","request_id":"req-1"}`, rec.Body.String())
+	})
+
+	t.Run("pick errors → 503 with reason", func(t *testing.T) {
+		cases := map[string]balancer.Balancer{
+			"no servers available":         &stubBalancer{},
+			"no healthy servers available": &stubPicker{err: balancer.ErrNoHealthyBackends},
+		}
+		for want, b := range cases {
+			handler := NewProxy(b, logger.New()).Handler()
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://any/foo", nil))
+
+			assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+			assert.Contains(t, rec.Body.String(), want)
+		}
+	})
+}
+func TestProxyFallsBackToRankedServers(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "second choice")
+	}))
+	t.Cleanup(backend.Close)
+
+	sb := &stubRanker{
+		stubBalancer: stubBalancer{server: "http://127.0.0.1:1"},
+		ranked:       []string{"http://127.0.0.1:1", backend.URL},
+	}
+	handler := NewProxy(sb, logger.New()).Handler()
+
+	t.Run("request without body goes to next server", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://any/foo", nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "second choice", rec.Body.String())
+	})
+
+	t.Run("request with body is not replayed", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "http://any/foo", strings.NewReader("payload"))
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusBadGateway, rec.Code)
+	})
+
+	t.Run("all ranked servers down → 502", func(t *testing.T) {
+		down := &stubRanker{ranked: []string{"http://127.0.0.1:1", "http://127.0.0.1:2"}}
+		rec := httptest.NewRecorder()
+		NewProxy(down, logger.New()).Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://any/foo", nil))
+		assert.Equal(t, http.StatusBadGateway, rec.Code)
+	})
+}
+
+func TestProxyReportsFeedback(t *testing.T) {
+	_, _, backendURL := setup(t)
+
+	sb := &stubFeedback{stubBalancer: stubBalancer{server: backendURL}}
+	NewProxy(sb, logger.New()).Handler().ServeHTTP(httptest.NewRecorder(),
+		httptest.NewRequest(http.MethodGet, backendURL+"/some/path?x=1&y=2", nil))
+
+	sb.server = "http://127.0.0.1:1"
+	NewProxy(sb, logger.New()).Handler().ServeHTTP(httptest.NewRecorder(),
+		httptest.NewRequest(http.MethodGet, "http://any/foo", nil))
+
+	require.Len(t, sb.observed, 2)
+	assert.Equal(t, outcome{status: http.StatusAccepted}, sb.observed[0])
+	assert.Equal(t, 0, sb.observed[1].status)
+	assert.Error(t, sb.observed[1].err)
+}
+
+// TestProxyClientCancelNotFailure проверяет, что уход клиента не сообщается стратегии
+// как ошибка бэкенда.
+func TestProxyClientCancelNotFailure(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	t.Cleanup(backend.Close)
+
+	sb := &stubFeedback{stubBalancer: stubBalancer{server: backend.URL}}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	NewProxy(sb, logger.Nop()).Handler().ServeHTTP(httptest.NewRecorder(),
+		httptest.NewRequest(http.MethodGet, "http://any/foo", nil).WithContext(ctx))
+
+	cancel()
+	require.Len(t, sb.observed, 1)
+	assert.Equal(t, outcome{}, sb.observed[0])
+}
+
+func TestProxyAddFeedback(t *testing.T) {
+	_, _, backendURL := setup(t)
+
+	sb := &stubFeedback{stubBalancer: stubBalancer{server: backendURL}}
+	extra := &stubFeedback{}
+	p := NewProxy(sb, logger.New())
+	p.AddFeedback(extra)
+	p.Handler().ServeHTTP(httptest.NewRecorder(),
+		httptest.NewRequest(http.MethodGet, backendURL+"/some/path?x=1&y=2", nil))
+
+	assert.Len(t, sb.observed, 1, "strategy receives feedback")
+	assert.Len(t, extra.observed, 1, "extra receiver gets the same outcome")
 }
+
 func BenchmarkProxy(b *testing.B) {
 	handler, _, backendURL := setup(&testing.T{})
 
@@ -86,3 +263,172 @@ func BenchmarkProxy(b *testing.B) {
 		handler.ServeHTTP(rec, req)
 	}
 }
+
+// TestProxyBackendCache проверяет, что обработчик бэкенда создаётся один раз и обновляется
+// при изменении состава и настроек соединений.
+func TestProxyBackendCache(t *testing.T) {
+	_, _, backendURL := setup(t)
+	p := NewProxy(&stubBalancer{server: backendURL}, logger.Nop())
+	require.NoError(t, p.AddBackend(backendURL))
+	assert.Error(t, p.AddBackend("http://bad host"))
+
+	cached, err := p.backendFor(backendURL)
+	require.NoError(t, err)
+	for range 3 {
+		rec := httptest.NewRecorder()
+		p.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://any/some/path?x=1&y=2", nil))
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+	}
+	again, _ := p.backendFor(backendURL)
+	assert.Same(t, cached, again, "handler is reused across requests")
+
+	p.RemoveBackend(backendURL)
+	_, ok := p.backends.Load(backendURL)
+	assert.False(t, ok)
+
+	require.NoError(t, p.AddBackend(backendURL))
+	p.SetUpstreams(TransportConfig{}, nil)
+	_, ok = p.backends.Load(backendURL)
+	assert.False(t, ok, "new transport settings rebuild handlers")
+
+	rec := httptest.NewRecorder()
+	p.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://any/some/path?x=1&y=2", nil))
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	_, ok = p.backends.Load(backendURL)
+	assert.True(t, ok, "handler is created on first request")
+}
+
+// stubTransport отвечает 200 без обращения к сети, чтобы бенчмарк измерял только работу прокси.
+type stubTransport struct{}
+
+func (stubTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode:    http.StatusOK,
+		Header:        http.Header{},
+		Body:          http.NoBody,
+		ContentLength: 0,
+		Request:       req,
+	}, nil
+}
+
+// BenchmarkProxyStrategies измеряет время и аллокации проксирования при высокой конкуренции
+// для всех стратегий балансировки на четырёх бэкендах.
+func BenchmarkProxyStrategies(b *testing.B) {
+	servers := []string{"http://10.0.0.1:8080", "http://10.0.0.2:8080", "http://10.0.0.3:8080", "http://10.0.0.4:8080"}
+	key, err := hashkey.New(hashkey.SourceHeader, "X-User-ID")
+	require.NoError(b, err)
+
+	strategies := map[string]func() balancer.Balancer{
+		"rr":          func() balancer.Balancer { return round_robin.NewRoundRobinBalancer(servers) },
+		"weighted_rr": func() balancer.Balancer { return weighted_rr.NewWeightedRoundRobinBalancer(servers, nil) },
+		"lc":          func() balancer.Balancer { return least_conn.NewLeastConnBalancer(servers) },
+		"p2c":         func() balancer.Balancer { return p2c.NewP2CBalancer(servers) },
+		"peak_ewma":   func() balancer.Balancer { return peak_ewma.NewPeakEWMABalancer(servers, 0) },
+		"chash":       func() balancer.Balancer { return consistent_hash.NewConsistentHashBalancer(servers, key, 0, 0) },
+		"maglev":      func() balancer.Balancer { return maglev.NewMaglevBalancer(servers, key, 0) },
+		"rendezvous":  func() balancer.Balancer { return rendezvous.NewRendezvousBalancer(servers, nil, key) },
+		"adaptive": func() balancer.Balancer {
+			return adapter.NewAdaptiveBalancer(round_robin.NewRoundRobinBalancer(servers),
+				least_conn.NewLeastConnBalancer(servers), p2c.NewP2CBalancer(servers), 10, 100)
+		},
+	}
+	// uncached пересоздаёт обработчик бэкенда на каждый запрос, как до появления кэша
+	for _, name := range slices.Sorted(maps.Keys(strategies)) {
+		for _, mode := range []string{"cached", "uncached"} {
+			b.Run(name+"/"+mode, func(b *testing.B) {
+				bal := strategies[name]()
+				if st, ok := bal.(balancer.Stoppable); ok {
+					b.Cleanup(st.Stop)
+				}
+				p := NewProxy(bal, logger.Nop())
+				p.upstream.transport = stubTransport{}
+				for _, server := range servers {
+					require.NoError(b, p.AddBackend(server))
+				}
+				handler := p.Handler()
+
+				var users atomic.Uint64
+				b.SetParallelism(16)
+				b.ReportAllocs()
+				b.ResetTimer()
+				b.RunParallel(func(pb *testing.PB) {
+					for pb.Next() {
+						if mode == "uncached" {
+							p.backends.Clear()
+						}
+						req := httptest.NewRequest(http.MethodGet, "http://lb/some/path", nil)
+						req.Header.Set("X-User-ID", strconv.FormatUint(users.Add(1)%1000, 10))
+						handler.ServeHTTP(httptest.NewRecorder(), req)
+					}
+				})
+			})
+		}
+	}
+}
+
+// stubRequestBalancer выбирает бэкенд по заголовку X-Backend.
+type stubRequestBalancer struct {
+	stubBalancer
+	byKey map[string]string
+}
+
+func (s *stubRequestBalancer) NextFor(r *http.Request) string {
+	return s.byKey[r.Header.Get("X-Backend")]
+}
+
+func TestProxyUsesRequestAwareBalancer(t *testing.T) {
+	newBackend := func(name string) *httptest.Server {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.WriteString(w, name)
+		}))
+		t.Cleanup(srv.Close)
+		return srv
+	}
+	b1, b2 := newBackend("one"), newBackend("two")
+	sb := &stubRequestBalancer{
+		stubBalancer: stubBalancer{server: b1.URL},
+		byKey:        map[string]string{"one": b1.URL, "two": b2.URL},
+	}
+	handler := NewProxy(sb, logger.New()).Handler()
+
+	for _, key := range []string{"two", "one", "two"} {
+		req := httptest.NewRequest(http.MethodGet, "http://any/foo", nil)
+		req.Header.Set("X-Backend", key)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, key, rec.Body.String())
+	}
+}
+
+// TestProxyPropagatesTrace проверяет span выбора сервера и запроса к бэкенду
+// и передачу контекста трассы бэкенду.
+func TestProxyPropagatesTrace(t *testing.T) {
+	var traceparent, tracestate string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent, tracestate = r.Header.Get("traceparent"), r.Header.Get("tracestate")
+	}))
+	t.Cleanup(backend.Close)
+
+	exp := &tracing.InMemoryExporter{}
+	tracer := tracing.NewTracer(exp)
+	handler := tracer.Middleware(NewProxy(&stubBalancer{server: backend.URL}, logger.New()).Handler())
+	req := httptest.NewRequest(http.MethodGet, "http://any/foo", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	req.Header.Set("tracestate", "vendor=1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	require.NoError(t, tracer.Shutdown(context.Background()))
+
+	spans := exp.Spans()
+	require.Len(t, spans, 3)
+	pick, upstream, server := spans[0], spans[1], spans[2]
+	assert.Equal(t, "balancer.pick", pick.Name)
+	assert.Equal(t, "upstream", upstream.Name)
+	assert.Equal(t, tracing.KindClient, upstream.Kind)
+	assert.Equal(t, server.Context.SpanID, upstream.Parent)
+	assert.Contains(t, upstream.Attrs, tracing.Attr{Key: "http.response.status_code", Value: http.StatusOK})
+
+	// Бэкенд получает контекст span запроса к нему, а не клиента
+	assert.Equal(t, upstream.Context.Traceparent(), traceparent)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", upstream.Context.TraceID.String())
+	assert.Equal(t, "vendor=1", tracestate)
+}
@@ -0,0 +1,226 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultMaxBodyBytes — сколько байт тела запроса буферизуется для повторной отправки по умолчанию.
+const DefaultMaxBodyBytes = 64 << 10
+
+// RetryPolicy описывает, когда и сколько раз запрос повторяется на другом бэкенде.
+type RetryPolicy struct {
+	MaxAttempts    int           // Всего попыток, включая первую; 1 и меньше — без повторов
+	ConnectFailure bool          // Повторять, если бэкенд не принял соединение
+	Timeout        bool          // Повторять при таймауте бэкенда
+	Statuses       []int         // Повторять при этих статусах ответа, например 502 и 503
+	Backoff        time.Duration // Базовая задержка перед повтором, удваивается с каждой попыткой
+	MaxBackoff     time.Duration // Предел задержки; сама задержка выбирается случайно от нуля до предела
+}
+
+// DefaultRetryPolicy — политика без настроек: до трёх попыток, если бэкенд не принял соединение.
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 3, ConnectFailure: true}
+
+// RetryRoute задаёт политику для запросов, путь которых начинается с PathPrefix.
+type RetryRoute struct {
+	PathPrefix string
+	Policy     RetryPolicy
+}
+
+// RetryConfig — настройки повторов прокси.
+type RetryConfig struct {
+	Default      RetryPolicy  // Политика для путей без отдельного маршрута
+	Routes       []RetryRoute // Выбирается маршрут с самым длинным подходящим префиксом
+	MaxBodyBytes int64        // Предел буферизации тела; запросы с телом больше предела не повторяются
+	Budget       *Budget      // Ограничение доли повторов; nil — без ограничения
+}
+
+// policyFor возвращает политику для пути запроса.
+func (c *RetryConfig) policyFor(r *http.Request) RetryPolicy {
+	policy, best := c.Default, -1
+	for _, route := range c.Routes {
+		if strings.HasPrefix(r.URL.Path, route.PathPrefix) && len(route.PathPrefix) > best {
+			policy, best = route.Policy, len(route.PathPrefix)
+		}
+	}
+	return policy
+}
+
+// retryErr сообщает, повторяется ли запрос при ошибке транспорта err.
+func (p RetryPolicy) retryErr(err error) bool {
+	if p.Timeout && isTimeout(err) {
+		return true
+	}
+	return p.ConnectFailure && isConnectFailure(err)
+}
+
+// retryStatus сообщает, повторяется ли запрос при статусе ответа.
+func (p RetryPolicy) retryStatus(status int) bool {
+	return slices.Contains(p.Statuses, status)
+}
+
+// backoff возвращает задержку перед повтором номер n (с 1) с полным разбросом.
+func (p RetryPolicy) backoff(n int) time.Duration {
+	if p.Backoff <= 0 {
+		return 0
+	}
+	d := p.Backoff << min(n-1, 30)
+	if p.MaxBackoff > 0 && (d > p.MaxBackoff || d <= 0) {
+		d = p.MaxBackoff
+	}
+	return rand.N(d + 1)
+}
+
+// isConnectFailure сообщает, что бэкенд не принял соединение: запрос до него не дошёл.
+func isConnectFailure(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// isTimeout сообщает о таймауте соединения или ожидания ответа.
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// idempotent сообщает, можно ли повторить запрос: метод идемпотентен по RFC 9110,
+// либо клиент явно разрешил повтор заголовком Idempotency-Key.
+func idempotent(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return r.Header.Get("Idempotency-Key") != ""
+}
+
+// bufferBody читает тело запроса в память, чтобы его можно было отправить повторно.
+// Если тело больше limit, возвращает false и восстанавливает r.Body для единственной попытки.
+func bufferBody(r *http.Request, limit int64) ([]byte, bool, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, true, nil
+	}
+	if r.ContentLength > limit {
+		return nil, false, nil
+	}
+	buf, err := io.ReadAll(io.LimitReader(r.Body, limit+1))
+	if err != nil {
+		return nil, false, err
+	}
+	if int64(len(buf)) > limit {
+		r.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(buf), r.Body), r.Body}
+		return nil, false, nil
+	}
+	_ = r.Body.Close()
+	return buf, true, nil
+}
+
+// budgetBuckets — на сколько частей делится окно бюджета.
+const budgetBuckets = 10
+
+// Budget ограничивает повторы и хеджированные запросы долей от числа запросов за скользящее окно,
+// чтобы при отказе или замедлении части бэкендов они не умножали нагрузку на остальные.
+type Budget struct {
+	ratio    float64
+	minExtra int
+	bucket   time.Duration
+	now      func() time.Time
+
+	mu    sync.Mutex
+	start time.Time // Начало текущей части окна
+	pos   int
+	reqs  [budgetBuckets]int
+	extra [budgetBuckets]int
+}
+
+// NewBudget создаёт бюджет: за окно window дополнительных запросов не больше ratio от числа запросов,
+// но не меньше minExtra, чтобы при малой нагрузке они оставались возможны.
+func NewBudget(ratio float64, minExtra int, window time.Duration) *Budget {
+	if window <= 0 {
+		window = 10 * time.Second
+	}
+	return &Budget{
+		ratio:    ratio,
+		minExtra: minExtra,
+		bucket:   window / budgetBuckets,
+		now:      time.Now,
+	}
+}
+
+// request учитывает входящий запрос.
+func (b *Budget) request() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.advance()
+	b.reqs[b.pos]++
+}
+
+// available сообщает, остался ли бюджет на дополнительный запрос.
+func (b *Budget) available() bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.advance()
+	return b.hasRoom()
+}
+
+// spend расходует бюджет на дополнительный запрос; false, если бюджет исчерпан.
+func (b *Budget) spend() bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.advance()
+	if !b.hasRoom() {
+		return false
+	}
+	b.extra[b.pos]++
+	return true
+}
+
+// hasRoom сравнивает число дополнительных запросов за окно с допустимым. Вызывается под mu.
+func (b *Budget) hasRoom() bool {
+	var reqs, extra int
+	for i := range budgetBuckets {
+		reqs += b.reqs[i]
+		extra += b.extra[i]
+	}
+	return extra < max(int(b.ratio*float64(reqs)), b.minExtra)
+}
+
+// advance сдвигает окно, обнуляя устаревшие части. Вызывается под mu.
+func (b *Budget) advance() {
+	now := b.now()
+	if b.start.IsZero() {
+		b.start = now
+		return
+	}
+	for n := 0; now.Sub(b.start) >= b.bucket && n < budgetBuckets; n++ {
+		b.pos = (b.pos + 1) % budgetBuckets
+		b.reqs[b.pos], b.extra[b.pos] = 0, 0
+		b.start = b.start.Add(b.bucket)
+	}
+	if now.Sub(b.start) >= b.bucket {
+		b.start = now
+	}
+}
@@ -0,0 +1,246 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coffee-realist/balancer/internal/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubRotation выбирает серверы по кругу.
+type stubRotation struct {
+	stubBalancer
+	mu      sync.Mutex
+	servers []string
+	i       int
+}
+
+func (s *stubRotation) Next() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	srv := s.servers[s.i%len(s.servers)]
+	s.i++
+	return srv
+}
+
+// recordingBackend отвечает статусом status и запоминает тела полученных запросов.
+type recordingBackend struct {
+	*httptest.Server
+	mu     sync.Mutex
+	bodies []string
+}
+
+func newRecordingBackend(t *testing.T, status int) *recordingBackend {
+	b := &recordingBackend{}
+	b.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		b.mu.Lock()
+		b.bodies = append(b.bodies, string(body))
+		b.mu.Unlock()
+		w.WriteHeader(status)
+		_, _ = io.WriteString(w, http.StatusText(status))
+	}))
+	t.Cleanup(b.Close)
+	return b
+}
+
+func (b *recordingBackend) received() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]string(nil), b.bodies...)
+}
+
+// TestProxyRetries проверяет условия повтора, выбор другого бэкенда и повторную отправку тела.
+func TestProxyRetries(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, ConnectFailure: true, Statuses: []int{http.StatusServiceUnavailable}}
+
+	t.Run("retryable status goes to another backend", func(t *testing.T) {
+		bad, good := newRecordingBackend(t, http.StatusServiceUnavailable), newRecordingBackend(t, http.StatusOK)
+		sb := &stubFeedback{}
+		p := NewProxy(&stubRotation{servers: []string{bad.URL, good.URL}}, logger.Nop())
+		p.AddFeedback(sb)
+		p.SetRetry(RetryConfig{Default: policy})
+
+		rec := httptest.NewRecorder()
+		p.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://any/foo", nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "OK", rec.Body.String())
+		assert.Equal(t, []outcome{{status: http.StatusServiceUnavailable}, {status: http.StatusOK}}, sb.observed)
+		retried, _ := p.RetryStats()
+		assert.Equal(t, uint64(1), retried)
+	})
+
+	t.Run("connect failure is retried", func(t *testing.T) {
+		good := newRecordingBackend(t, http.StatusOK)
+		p := NewProxy(&stubRotation{servers: []string{"http://127.0.0.1:1", good.URL}}, logger.Nop())
+		p.SetRetry(RetryConfig{Default: policy})
+
+		rec := httptest.NewRecorder()
+		p.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://any/foo", nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("last response is returned when no other backend", func(t *testing.T) {
+		bad := newRecordingBackend(t, http.StatusServiceUnavailable)
+		p := NewProxy(&stubBalancer{server: bad.URL}, logger.Nop())
+		p.SetRetry(RetryConfig{Default: policy})
+
+		rec := httptest.NewRecorder()
+		p.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://any/foo", nil))
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assert.Len(t, bad.received(), 1, "same backend is not retried")
+	})
+
+	t.Run("discarded response is replayed when retry is not possible", func(t *testing.T) {
+		bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Retry-After", "5")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = io.WriteString(w, `{"error":"maintenance"}`)
+		}))
+		t.Cleanup(bad.Close)
+		p := NewProxy(&stubBalancer{server: bad.URL}, logger.Nop())
+		p.SetRetry(RetryConfig{Default: policy})
+
+		rec := httptest.NewRecorder()
+		p.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://any/foo", nil))
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assert.Equal(t, "5", rec.Header().Get("Retry-After"))
+		assert.Equal(t, `{"error":"maintenance"}`, rec.Body.String())
+	})
+
+	t.Run("response over replay limit is passed without retry", func(t *testing.T) {
+		large := strings.Repeat("x", maxReplayBytes+1)
+		bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = io.WriteString(w, large)
+		}))
+		t.Cleanup(bad.Close)
+		good := newRecordingBackend(t, http.StatusOK)
+		p := NewProxy(&stubRotation{servers: []string{bad.URL, good.URL}}, logger.Nop())
+		p.SetRetry(RetryConfig{Default: policy})
+
+		rec := httptest.NewRecorder()
+		p.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://any/foo", nil))
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assert.Equal(t, large, rec.Body.String())
+		assert.Empty(t, good.received())
+	})
+
+	t.Run("attempts are limited", func(t *testing.T) {
+		a, b := newRecordingBackend(t, http.StatusServiceUnavailable), newRecordingBackend(t, http.StatusServiceUnavailable)
+		p := NewProxy(&stubRotation{servers: []string{a.URL, b.URL}}, logger.Nop())
+		p.SetRetry(RetryConfig{Default: policy})
+
+		rec := httptest.NewRecorder()
+		p.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://any/foo", nil))
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assert.Equal(t, "Service Unavailable", rec.Body.String(), "last attempt passes the backend response")
+		assert.Len(t, a.received(), 2)
+		assert.Len(t, b.received(), 1)
+	})
+
+	t.Run("non-idempotent request needs opt-in and body is replayed", func(t *testing.T) {
+		bad, good := newRecordingBackend(t, http.StatusServiceUnavailable), newRecordingBackend(t, http.StatusOK)
+		p := NewProxy(&stubRotation{servers: []string{bad.URL, good.URL}}, logger.Nop())
+		p.SetRetry(RetryConfig{Default: policy})
+		handler := p.Handler()
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "http://any/orders", strings.NewReader("first")))
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+		p.balancer.(*stubRotation).i = 0
+		req := httptest.NewRequest(http.MethodPost, "http://any/orders", strings.NewReader("second"))
+		req.Header.Set("Idempotency-Key", "order-1")
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, []string{"first", "second"}, bad.received())
+		assert.Equal(t, []string{"second"}, good.received())
+	})
+
+	t.Run("body over limit is not retried", func(t *testing.T) {
+		bad, good := newRecordingBackend(t, http.StatusServiceUnavailable), newRecordingBackend(t, http.StatusOK)
+		p := NewProxy(&stubRotation{servers: []string{bad.URL, good.URL}}, logger.Nop())
+		p.SetRetry(RetryConfig{Default: policy, MaxBodyBytes: 4})
+
+		req := httptest.NewRequest(http.MethodPut, "http://any/blob", io.NopCloser(strings.NewReader("0123456789")))
+		req.ContentLength = -1
+		rec := httptest.NewRecorder()
+		p.Handler().ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assert.Equal(t, []string{"0123456789"}, bad.received(), "streamed body is complete")
+		assert.Empty(t, good.received())
+	})
+
+	t.Run("budget stops retries", func(t *testing.T) {
+		bad, good := newRecordingBackend(t, http.StatusServiceUnavailable), newRecordingBackend(t, http.StatusOK)
+		p := NewProxy(&stubRotation{servers: []string{bad.URL, good.URL}}, logger.Nop())
+		p.SetRetry(RetryConfig{Default: policy, Budget: NewBudget(0, 1, time.Minute)})
+		handler := p.Handler()
+
+		codes := make([]int, 0, 2)
+		for range 2 {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://any/foo", nil))
+			codes = append(codes, rec.Code)
+			p.balancer.(*stubRotation).i = 0
+		}
+		assert.Equal(t, []int{http.StatusOK, http.StatusServiceUnavailable}, codes)
+		retried, _ := p.RetryStats()
+		assert.Equal(t, uint64(1), retried)
+	})
+}
+
+// TestRetryPolicyFor проверяет выбор политики по самому длинному префиксу пути.
+func TestRetryPolicyFor(t *testing.T) {
+	cfg := RetryConfig{
+		Default: RetryPolicy{MaxAttempts: 3},
+		Routes: []RetryRoute{
+			{PathPrefix: "/api/", Policy: RetryPolicy{MaxAttempts: 2}},
+			{PathPrefix: "/api/payments", Policy: RetryPolicy{MaxAttempts: 1}},
+		},
+	}
+	for path, want := range map[string]int{"/": 3, "/api/users": 2, "/api/payments/1": 1} {
+		assert.Equal(t, want, cfg.policyFor(httptest.NewRequest(http.MethodGet, path, nil)).MaxAttempts, path)
+	}
+}
+
+// TestRetryBackoff проверяет рост и предел задержки.
+func TestRetryBackoff(t *testing.T) {
+	p := RetryPolicy{Backoff: 10 * time.Millisecond, MaxBackoff: 25 * time.Millisecond}
+	for range 100 {
+		assert.LessOrEqual(t, p.backoff(1), 10*time.Millisecond)
+		assert.LessOrEqual(t, p.backoff(5), 25*time.Millisecond)
+		assert.LessOrEqual(t, p.backoff(100), 25*time.Millisecond)
+	}
+	assert.Zero(t, RetryPolicy{}.backoff(3))
+}
+
+// TestBudget проверяет долю дополнительных запросов за окно и его сдвиг.
+func TestBudget(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := NewBudget(0.2, 1, 10*time.Second)
+	b.now = func() time.Time { return now }
+
+	for range 10 {
+		b.request()
+	}
+	assert.True(t, b.spend())
+	assert.True(t, b.spend())
+	assert.False(t, b.available(), "20% of 10 requests")
+	assert.False(t, b.spend())
+
+	// Через окно старые запросы и повторы забываются, остаётся минимум
+	now = now.Add(11 * time.Second)
+	require.True(t, b.available())
+	assert.True(t, b.spend())
+	assert.False(t, b.spend())
+}
@@ -0,0 +1,106 @@
+package proxy
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Значения по умолчанию для незаданных полей TransportConfig.
+const (
+	DefaultDialTimeout         = 5 * time.Second
+	DefaultTLSHandshakeTimeout = 5 * time.Second
+	DefaultKeepAlive           = 30 * time.Second
+	DefaultIdleConnTimeout     = 90 * time.Second
+	DefaultMaxIdleConnsPerHost = 64
+)
+
+// TransportConfig — настройки соединений с бэкендами пула. Нулевые длительности и размеры
+// заменяются значениями по умолчанию, кроме ResponseHeaderTimeout, RequestTimeout
+// и MaxConnsPerHost: их нулевое значение означает отсутствие ограничения.
+type TransportConfig struct {
+	DialTimeout           time.Duration // Установка TCP-соединения
+	TLSHandshakeTimeout   time.Duration // TLS-рукопожатие
+	ResponseHeaderTimeout time.Duration // Ожидание заголовков ответа после отправки запроса
+	RequestTimeout        time.Duration // Весь обмен с бэкендом, включая передачу тела ответа
+	KeepAlive             time.Duration // Период TCP keep-alive; отрицательный — keep-alive выключен
+	IdleConnTimeout       time.Duration // Сколько простаивающее соединение остаётся в пуле
+	MaxIdleConnsPerHost   int           // Простаивающих соединений на бэкенд
+	MaxConnsPerHost       int           // Всего соединений на бэкенд
+}
+
+// UpstreamPool — группа бэкендов с общими настройками соединений.
+type UpstreamPool struct {
+	Servers   []string
+	Transport TransportConfig
+}
+
+// upstream — транспорт и ограничение времени запроса к бэкенду.
+type upstream struct {
+	transport http.RoundTripper
+	timeout   time.Duration // RequestTimeout, 0 — без ограничения
+}
+
+// NewTransport создаёт HTTP-транспорт к бэкендам по настройкам cfg.
+func NewTransport(cfg TransportConfig) *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   withDefault(cfg.DialTimeout, DefaultDialTimeout),
+		KeepAlive: withDefault(cfg.KeepAlive, DefaultKeepAlive),
+	}
+	maxIdle := cfg.MaxIdleConnsPerHost
+	if maxIdle <= 0 {
+		maxIdle = DefaultMaxIdleConnsPerHost
+	}
+	return &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		TLSHandshakeTimeout:   withDefault(cfg.TLSHandshakeTimeout, DefaultTLSHandshakeTimeout),
+		ResponseHeaderTimeout: cfg.ResponseHeaderTimeout,
+		IdleConnTimeout:       withDefault(cfg.IdleConnTimeout, DefaultIdleConnTimeout),
+		MaxIdleConnsPerHost:   maxIdle,
+		MaxConnsPerHost:       cfg.MaxConnsPerHost,
+		ExpectContinueTimeout: time.Second,
+	}
+}
+
+// withDefault возвращает d или def, если d не задана.
+func withDefault(d, def time.Duration) time.Duration {
+	if d == 0 {
+		return def
+	}
+	return d
+}
+
+// SetUpstreams задаёт настройки соединений: def — для бэкендов вне пулов, в том числе
+// добавленных во время работы, pools — для перечисленных в них бэкендов.
+// Вызывается до начала обслуживания.
+func (p *Proxy) SetUpstreams(def TransportConfig, pools []UpstreamPool) {
+	p.upstream = upstream{transport: NewTransport(def), timeout: def.RequestTimeout}
+	p.pools = make(map[string]upstream)
+	for _, pool := range pools {
+		u := upstream{transport: NewTransport(pool.Transport), timeout: pool.Transport.RequestTimeout}
+		for _, server := range pool.Servers {
+			p.pools[server] = u
+		}
+	}
+	// Обработчики с прежним транспортом пересоздаются при следующем обращении
+	p.backends.Clear()
+}
+
+// upstreamFor возвращает транспорт и ограничение времени для сервера.
+func (p *Proxy) upstreamFor(server string) upstream {
+	if u, ok := p.pools[server]; ok {
+		return u
+	}
+	return p.upstream
+}
+
+// withTimeout ограничивает контекст запроса к бэкенду временем d, если оно задано.
+func withTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, d)
+}
@@ -0,0 +1,55 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/coffee-realist/balancer/internal/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestProxyUpstreamTimeouts проверяет ограничения времени пулов и ответ 504 при таймауте бэкенда.
+func TestProxyUpstreamTimeouts(t *testing.T) {
+	slow := newSlowBackend(t, 200*time.Millisecond)
+
+	t.Run("default transport waits for slow backend", func(t *testing.T) {
+		p := NewProxy(&stubBalancer{server: slow.URL}, logger.Nop())
+		p.SetUpstreams(TransportConfig{}, nil)
+
+		rec := httptest.NewRecorder()
+		p.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://any/foo", nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "slow", rec.Body.String())
+	})
+
+	for name, tc := range map[string]TransportConfig{
+		"response header timeout": {ResponseHeaderTimeout: 20 * time.Millisecond},
+		"request timeout":         {RequestTimeout: 20 * time.Millisecond},
+	} {
+		t.Run(name+" of pool", func(t *testing.T) {
+			sb := &stubFeedback{stubBalancer: stubBalancer{server: slow.URL}}
+			p := NewProxy(sb, logger.Nop())
+			p.SetRetry(RetryConfig{Default: RetryPolicy{MaxAttempts: 1}})
+			p.SetUpstreams(TransportConfig{}, []UpstreamPool{{Servers: []string{slow.URL}, Transport: tc}})
+
+			rec := httptest.NewRecorder()
+			p.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://any/foo", nil))
+			assert.Equal(t, http.StatusGatewayTimeout, rec.Code)
+			if assert.Len(t, sb.observed, 1) {
+				assert.True(t, isTimeout(sb.observed[0].err), "timeout must reach feedback: %v", sb.observed[0].err)
+			}
+		})
+	}
+}
+
+// TestNewTransport проверяет подстановку значений по умолчанию.
+func TestNewTransport(t *testing.T) {
+	tr := NewTransport(TransportConfig{ResponseHeaderTimeout: time.Second, MaxConnsPerHost: 8})
+	assert.Equal(t, DefaultTLSHandshakeTimeout, tr.TLSHandshakeTimeout)
+	assert.Equal(t, DefaultIdleConnTimeout, tr.IdleConnTimeout)
+	assert.Equal(t, DefaultMaxIdleConnsPerHost, tr.MaxIdleConnsPerHost)
+	assert.Equal(t, time.Second, tr.ResponseHeaderTimeout)
+	assert.Equal(t, 8, tr.MaxConnsPerHost)
+}
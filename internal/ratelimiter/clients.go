@@ -0,0 +1,179 @@
+package ratelimiter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Ошибки управления клиентами.
+var (
+	ErrClientNotFound = errors.New("not found")
+	ErrETagMismatch   = errors.New("etag mismatch")
+	ErrInvalidConfig  = errors.New("capacity, refill_rate and refill_interval must be positive")
+)
+
+// Ограничения размера страницы ListClients.
+const (
+	DefaultListLimit = 100
+	MaxListLimit     = 1000
+)
+
+// ClientInfo — клиент в списке ListClients.
+type ClientInfo struct {
+	ID string `json:"id"`
+	ClientConfig
+}
+
+// ClientPatch — частичное изменение конфигурации клиента; nil-поля не меняются.
+type ClientPatch struct {
+	Capacity       *float64       `json:"capacity"`
+	RefillRate     *float64       `json:"refill_rate"`
+	RefillInterval *time.Duration `json:"refill_interval"`
+}
+
+// apply применяет изменение к конфигурации.
+func (p ClientPatch) apply(cfg *ClientConfig) {
+	if p.Capacity != nil {
+		cfg.Capacity = *p.Capacity
+	}
+	if p.RefillRate != nil {
+		cfg.RefillRate = *p.RefillRate
+	}
+	if p.RefillInterval != nil {
+		cfg.RefillInterval = *p.RefillInterval
+	}
+}
+
+// ETag возвращает сильный ETag конфигурации клиента. Текущее число токенов
+// в него не входит: оно меняется с каждым запросом и не является правкой оператора.
+func (c ClientConfig) ETag() string {
+	c.Tokens = 0
+	data, _ := json.Marshal(c)
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:8]) + `"`
+}
+
+// ETagMatches проверяет значение заголовка If-Match: "*" или список ETag через запятую.
+func ETagMatches(ifMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// validate проверяет, что с конфигурацией можно запустить пополнение токенов.
+func (c ClientConfig) validate() error {
+	if c.Capacity <= 0 || c.RefillRate <= 0 || c.RefillInterval <= 0 {
+		return ErrInvalidConfig
+	}
+	return nil
+}
+
+// ListClients возвращает до limit клиентов с ID, начинающимися с prefix, в порядке ID
+// после cursor, и курсор следующей страницы (пустой на последней странице).
+func (m *DBManager) ListClients(prefix, cursor string, limit int) ([]ClientInfo, string, error) {
+	if limit <= 0 {
+		limit = DefaultListLimit
+	}
+	limit = min(limit, MaxListLimit)
+
+	// Запрашиваем на одну строку больше, чтобы узнать, есть ли следующая страница.
+	// substr вместо LIKE: LIKE в SQLite не различает регистр и требует экранирования.
+	rows, err := m.db.Query(`
+		SELECT id, config, tokens FROM clients
+		WHERE id > ? AND substr(id, 1, length(?)) = ?
+		ORDER BY id
+		LIMIT ?
+	`, cursor, prefix, prefix, limit+1)
+	if err != nil {
+		return nil, "", err
+	}
+	defer func() { _ = rows.Close() }()
+
+	list := make([]ClientInfo, 0, limit)
+	for rows.Next() {
+		var info ClientInfo
+		var cfgJSON string
+		if err := rows.Scan(&info.ID, &cfgJSON, &info.Tokens); err != nil {
+			return nil, "", err
+		}
+		if err := json.Unmarshal([]byte(cfgJSON), &info.ClientConfig); err != nil {
+			return nil, "", err
+		}
+		// В памяти — актуальные конфигурация и баланс, в БД баланс сохраняется периодически
+		if cfg, err := m.GetClient(info.ID); err == nil {
+			info.ClientConfig = cfg
+		}
+		list = append(list, info)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	next := ""
+	if len(list) > limit {
+		list = list[:limit]
+		next = list[limit-1].ID
+	}
+	return list, next, nil
+}
+
+// UpdateClient частично меняет конфигурацию клиента, сохраняя текущий баланс токенов
+// (он лишь ограничивается новой ёмкостью). Непустой ifMatch сверяется с ETag текущей
+// конфигурации, чтобы не перезаписать чужую правку.
+func (m *DBManager) UpdateClient(id string, patch ClientPatch, ifMatch string) (ClientConfig, error) {
+	// Изменения сериализуются отдельным мьютексом: карта клиентов не блокируется
+	// на время записи в БД, и Allow не ждёт её
+	m.updMu.Lock()
+	defer m.updMu.Unlock()
+	m.mu.RLock()
+	tb, ok := m.clients[id]
+	m.mu.RUnlock()
+	if !ok {
+		return ClientConfig{}, ErrClientNotFound
+	}
+
+	tb.mu.Lock()
+	cfg := tb.config
+	tb.mu.Unlock()
+	if ifMatch != "" && !ETagMatches(ifMatch, cfg.ETag()) {
+		return ClientConfig{}, ErrETagMismatch
+	}
+	patch.apply(&cfg)
+	if err := cfg.validate(); err != nil {
+		return ClientConfig{}, err
+	}
+	cfgJSON, err := json.Marshal(cfg)
+	if err != nil {
+		return ClientConfig{}, err
+	}
+
+	// Сначала запись в БД: при ошибке в памяти остаётся прежняя конфигурация,
+	// и ответ API совпадает с тем, что действует
+	tb.mu.Lock()
+	tokens := min(tb.tokens, cfg.Capacity)
+	tb.mu.Unlock()
+	_, err = m.db.Exec(`
+		UPDATE clients
+		SET config = ?, tokens = ?, last_updated = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`, string(cfgJSON), tokens, id)
+	if err != nil {
+		return ClientConfig{}, err
+	}
+
+	tb.mu.Lock()
+	tb.config = cfg
+	tb.tokens = min(tb.tokens, cfg.Capacity)
+	cfg.Tokens = tb.tokens
+	tb.mu.Unlock()
+	tb.ticker.Reset(cfg.RefillInterval)
+	return cfg, nil
+}
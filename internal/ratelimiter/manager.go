@@ -3,8 +3,8 @@ package ratelimiter
 import (
 	"database/sql"
 	"encoding/json"
-	"errors"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3" // драйвер SQLite
@@ -36,8 +36,11 @@ type tokenBucketClient struct {
 type DBManager struct {
 	db      *sql.DB                       // подключение к SQLite
 	mu      sync.RWMutex                  // мьютекс для управления картой клиентов
+	updMu   sync.Mutex                    // сериализует UpdateClient от сверки ETag до записи в БД
 	clients map[string]*tokenBucketClient // карта ID клиента к его структуре
 	stopAll chan struct{}                 // канал остановки всех горутин
+	allowed atomic.Uint64                 // разрешённые запросы
+	denied  atomic.Uint64                 // отклонённые запросы, включая неизвестных клиентов
 }
 
 // NewDBManager открывает SQLite файл и загружает клиентов из БД
@@ -172,24 +175,32 @@ func (m *DBManager) Allow(id string) bool {
 	tb, ok := m.clients[id]
 	m.mu.RUnlock()
 	if !ok {
+		m.denied.Add(1)
 		return false
 	}
 	tb.mu.Lock()
 	defer tb.mu.Unlock()
 	if tb.tokens >= 1 {
 		tb.tokens--
+		m.allowed.Add(1)
 		return true
 	}
+	m.denied.Add(1)
 	return false
 }
 
+// Decisions возвращает число разрешённых и отклонённых запросов с момента запуска
+func (m *DBManager) Decisions() (allowed, denied uint64) {
+	return m.allowed.Load(), m.denied.Load()
+}
+
 // GetClient возвращает конфигурацию клиента и текущее количество токенов
 func (m *DBManager) GetClient(id string) (ClientConfig, error) {
 	m.mu.RLock()
 	tb, ok := m.clients[id]
 	m.mu.RUnlock()
 	if !ok {
-		return ClientConfig{}, errors.New("not found")
+		return ClientConfig{}, ErrClientNotFound
 	}
 	tb.mu.Lock()
 	defer tb.mu.Unlock()
@@ -204,7 +215,7 @@ func (m *DBManager) RemoveClient(id string) error {
 	tb, ok := m.clients[id]
 	m.mu.Unlock()
 	if !ok {
-		return errors.New("not found")
+		return ErrClientNotFound
 	}
 	close(tb.stopCh)
 	tb.ticker.Stop()
@@ -222,13 +233,14 @@ func (m *DBManager) RemoveClient(id string) error {
 	return nil
 }
 
-// runRefill запускает горутину, которая пополняет токены по таймеру
+// runRefill запускает горутину, которая пополняет токены по таймеру.
+// Конфигурация читается на каждом тике: UpdateClient меняет её на месте.
 func (m *DBManager) runRefill(tb *tokenBucketClient) {
-	cfg := tb.config
 	for {
 		select {
 		case <-tb.ticker.C:
 			tb.mu.Lock()
+			cfg := tb.config
 			tb.tokens += cfg.RefillRate * cfg.RefillInterval.Seconds()
 			if tb.tokens > cfg.Capacity {
 				tb.tokens = cfg.Capacity
@@ -2,6 +2,7 @@ package ratelimiter
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -38,3 +39,95 @@ func TestSQLitePersistence(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, cfg.Capacity, got.Capacity)
 }
+
+// newTestManager создаёт DBManager во временном каталоге.
+func newTestManager(t *testing.T) *DBManager {
+	mgr, err := NewDBManager(filepath.Join(t.TempDir(), "clients.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(mgr.Stop)
+	return mgr
+}
+
+// TestListClients проверяет постраничный вывод и фильтр по префиксу.
+func TestListClients(t *testing.T) {
+	mgr := newTestManager(t)
+	cfg := ClientConfig{Capacity: 1, RefillRate: 1, RefillInterval: time.Second}
+	for _, id := range []string{"team-a/1", "team-a/2", "team-a/3", "team-b/1", "Team-a/4"} {
+		assert.NoError(t, mgr.AddClient(id, cfg))
+	}
+
+	page, next, err := mgr.ListClients("team-a/", "", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"team-a/1", "team-a/2"}, clientIDs(page))
+	assert.Equal(t, "team-a/2", next)
+
+	page, next, err = mgr.ListClients("team-a/", next, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"team-a/3"}, clientIDs(page), "prefix is case-sensitive")
+	assert.Empty(t, next)
+
+	page, _, err = mgr.ListClients("", "", 0)
+	assert.NoError(t, err)
+	assert.Len(t, page, 5)
+	assert.Equal(t, cfg.Capacity, page[0].Tokens)
+}
+
+// clientIDs возвращает ID клиентов из списка.
+func clientIDs(list []ClientInfo) []string {
+	ids := make([]string, len(list))
+	for i, c := range list {
+		ids[i] = c.ID
+	}
+	return ids
+}
+
+// TestUpdateClient проверяет частичное изменение с сохранением баланса и сверкой ETag.
+func TestUpdateClient(t *testing.T) {
+	mgr := newTestManager(t)
+	assert.NoError(t, mgr.AddClient("u1", ClientConfig{Capacity: 10, RefillRate: 1, RefillInterval: time.Hour}))
+	for range 4 {
+		mgr.Allow("u1")
+	}
+	before, _ := mgr.GetClient("u1")
+	etag := before.ETag()
+
+	rate := 5.0
+	got, err := mgr.UpdateClient("u1", ClientPatch{RefillRate: &rate}, etag)
+	assert.NoError(t, err)
+	assert.Equal(t, 5.0, got.RefillRate)
+	assert.Equal(t, 10.0, got.Capacity)
+	assert.Equal(t, 6.0, got.Tokens, "balance is kept")
+	assert.NotEqual(t, etag, got.ETag())
+
+	capacity := 3.0
+	_, err = mgr.UpdateClient("u1", ClientPatch{Capacity: &capacity}, etag)
+	assert.ErrorIs(t, err, ErrETagMismatch, "stale etag")
+
+	got, err = mgr.UpdateClient("u1", ClientPatch{Capacity: &capacity}, "*")
+	assert.NoError(t, err)
+	assert.Equal(t, 3.0, got.Tokens, "balance is capped by new capacity")
+
+	zero := time.Duration(0)
+	_, err = mgr.UpdateClient("u1", ClientPatch{RefillInterval: &zero}, "")
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+	_, err = mgr.UpdateClient("missing", ClientPatch{}, "")
+	assert.ErrorIs(t, err, ErrClientNotFound)
+}
+
+// TestUpdateClientDBError проверяет, что при ошибке записи в БД новая конфигурация не вступает в силу.
+func TestUpdateClientDBError(t *testing.T) {
+	mgr := newTestManager(t)
+	assert.NoError(t, mgr.AddClient("u1", ClientConfig{Capacity: 10, RefillRate: 1, RefillInterval: time.Hour}))
+	_, err := mgr.db.Exec(`CREATE TRIGGER fail_update BEFORE UPDATE ON clients BEGIN SELECT RAISE(ABORT, 'disk full'); END`)
+	assert.NoError(t, err)
+
+	capacity := 3.0
+	_, err = mgr.UpdateClient("u1", ClientPatch{Capacity: &capacity}, "")
+	assert.Error(t, err)
+	got, err := mgr.GetClient("u1")
+	assert.NoError(t, err)
+	assert.Equal(t, 10.0, got.Capacity, "in-memory config is unchanged")
+	assert.Equal(t, 10.0, got.Tokens)
+}
@@ -2,6 +2,7 @@ package ratelimiter
 
 import (
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -18,6 +19,8 @@ type TokenBucketLimiter struct {
 	refillInterval time.Duration // Интервал между тиками.
 	buckets        sync.Map      // Хранение токенов для каждого ключа.
 	stopCh         chan struct{} // Канал для остановки горутины пополнения.
+	allowed        atomic.Uint64 // Разрешённые запросы.
+	denied         atomic.Uint64 // Отклонённые запросы.
 }
 
 // bucket представляет собой структуру с токенами и мьютексом для синхронизации.
@@ -51,11 +54,18 @@ func (tb *TokenBucketLimiter) Allow(key string) bool {
 	// Если токенов достаточно, уменьшаем их количество и разрешаем доступ.
 	if b.tokens >= 1 {
 		b.tokens--
+		tb.allowed.Add(1)
 		return true
 	}
+	tb.denied.Add(1)
 	return false
 }
 
+// Decisions возвращает число разрешённых и отклонённых запросов с момента запуска.
+func (tb *TokenBucketLimiter) Decisions() (allowed, denied uint64) {
+	return tb.allowed.Load(), tb.denied.Load()
+}
+
 // startRefill регулярно пополняет токены для всех клиентов.
 func (tb *TokenBucketLimiter) startRefill() {
 	ticker := time.NewTicker(tb.refillInterval)
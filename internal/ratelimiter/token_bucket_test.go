@@ -30,6 +30,11 @@ func TestTokenBucketLimiter_ConsumeInitialTokens(t *testing.T) {
 		// Проверка, что шестой запрос отклоняется, когда бакет пуст.
 		assert.False(t, rl.Allow(client), "bucket should be empty after capacity requests")
 	})
+	t.Run("decisions are counted", func(t *testing.T) {
+		allowed, denied := rl.Decisions()
+		assert.Equal(t, uint64(5), allowed)
+		assert.Equal(t, uint64(1), denied)
+	})
 }
 
 // TestTokenBucketLimiter_RefillLogic тестирует логику пополнения токенов.
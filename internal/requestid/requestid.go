@@ -0,0 +1,115 @@
+// Package requestid присваивает запросам идентификатор X-Request-ID для сопоставления
+// журналов балансировщика, span трассировки и журналов бэкендов.
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/coffee-realist/balancer/internal/tracing"
+)
+
+// Header — заголовок с идентификатором запроса.
+const Header = "X-Request-ID"
+
+// maxLen ограничивает длину принимаемого от клиента идентификатора.
+const maxLen = 128
+
+// New генерирует идентификатор в формате UUIDv7: первые 48 бит — время в миллисекундах,
+// поэтому идентификаторы упорядочены по времени создания.
+func New() string {
+	var b [16]byte
+	if _, err := rand.Read(b[6:]); err != nil {
+		panic("requestid: random source failed: " + err.Error())
+	}
+	var ms [8]byte
+	binary.BigEndian.PutUint64(ms[:], uint64(time.Now().UnixMilli()))
+	copy(b[:6], ms[2:])
+	b[6] = b[6]&0x0f | 0x70 // Версия 7
+	b[8] = b[8]&0x3f | 0x80 // Вариант RFC 9562
+
+	var s [36]byte
+	hex.Encode(s[0:8], b[0:4])
+	s[8] = '-'
+	hex.Encode(s[9:13], b[4:6])
+	s[13] = '-'
+	hex.Encode(s[14:18], b[6:8])
+	s[18] = '-'
+	hex.Encode(s[19:23], b[8:10])
+	s[23] = '-'
+	hex.Encode(s[24:], b[10:])
+	return string(s[:])
+}
+
+// Valid сообщает, можно ли принять идентификатор клиента: непустой, не длиннее 128 символов,
+// только буквы, цифры и символы -_.:+/= — чтобы его можно было без экранирования
+// выводить в журналы и заголовки.
+func Valid(id string) bool {
+	if id == "" || len(id) > maxLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		case c == '-' || c == '_' || c == '.' || c == ':' || c == '+' || c == '/' || c == '=':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+type ctxKey struct{}
+
+// NewContext возвращает контекст с идентификатором запроса.
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+// FromContext возвращает идентификатор запроса или пустую строку.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(ctxKey{}).(string)
+	return id
+}
+
+// Middleware сохраняет корректный X-Request-ID клиента или создаёт новый. Идентификатор
+// записывается в заголовок запроса (и передаётся бэкенду), возвращается в заголовке ответа,
+// кладётся в контекст и добавляется атрибутом request.id к span запроса.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(Header)
+		if !Valid(id) {
+			id = New()
+		}
+		r.Header.Set(Header, id)
+		w.Header().Set(Header, id)
+
+		ctx := NewContext(r.Context(), id)
+		ctx = tracing.ContextWithAttrs(ctx, tracing.Attr{Key: "request.id", Value: id})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// errorBody — тело JSON-ошибки.
+type errorBody struct {
+	Error     string `json:"error"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// Error отвечает JSON-ошибкой {"error": msg, "request_id": ...} с заданным кодом.
+// Аналог http.Error для ответов прокси и управляющего API.
+func Error(w http.ResponseWriter, r *http.Request, msg string, code int) {
+	body, _ := json.Marshal(errorBody{Error: msg, RequestID: FromContext(r.Context())})
+	h := w.Header()
+	h.Del("Content-Length")
+	h.Set("Content-Type", "application/json")
+	h.Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(code)
+	_, _ = w.Write(append(body, '\n'))
+}
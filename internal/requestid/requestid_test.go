@@ -0,0 +1,98 @@
+package requestid
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/coffee-realist/balancer/internal/tracing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestNew проверяет формат UUIDv7 и упорядоченность по времени.
+func TestNew(t *testing.T) {
+	uuidV7 := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	prev := New()
+	assert.Regexp(t, uuidV7, prev)
+	for range 100 {
+		id := New()
+		assert.Regexp(t, uuidV7, id)
+		assert.NotEqual(t, prev, id)
+		assert.LessOrEqual(t, prev[:13], id[:13], "timestamp prefix does not go back")
+		prev = id
+	}
+}
+
+// TestValid проверяет, какие идентификаторы клиента принимаются.
+func TestValid(t *testing.T) {
+	for _, id := range []string{"abc", "0190b1e2-7c4a-7d3e-9f00-0123456789ab", "req_1.2:3+4/5="} {
+		assert.True(t, Valid(id), id)
+	}
+	for _, id := range []string{"", "with space", "quote\"", "new\nline", strings.Repeat("a", 129)} {
+		assert.False(t, Valid(id), id)
+	}
+}
+
+// TestMiddleware проверяет сохранение, генерацию и передачу идентификатора.
+func TestMiddleware(t *testing.T) {
+	var upstream, fromCtx string
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstream, fromCtx = r.Header.Get(Header), FromContext(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(Header, "client-42")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, "client-42", rec.Header().Get(Header))
+	assert.Equal(t, "client-42", upstream)
+	assert.Equal(t, "client-42", fromCtx)
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(Header, "bad id\x7f")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	id := rec.Header().Get(Header)
+	assert.True(t, Valid(id))
+	assert.NotEqual(t, "bad id\x7f", id)
+	assert.Equal(t, id, upstream)
+	assert.Equal(t, id, fromCtx)
+}
+
+// TestMiddlewareSpans проверяет атрибут request.id у span запроса.
+func TestMiddlewareSpans(t *testing.T) {
+	exp := &tracing.InMemoryExporter{}
+	tracer := tracing.NewTracer(exp)
+	handler := Middleware(tracer.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, span := tracing.Start(r.Context(), "child", tracing.KindInternal)
+		span.End()
+	})))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(Header, "client-42")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	require.NoError(t, tracer.Shutdown(context.Background()))
+
+	spans := exp.Spans()
+	require.Len(t, spans, 2)
+	for _, s := range spans {
+		assert.Contains(t, s.Attrs, tracing.Attr{Key: "request.id", Value: "client-42"}, s.Name)
+	}
+}
+
+// TestError проверяет JSON-тело ошибки с идентификатором запроса.
+func TestError(t *testing.T) {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	Error(rec, req.WithContext(NewContext(req.Context(), "client-42")), "bad gateway", http.StatusBadGateway)
+	assert.Equal(t, http.StatusBadGateway, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"error":"bad gateway","request_id":"client-42"}`, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	Error(rec, req, "not found", http.StatusNotFound)
+	assert.JSONEq(t, `{"error":"not found"}`, rec.Body.String(), "no id outside middleware")
+}
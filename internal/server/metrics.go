@@ -0,0 +1,151 @@
+package server
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/coffee-realist/balancer/internal/api"
+	"github.com/coffee-realist/balancer/internal/balancer"
+	"github.com/coffee-realist/balancer/internal/metrics"
+	"github.com/coffee-realist/balancer/internal/proxy"
+	"github.com/coffee-realist/balancer/internal/ratelimiter"
+)
+
+// proxyMetrics считает обращения к бэкендам и их длительность; реализует proxy.Observer.
+type proxyMetrics struct {
+	requests *metrics.CounterVec
+	latency  *metrics.HistogramVec
+}
+
+// Observe учитывает обращение к бэкенду. Ошибка транспорта учитывается со статусом "error".
+func (m *proxyMetrics) Observe(r *http.Request, server string, latency time.Duration, status int, err error) {
+	code := "error"
+	if err == nil && status != 0 {
+		code = strconv.Itoa(status)
+	}
+	method := metricMethod(r.Method)
+	m.requests.With(server, method, code).Inc()
+	m.latency.With(server, method, code).Observe(latency.Seconds())
+}
+
+// metricMethod ограничивает значения метки method стандартными методами,
+// чтобы произвольные методы клиентов не порождали новые серии.
+func metricMethod(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodOptions, http.MethodConnect, http.MethodTrace:
+		return method
+	}
+	return "OTHER"
+}
+
+// modePicker — стратегия, считающая выборы по режимам (adapter.AdaptiveBalancer).
+type modePicker interface {
+	ModePicks() map[string]uint64
+}
+
+// limiterDecisions — лимитер, считающий свои решения.
+type limiterDecisions interface {
+	Decisions() (allowed, denied uint64)
+}
+
+// newMetrics регистрирует метрики прокси, бэкендов, адаптивной стратегии и лимитеров.
+func newMetrics(
+	prox *proxy.Proxy,
+	bal balancer.Balancer,
+	backends api.Backends,
+	globalRL *ratelimiter.TokenBucketLimiter,
+	clientRL *ratelimiter.DBManager,
+) *metrics.Registry {
+	reg := metrics.NewRegistry()
+
+	prox.AddObserver(&proxyMetrics{
+		requests: reg.NewCounterVec("balancer_upstream_requests_total",
+			"Requests proxied to backends.", "backend", "method", "status"),
+		latency: reg.NewHistogramVec("balancer_upstream_request_duration_seconds",
+			"Time until backend response headers or transport error.", metrics.DefaultBuckets,
+			"backend", "method", "status"),
+	})
+
+	reg.NewCounterFunc("balancer_retries_total", "Retries on another backend by outcome.",
+		[]string{"outcome"}, func() []metrics.Sample {
+			retried, exhausted := prox.RetryStats()
+			return []metrics.Sample{
+				{Labels: []string{"budget_exhausted"}, Value: float64(exhausted)},
+				{Labels: []string{"retried"}, Value: float64(retried)},
+			}
+		})
+
+	reg.NewCounterFunc("balancer_hedges_total", "Hedged requests to another backend by outcome.",
+		[]string{"outcome"}, func() []metrics.Sample {
+			sent, won, exhausted := prox.HedgeStats()
+			return []metrics.Sample{
+				{Labels: []string{"budget_exhausted"}, Value: float64(exhausted)},
+				{Labels: []string{"sent"}, Value: float64(sent)},
+				{Labels: []string{"won"}, Value: float64(won)},
+			}
+		})
+
+	if backends.Members != nil {
+		reg.NewGaugeFunc("balancer_backend_in_flight", "Requests in flight per backend.",
+			[]string{"backend"}, func() []metrics.Sample {
+				list := backends.List()
+				samples := make([]metrics.Sample, len(list))
+				for i, b := range list {
+					samples[i] = metrics.Sample{Labels: []string{b.Server}, Value: float64(b.Active)}
+				}
+				return samples
+			})
+	}
+	reg.NewGaugeFunc("balancer_backend_healthy", "Backend health: 1 healthy, 0 unhealthy.",
+		[]string{"backend"}, func() []metrics.Sample {
+			list := backends.List()
+			samples := make([]metrics.Sample, len(list))
+			for i, b := range list {
+				samples[i] = metrics.Sample{Labels: []string{b.Server}, Value: boolValue(b.Healthy)}
+			}
+			return samples
+		})
+
+	if mp, ok := bal.(modePicker); ok {
+		reg.NewCounterFunc("balancer_adaptive_picks_total", "Backends picked by adaptive balancer per mode.",
+			[]string{"mode"}, func() []metrics.Sample {
+				picks := mp.ModePicks()
+				samples := make([]metrics.Sample, 0, len(picks))
+				for mode, n := range picks {
+					samples = append(samples, metrics.Sample{Labels: []string{mode}, Value: float64(n)})
+				}
+				slices.SortFunc(samples, func(a, b metrics.Sample) int { return slices.Compare(a.Labels, b.Labels) })
+				return samples
+			})
+	}
+
+	limiters := []struct {
+		name string
+		l    limiterDecisions
+	}{{"global", globalRL}, {"client", clientRL}}
+	reg.NewCounterFunc("balancer_ratelimit_decisions_total", "Rate limiter decisions per limiter.",
+		[]string{"limiter", "decision"}, func() []metrics.Sample {
+			samples := make([]metrics.Sample, 0, 2*len(limiters))
+			for _, lim := range limiters {
+				allowed, denied := lim.l.Decisions()
+				samples = append(samples,
+					metrics.Sample{Labels: []string{lim.name, "allow"}, Value: float64(allowed)},
+					metrics.Sample{Labels: []string{lim.name, "deny"}, Value: float64(denied)},
+				)
+			}
+			return samples
+		})
+
+	return reg
+}
+
+// boolValue переводит флаг в значение метрики.
+func boolValue(v bool) float64 {
+	if v {
+		return 1
+	}
+	return 0
+}
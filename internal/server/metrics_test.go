@@ -0,0 +1,64 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coffee-realist/balancer/internal/api"
+	"github.com/coffee-realist/balancer/internal/balancer"
+	"github.com/coffee-realist/balancer/internal/balancer/round_robin"
+	"github.com/coffee-realist/balancer/internal/logger"
+	"github.com/coffee-realist/balancer/internal/proxy"
+	"github.com/coffee-realist/balancer/internal/ratelimiter"
+)
+
+// TestMetrics проверяет метрики прокси, бэкендов и лимитеров в выводе /metrics.
+func TestMetrics(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	defer backend.Close()
+
+	bal := round_robin.NewRoundRobinBalancer([]string{backend.URL})
+	prox := proxy.NewProxy(bal, logger.New())
+	globalRL := ratelimiter.NewTokenBucketLimiter(1, 1, time.Hour)
+	defer globalRL.Stop()
+	clientRL, err := ratelimiter.NewDBManager(filepath.Join(t.TempDir(), "clients.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clientRL.Stop()
+
+	reg := newMetrics(prox, bal, api.Backends{Members: bal.(balancer.Membership)}, globalRL, clientRL)
+
+	prox.Handler().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	globalRL.Allow("ip")
+	globalRL.Allow("ip")
+	clientRL.Allow("unknown")
+
+	var b strings.Builder
+	if err := reg.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	for _, want := range []string{
+		`balancer_upstream_requests_total{backend="` + backend.URL + `",method="GET",status="418"} 1`,
+		`balancer_upstream_request_duration_seconds_count{backend="` + backend.URL + `",method="GET",status="418"} 1`,
+		`balancer_backend_in_flight{backend="` + backend.URL + `"} 0`,
+		`balancer_backend_healthy{backend="` + backend.URL + `"} 1`,
+		`balancer_ratelimit_decisions_total{limiter="global",decision="allow"} 1`,
+		`balancer_ratelimit_decisions_total{limiter="global",decision="deny"} 1`,
+		`balancer_ratelimit_decisions_total{limiter="client",decision="deny"} 1`,
+	} {
+		if !strings.Contains(out, want+"\n") {
+			t.Errorf("metrics output lacks %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "balancer_adaptive_picks_total") {
+		t.Error("adaptive metrics are exported only for adaptive balancer")
+	}
+}
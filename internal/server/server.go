@@ -1,30 +1,55 @@
 package server
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/coffee-realist/balancer/internal/balancer"
+	"io"
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
+	"strconv"
+	"sync"
 	"syscall"
 	"time"
 
+	"github.com/coffee-realist/balancer/internal/accesslog"
 	"github.com/coffee-realist/balancer/internal/api"
+	"github.com/coffee-realist/balancer/internal/auth"
 	"github.com/coffee-realist/balancer/internal/balancer/adapter"
+	"github.com/coffee-realist/balancer/internal/balancer/consistent_hash"
+	"github.com/coffee-realist/balancer/internal/balancer/hashkey"
+	"github.com/coffee-realist/balancer/internal/balancer/healthcheck"
 	"github.com/coffee-realist/balancer/internal/balancer/least_conn"
+	"github.com/coffee-realist/balancer/internal/balancer/maglev"
+	"github.com/coffee-realist/balancer/internal/balancer/outlier"
 	"github.com/coffee-realist/balancer/internal/balancer/p2c"
+	"github.com/coffee-realist/balancer/internal/balancer/peak_ewma"
+	"github.com/coffee-realist/balancer/internal/balancer/rendezvous"
 	"github.com/coffee-realist/balancer/internal/balancer/round_robin"
+	"github.com/coffee-realist/balancer/internal/balancer/stats"
+	"github.com/coffee-realist/balancer/internal/balancer/weighted_rr"
 	"github.com/coffee-realist/balancer/internal/config"
+	"github.com/coffee-realist/balancer/internal/forwarded"
 	"github.com/coffee-realist/balancer/internal/logger"
 	"github.com/coffee-realist/balancer/internal/proxy"
 	"github.com/coffee-realist/balancer/internal/ratelimiter"
+	"github.com/coffee-realist/balancer/internal/requestid"
+	"github.com/coffee-realist/balancer/internal/tracing"
 )
 
 // Start инициализирует и запускает HTTP сервер с необходимыми компонентами.
 func Start(cfg *config.Config) error {
-	log := logger.New()
+	log, logLevel, err := logger.NewFromConfig(logger.Config{Level: cfg.Log.Level, Format: cfg.Log.Format})
+	if err != nil {
+		return fmt.Errorf("invalid log config: %w", err)
+	}
 
 	// Настройка RateLimiter с параметрами из конфигурации.
 	rlCfg := cfg.RateLimiter
@@ -56,25 +81,57 @@ func Start(cfg *config.Config) error {
 	}
 	defer dbMgr.Stop()
 
-	// Выбор алгоритма балансировки на основе конфигурации.
-	hcInterval := cfg.HealthCheckInterval
-	if hcInterval <= 0 {
-		hcInterval = 2 * time.Second
+	// Активные проверки здоровья: один цикл проверок, общий для всех стратегий.
+	var (
+		health   []balancer.Health
+		trackers []serverTracker
+		checks   api.HealthChecks
+	)
+	if healthCheckEnabled(cfg) {
+		checker, err := newHealthChecker(cfg)
+		if err != nil {
+			return fmt.Errorf("invalid health check config: %w", err)
+		}
+		defer checker.Stop()
+		health, trackers, checks = append(health, checker), append(trackers, checker), checker
 	}
 
+	// Выбор алгоритма балансировки на основе конфигурации.
 	var bal balancer.Balancer
 	switch cfg.Algorithm {
 	case "rr":
 		bal = round_robin.NewRoundRobinBalancer(cfg.Servers)
+	case "weighted_rr":
+		bal = weighted_rr.NewWeightedRoundRobinBalancer(cfg.Servers, cfg.Weights)
 	case "lc":
 		bal = least_conn.NewLeastConnBalancer(cfg.Servers)
 	case "p2c":
-		bal = p2c.NewP2CBalancer(cfg.Servers, hcInterval)
+		bal = p2c.NewP2CBalancer(cfg.Servers)
+	case "peak_ewma":
+		bal = peak_ewma.NewPeakEWMABalancer(cfg.Servers, cfg.EWMA.Decay)
+	case "chash":
+		key, err := hashkey.New(cfg.Hash.KeySource, cfg.Hash.KeyName)
+		if err != nil {
+			return fmt.Errorf("invalid hash config: %w", err)
+		}
+		bal = consistent_hash.NewConsistentHashBalancer(cfg.Servers, key, cfg.Hash.VirtualNodes, cfg.Hash.LoadFactor)
+	case "maglev":
+		key, err := hashkey.New(cfg.Hash.KeySource, cfg.Hash.KeyName)
+		if err != nil {
+			return fmt.Errorf("invalid hash config: %w", err)
+		}
+		bal = maglev.NewMaglevBalancer(cfg.Servers, key, cfg.Hash.TableSize)
+	case "rendezvous":
+		key, err := hashkey.New(cfg.Hash.KeySource, cfg.Hash.KeyName)
+		if err != nil {
+			return fmt.Errorf("invalid hash config: %w", err)
+		}
+		bal = rendezvous.NewRendezvousBalancer(cfg.Servers, cfg.Weights, key)
 	case "adaptive":
 		// Инициализация адаптивного балансировщика, комбинирующего несколько алгоритмов.
 		rr := round_robin.NewRoundRobinBalancer(cfg.Servers)
 		lc := least_conn.NewLeastConnBalancer(cfg.Servers)
-		p2cb := p2c.NewP2CBalancer(cfg.Servers, hcInterval)
+		p2cb := p2c.NewP2CBalancer(cfg.Servers)
 		low, high := cfg.Adaptive.LowThreshold, cfg.Adaptive.HighThreshold
 		if low < 0 {
 			low = 10
@@ -92,26 +149,174 @@ func Start(cfg *config.Config) error {
 	// Инициализация Proxy с выбранным балансировщиком.
 	prox := proxy.NewProxy(bal, log)
 
-	// Инициализация HTTP роутера и middleware.
-	mux := http.NewServeMux()
-	// Регистрация API с передачей DBManager и обработчика прокси.
-	api.Register(mux, dbMgr, prox.Handler(), log)
-	handler := loggingMiddleware(mux, log)
-	handler = rateLimitMiddleware(handler, globalRL, dbMgr, log)
+	// Соединения с бэкендами: общие настройки и пулы со своими таймаутами.
+	transport, pools, err := newUpstreams(cfg.Upstream)
+	if err != nil {
+		return fmt.Errorf("invalid upstream config: %w", err)
+	}
+	prox.SetUpstreams(transport, pools)
+	// Обработчики бэкендов создаются заранее и обновляются при изменении состава.
+	for _, server := range cfg.Servers {
+		if err := prox.AddBackend(server); err != nil {
+			log.With("backend", server).Warnf("invalid server URL: %v", err)
+		}
+	}
+	trackers = append(trackers, proxyBackends{prox})
 
-	// Запуск HTTP-сервера с поддержкой graceful shutdown.
-	srv := &http.Server{
-		Addr:    cfg.ListenPort,
-		Handler: handler,
+	// Заголовки пересылки для бэкендов; заголовки клиентов вне доверенных прокси отбрасываются.
+	forwarding, trusted, err := newForwarding(cfg.Forwarding)
+	if err != nil {
+		return fmt.Errorf("invalid forwarding config: %w", err)
+	}
+	prox.SetForwarding(forwarding)
+
+	// Повторы неудачных запросов на других бэкендах.
+	retry, err := newRetryConfig(cfg.Retry)
+	if err != nil {
+		return fmt.Errorf("invalid retry config: %w", err)
+	}
+	prox.SetRetry(retry)
+
+	// Дублирование медленных запросов на другой бэкенд.
+	hedge, err := newHedgeConfig(cfg.Hedge)
+	if err != nil {
+		return fmt.Errorf("invalid hedge config: %w", err)
 	}
+	prox.SetHedge(hedge)
 
-	// Запуск сервера в отдельной горутине.
-	go func() {
-		log.Infof("starting HTTP server on %s", cfg.ListenPort)
-		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			log.Errorf("server error: %v", err)
+	// Журнал доступа пишется асинхронно и закрывается после остановки серверов.
+	accessLog, err := newAccessLog(cfg.AccessLog)
+	if err != nil {
+		return fmt.Errorf("invalid access log config: %w", err)
+	}
+	if accessLog != nil {
+		defer func() { _ = accessLog.Close() }()
+		prox.AddObserver(accessLog)
+	}
+
+	// Трассировка: span экспортируются пачками и дописываются после остановки серверов.
+	tracer, err := newTracer(cfg.Tracing)
+	if err != nil {
+		return fmt.Errorf("invalid tracing config: %w", err)
+	}
+	if tracer != nil {
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := tracer.Shutdown(ctx); err != nil {
+				log.Warnf("tracing shutdown: %v", err)
+			}
+		}()
+	}
+
+	// Счётчики запросов и ошибок по бэкендам для admin API.
+	backendStats := stats.NewCollector()
+	prox.AddFeedback(backendStats)
+
+	// Пассивная проверка здоровья по результатам проксируемых запросов.
+	if oc := cfg.Outlier; oc.Enabled {
+		detector := outlier.NewDetector(cfg.Servers, outlier.Config{
+			ConsecutiveErrors:        oc.ConsecutiveErrors,
+			Interval:                 oc.Interval,
+			BaseEjectionTime:         oc.BaseEjectionTime,
+			MaxEjectionPercent:       oc.MaxEjectionPercent,
+			SuccessRateMinHosts:      oc.SuccessRateMinHosts,
+			SuccessRateRequestVolume: oc.SuccessRateRequestVolume,
+			SuccessRateStdevFactor:   oc.SuccessRateStdevFactor,
+		})
+		defer detector.Stop()
+		health = append(health, detector)
+		trackers = append(trackers, detector)
+		prox.AddFeedback(detector)
+	}
+	// Сервер участвует в выборе, только если здоров и по активным, и по пассивным проверкам.
+	allHealthy := balancer.AllHealthy(health...)
+	if ha, ok := bal.(balancer.HealthAware); ok {
+		ha.SetHealth(allHealthy)
+	}
+
+	// Изменение состава бэкендов во время работы, если стратегия это поддерживает.
+	var members balancer.Membership
+	if m, ok := bal.(balancer.Membership); ok {
+		members = &trackedMembership{Membership: m, trackers: trackers, stats: backendStats}
+	}
+
+	// Управляющие обработчики: клиенты, состав и состояние бэкендов.
+	backends := api.Backends{
+		Servers: cfg.Servers,
+		Members: members,
+		Health:  allHealthy,
+		Checks:  checks,
+		Stats:   backendStats,
+	}
+
+	// Управляющий API и метрики на отдельном mux, при необходимости за аутентификацией.
+	adminMux := http.NewServeMux()
+	api.RegisterManagement(adminMux, dbMgr, members, log)
+	api.RegisterAdmin(adminMux, backends, log)
+	api.RegisterLogLevel(adminMux, logLevel, log)
+	adminMux.Handle("/metrics", newMetrics(prox, bal, backends, globalRL, dbMgr))
+	authn, err := newAdminAuth(cfg)
+	if err != nil {
+		return fmt.Errorf("invalid admin auth config: %w", err)
+	}
+	var adminHandler http.Handler = adminMux
+	if authn != nil {
+		adminHandler = auth.Middleware(adminMux, authn, log)
+	}
+
+	// Инициализация HTTP роутера и middleware.
+	mux := http.NewServeMux()
+	mux.Handle("/", prox.Handler())
+	servers := make([]*http.Server, 0, 2)
+	if cfg.AdminListen == "" {
+		// Управление и проксирование на одном адресе.
+		api.Mount(mux, adminHandler)
+		if authn == nil {
+			log.Warnf("management API (/clients, /servers, /admin, /metrics) is served on public listener %s without authentication; set admin_listen or admin_auth", cfg.ListenPort)
 		}
-	}()
+	} else {
+		// Публичный адрес только проксирует, управление — на отдельном адресе
+		// со своей цепочкой middleware: без лимитов, которые рассчитаны на клиентов.
+		tlsCfg, err := newAdminTLS(cfg.AdminTLS)
+		if err != nil {
+			return fmt.Errorf("invalid admin tls config: %w", err)
+		}
+		adminSrv := newHTTPServer(cfg.AdminListen, requestid.Middleware(loggingMiddleware(adminHandler, log)), cfg.ServerTimeouts)
+		adminSrv.TLSConfig = tlsCfg
+		servers = append(servers, adminSrv)
+	}
+	handler := rateLimitMiddleware(mux, globalRL, dbMgr, log)
+	handler = loggingMiddleware(handler, log)
+	if accessLog != nil {
+		handler = accessLog.Middleware(handler)
+	}
+	if tracer != nil {
+		// Серверный span охватывает всю обработку запроса
+		handler = tracer.Middleware(handler)
+	}
+	// Адрес клиента с учётом доверенных прокси нужен журналу доступа и прокси
+	handler = forwarded.Middleware(handler, trusted)
+	// Идентификатор запроса нужен всем middleware: журналам, журналу доступа и span
+	handler = requestid.Middleware(handler)
+	servers = append(servers, newHTTPServer(cfg.ListenPort, handler, cfg.ServerTimeouts))
+
+	// Запуск серверов в отдельных горутинах.
+	for _, srv := range servers {
+		go func() {
+			log.Infof("starting HTTP server on %s", srv.Addr)
+			var err error
+			if srv.TLSConfig != nil {
+				// Сертификат уже загружен в TLSConfig
+				err = srv.ListenAndServeTLS("", "")
+			} else {
+				err = srv.ListenAndServe()
+			}
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Errorf("server error: %v", err)
+			}
+		}()
+	}
 
 	// Ожидание сигнала для graceful shutdown.
 	stop := make(chan os.Signal, 1)
@@ -119,10 +324,514 @@ func Start(cfg *config.Config) error {
 	<-stop
 	log.Infof("shutdown signal received, shutting down...")
 
-	// Ожидание завершения работы сервера.
+	// Ожидание завершения работы серверов.
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
-	return srv.Shutdown(ctx)
+	return shutdown(ctx, servers)
+}
+
+// newRetryConfig переводит настройки повторов в политику прокси, подставляя значения по умолчанию.
+func newRetryConfig(rc config.RetryConfig) (proxy.RetryConfig, error) {
+	def, err := newRetryPolicy(rc.RetryPolicyConfig, proxy.DefaultRetryPolicy)
+	if err != nil {
+		return proxy.RetryConfig{}, err
+	}
+	out := proxy.RetryConfig{Default: def, MaxBodyBytes: rc.MaxBodyBytes}
+	for _, route := range rc.Routes {
+		if route.PathPrefix == "" {
+			return proxy.RetryConfig{}, errors.New("retry route requires path_prefix")
+		}
+		policy, err := newRetryPolicy(route.RetryPolicyConfig, def)
+		if err != nil {
+			return proxy.RetryConfig{}, fmt.Errorf("route %s: %w", route.PathPrefix, err)
+		}
+		out.Routes = append(out.Routes, proxy.RetryRoute{PathPrefix: route.PathPrefix, Policy: policy})
+	}
+
+	b := rc.Budget
+	if b.Ratio >= 0 {
+		if b.Ratio == 0 {
+			b.Ratio = 0.2
+		}
+		if b.MinRetries <= 0 {
+			b.MinRetries = 10
+		}
+		out.Budget = proxy.NewBudget(b.Ratio, b.MinRetries, b.Window)
+	}
+	return out, nil
+}
+
+// newRetryPolicy собирает политику; незаданные поля берутся из base.
+func newRetryPolicy(pc config.RetryPolicyConfig, base proxy.RetryPolicy) (proxy.RetryPolicy, error) {
+	policy := base
+	if pc.MaxAttempts > 0 {
+		policy.MaxAttempts = pc.MaxAttempts
+	}
+	if pc.Backoff > 0 {
+		policy.Backoff = pc.Backoff
+	}
+	if pc.MaxBackoff > 0 {
+		policy.MaxBackoff = pc.MaxBackoff
+	}
+	if pc.On == nil {
+		return policy, nil
+	}
+	policy.ConnectFailure, policy.Timeout, policy.Statuses = false, false, nil
+	for _, cond := range pc.On {
+		switch cond {
+		case "connect-failure":
+			policy.ConnectFailure = true
+		case "timeout":
+			policy.Timeout = true
+		default:
+			code, err := strconv.Atoi(cond)
+			if err != nil || code < 100 || code > 599 {
+				return proxy.RetryPolicy{}, fmt.Errorf("unknown retry condition %q", cond)
+			}
+			policy.Statuses = append(policy.Statuses, code)
+		}
+	}
+	return policy, nil
+}
+
+// Таймауты входящих соединений по умолчанию: медленная передача заголовков (slowloris)
+// и простаивающие keep-alive соединения не удерживают ресурсы бесконечно.
+const (
+	defaultReadHeaderTimeout = 10 * time.Second
+	defaultIdleTimeout       = 120 * time.Second
+)
+
+// newHTTPServer создаёт HTTP-сервер с таймаутами входящих соединений.
+func newHTTPServer(addr string, handler http.Handler, tc config.ServerTimeoutsConfig) *http.Server {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: tc.ReadHeaderTimeout,
+		ReadTimeout:       tc.ReadTimeout,
+		WriteTimeout:      tc.WriteTimeout,
+		IdleTimeout:       tc.IdleTimeout,
+		MaxHeaderBytes:    tc.MaxHeaderBytes,
+	}
+	if srv.ReadHeaderTimeout <= 0 {
+		srv.ReadHeaderTimeout = defaultReadHeaderTimeout
+	}
+	if srv.IdleTimeout <= 0 {
+		srv.IdleTimeout = defaultIdleTimeout
+	}
+	return srv
+}
+
+// newUpstreams переводит настройки соединений с бэкендами в транспорт прокси:
+// незаданные поля пулов берутся из общих настроек.
+func newUpstreams(uc config.UpstreamConfig) (proxy.TransportConfig, []proxy.UpstreamPool, error) {
+	def, err := newTransportConfig(uc.TransportConfig, proxy.TransportConfig{})
+	if err != nil {
+		return proxy.TransportConfig{}, nil, err
+	}
+	seen := make(map[string]string)
+	pools := make([]proxy.UpstreamPool, 0, len(uc.Pools))
+	for i, pc := range uc.Pools {
+		name := pc.Name
+		if name == "" {
+			name = strconv.Itoa(i)
+		}
+		if len(pc.Servers) == 0 {
+			return proxy.TransportConfig{}, nil, fmt.Errorf("pool %s: no servers", name)
+		}
+		for _, server := range pc.Servers {
+			if other, ok := seen[server]; ok {
+				return proxy.TransportConfig{}, nil, fmt.Errorf("pool %s: server %s already in pool %s", name, server, other)
+			}
+			seen[server] = name
+		}
+		tc, err := newTransportConfig(pc.TransportConfig, def)
+		if err != nil {
+			return proxy.TransportConfig{}, nil, fmt.Errorf("pool %s: %w", name, err)
+		}
+		pools = append(pools, proxy.UpstreamPool{Servers: pc.Servers, Transport: tc})
+	}
+	return def, pools, nil
+}
+
+// newTransportConfig собирает настройки транспорта; незаданные поля берутся из base.
+func newTransportConfig(tc config.TransportConfig, base proxy.TransportConfig) (proxy.TransportConfig, error) {
+	if tc.DialTimeout < 0 || tc.TLSHandshakeTimeout < 0 || tc.ResponseHeaderTimeout < 0 ||
+		tc.RequestTimeout < 0 || tc.IdleConnTimeout < 0 {
+		return proxy.TransportConfig{}, errors.New("timeouts must not be negative")
+	}
+	if tc.MaxIdleConnsPerHost < 0 || tc.MaxConnsPerHost < 0 {
+		return proxy.TransportConfig{}, errors.New("connection limits must not be negative")
+	}
+	out := base
+	if tc.DialTimeout > 0 {
+		out.DialTimeout = tc.DialTimeout
+	}
+	if tc.TLSHandshakeTimeout > 0 {
+		out.TLSHandshakeTimeout = tc.TLSHandshakeTimeout
+	}
+	if tc.ResponseHeaderTimeout > 0 {
+		out.ResponseHeaderTimeout = tc.ResponseHeaderTimeout
+	}
+	if tc.RequestTimeout > 0 {
+		out.RequestTimeout = tc.RequestTimeout
+	}
+	if tc.KeepAlive != 0 {
+		out.KeepAlive = tc.KeepAlive
+	}
+	if tc.IdleConnTimeout > 0 {
+		out.IdleConnTimeout = tc.IdleConnTimeout
+	}
+	if tc.MaxIdleConnsPerHost > 0 {
+		out.MaxIdleConnsPerHost = tc.MaxIdleConnsPerHost
+	}
+	if tc.MaxConnsPerHost > 0 {
+		out.MaxConnsPerHost = tc.MaxConnsPerHost
+	}
+	return out, nil
+}
+
+// newForwarding переводит настройки заголовков пересылки в политику прокси
+// и список доверенных прокси.
+func newForwarding(fc config.ForwardingConfig) (proxy.ForwardingConfig, forwarded.TrustedProxies, error) {
+	trusted, err := forwarded.ParseTrustedProxies(fc.TrustedProxies)
+	if err != nil {
+		return proxy.ForwardingConfig{}, nil, err
+	}
+	out := proxy.ForwardingConfig{XRealIP: fc.XRealIP}
+	if out.XForwarded, err = headerMode(fc.XForwarded, proxy.HeaderAppend); err != nil {
+		return proxy.ForwardingConfig{}, nil, fmt.Errorf("x_forwarded: %w", err)
+	}
+	if out.Forwarded, err = headerMode(fc.Forwarded, proxy.HeaderOff); err != nil {
+		return proxy.ForwardingConfig{}, nil, fmt.Errorf("forwarded: %w", err)
+	}
+	return out, trusted, nil
+}
+
+// headerMode разбирает режим заголовка пересылки; пустое значение заменяется на def.
+func headerMode(s string, def proxy.HeaderMode) (proxy.HeaderMode, error) {
+	switch mode := proxy.HeaderMode(s); mode {
+	case "":
+		return def, nil
+	case proxy.HeaderAppend, proxy.HeaderReplace, proxy.HeaderOff:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown mode %q", s)
+	}
+}
+
+// newHedgeConfig переводит настройки хеджирования в политику прокси; без enabled хеджирование выключено.
+func newHedgeConfig(hc config.HedgeConfig) (proxy.HedgeConfig, error) {
+	if !hc.Enabled {
+		return proxy.HedgeConfig{}, nil
+	}
+	def, err := newHedgePolicy(hc.HedgePolicyConfig, proxy.HedgePolicy{})
+	if err != nil {
+		return proxy.HedgeConfig{}, err
+	}
+	out := proxy.HedgeConfig{Default: def}
+	for _, route := range hc.Routes {
+		if route.PathPrefix == "" {
+			return proxy.HedgeConfig{}, errors.New("hedge route requires path_prefix")
+		}
+		policy := proxy.HedgePolicy{}
+		if !route.Disabled {
+			if policy, err = newHedgePolicy(route.HedgePolicyConfig, def); err != nil {
+				return proxy.HedgeConfig{}, fmt.Errorf("route %s: %w", route.PathPrefix, err)
+			}
+		}
+		out.Routes = append(out.Routes, proxy.HedgeRoute{PathPrefix: route.PathPrefix, Policy: policy})
+	}
+
+	b := hc.Budget
+	if b.Ratio >= 0 {
+		if b.Ratio == 0 {
+			b.Ratio = 0.1
+		}
+		if b.MinHedges <= 0 {
+			b.MinHedges = 10
+		}
+		out.Budget = proxy.NewBudget(b.Ratio, b.MinHedges, b.Window)
+	}
+	return out, nil
+}
+
+// newHedgePolicy собирает политику хеджирования; незаданные поля берутся из base.
+func newHedgePolicy(pc config.HedgePolicyConfig, base proxy.HedgePolicy) (proxy.HedgePolicy, error) {
+	if pc.Delay < 0 || pc.MinDelay < 0 {
+		return proxy.HedgePolicy{}, errors.New("hedge delay must not be negative")
+	}
+	if pc.Percentile < 0 || pc.Percentile >= 100 {
+		return proxy.HedgePolicy{}, fmt.Errorf("hedge percentile %v out of range (0, 100)", pc.Percentile)
+	}
+	policy := base
+	if pc.Delay > 0 {
+		policy.Delay = pc.Delay
+	}
+	if pc.Percentile > 0 {
+		policy.Percentile = pc.Percentile
+	}
+	if pc.MinDelay > 0 {
+		policy.MinDelay = pc.MinDelay
+	}
+	return policy, nil
+}
+
+// newTracer создаёт трассировщик с экспортом по OTLP/HTTP; nil, если трассировка выключена.
+func newTracer(tc config.TracingConfig) (*tracing.Tracer, error) {
+	if !tc.Enabled {
+		return nil, nil
+	}
+	exp, err := tracing.NewOTLPExporter(tracing.OTLPConfig{
+		Endpoint:    tc.OTLPEndpoint,
+		ServiceName: tc.ServiceName,
+		Headers:     tc.Headers,
+		Timeout:     tc.Timeout,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tracing.NewTracer(exp), nil
+}
+
+// newAccessLog создаёт журнал доступа по конфигурации; nil, если он выключен.
+func newAccessLog(ac config.AccessLogConfig) (*accesslog.Logger, error) {
+	if !ac.Enabled {
+		return nil, nil
+	}
+	format, err := accesslog.NewFormatter(ac.Format, ac.Template)
+	if err != nil {
+		return nil, err
+	}
+	var out io.WriteCloser
+	switch ac.Output {
+	case "", "stdout":
+		out = accesslog.NewStream(os.Stdout)
+	case "file":
+		if ac.File == "" {
+			return nil, errors.New("access_log.file is required for file output")
+		}
+		out, err = accesslog.NewRotatingFile(ac.File, int64(ac.MaxSizeMB)<<20, ac.RotateInterval, ac.MaxBackups)
+	case "syslog":
+		tag := ac.SyslogTag
+		if tag == "" {
+			tag = "balancer"
+		}
+		out, err = accesslog.NewSyslog(ac.SyslogNetwork, ac.SyslogAddress, tag)
+	default:
+		return nil, fmt.Errorf("unknown access log output %q", ac.Output)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return accesslog.New(format, accesslog.NewWriter(out, ac.QueueSize)), nil
+}
+
+// newAdminAuth собирает способы аутентификации управляющего API из конфигурации.
+// Возвращает nil, если ни один способ не задан.
+func newAdminAuth(cfg *config.Config) (auth.Authenticator, error) {
+	ac := cfg.AdminAuth
+	var chain auth.Chain
+	if ac.TokenFile != "" {
+		tokens, err := auth.LoadTokenFile(ac.TokenFile)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, tokens)
+	}
+	if ac.HMACSecretFile != "" {
+		secret, err := os.ReadFile(ac.HMACSecretFile)
+		if err != nil {
+			return nil, err
+		}
+		secret = bytes.TrimSpace(secret)
+		if len(secret) == 0 {
+			return nil, fmt.Errorf("empty hmac secret in %s", ac.HMACSecretFile)
+		}
+		chain = append(chain, auth.HMACTokens{Secret: secret})
+	}
+	if len(ac.ClientCertRoles) > 0 {
+		if cfg.AdminListen == "" || cfg.AdminTLS.ClientCAFile == "" {
+			return nil, errors.New("client_cert_roles require admin_listen and admin_tls.client_ca_file")
+		}
+		certs := make(auth.ClientCerts, len(ac.ClientCertRoles))
+		for cn, name := range ac.ClientCertRoles {
+			role, err := auth.ParseRole(name)
+			if err != nil {
+				return nil, fmt.Errorf("client cert %q: %w", cn, err)
+			}
+			certs[cn] = role
+		}
+		chain = append(chain, certs)
+	}
+	if len(chain) == 0 {
+		return nil, nil
+	}
+	return chain, nil
+}
+
+// newAdminTLS создаёт TLS-конфигурацию admin_listen; nil, если сертификат не задан.
+// Клиентский сертификат запрашивается, но не обязателен: без него можно войти по токену.
+func newAdminTLS(tc config.AdminTLSConfig) (*tls.Config, error) {
+	if tc.CertFile == "" && tc.KeyFile == "" {
+		if tc.ClientCAFile != "" {
+			return nil, errors.New("client_ca_file requires cert_file and key_file")
+		}
+		return nil, nil
+	}
+	cert, err := tls.LoadX509KeyPair(tc.CertFile, tc.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	tlsCfg := &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	if tc.ClientCAFile != "" {
+		pem, err := os.ReadFile(tc.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in %s", tc.ClientCAFile)
+		}
+		tlsCfg.ClientCAs = pool
+		tlsCfg.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return tlsCfg, nil
+}
+
+// shutdown параллельно завершает серверы и возвращает их ошибки.
+func shutdown(ctx context.Context, servers []*http.Server) error {
+	errs := make([]error, len(servers))
+	var wg sync.WaitGroup
+	for i, srv := range servers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = srv.Shutdown(ctx)
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// serverTracker — компонент со своим списком серверов (health checks, outlier detection),
+// который нужно держать в согласии с составом стратегии.
+type serverTracker interface {
+	Add(server string)
+	Remove(server string)
+}
+
+// proxyBackends обновляет кэш обработчиков бэкендов прокси при изменении состава.
+type proxyBackends struct{ *proxy.Proxy }
+
+// Add создаёт обработчик добавленного сервера; адрес уже проверен API.
+func (b proxyBackends) Add(server string) { _ = b.AddBackend(server) }
+
+// Remove удаляет обработчик сервера.
+func (b proxyBackends) Remove(server string) { b.RemoveBackend(server) }
+
+// trackedMembership дополняет Membership стратегии обновлением списков серверов у trackers.
+type trackedMembership struct {
+	balancer.Membership
+	trackers []serverTracker
+	stats    *stats.Collector // Показатели удалённых серверов сбрасываются; nil — без статистики
+}
+
+// Add добавляет сервер в стратегию и начинает его отслеживать.
+func (m *trackedMembership) Add(server string, weight int) error {
+	if err := m.Membership.Add(server, weight); err != nil {
+		return err
+	}
+	for _, t := range m.trackers {
+		t.Add(server)
+	}
+	return nil
+}
+
+// Remove удаляет сервер из стратегии и прекращает его отслеживать.
+func (m *trackedMembership) Remove(server string) error {
+	if err := m.Membership.Remove(server); err != nil {
+		return err
+	}
+	for _, t := range m.trackers {
+		t.Remove(server)
+	}
+	m.removeStats(server)
+	return nil
+}
+
+// Drain прекращает отслеживать сервер сразу: новые запросы на него не пойдут,
+// поэтому его здоровье больше не влияет на выбор.
+func (m *trackedMembership) Drain(server string) error {
+	if err := m.Membership.Drain(server); err != nil {
+		return err
+	}
+	for _, t := range m.trackers {
+		t.Remove(server)
+	}
+	// Сервер без начатых запросов удаляется сразу
+	if !slices.ContainsFunc(m.Members(), func(mi balancer.MemberInfo) bool { return mi.Server == server }) {
+		m.removeStats(server)
+	}
+	return nil
+}
+
+// removeStats сбрасывает показатели удалённого сервера.
+func (m *trackedMembership) removeStats(server string) {
+	if m.stats != nil {
+		m.stats.Remove(server)
+	}
+}
+
+// SetState меняет состояние сервера. Включение может вернуть в ротацию сервер,
+// выводимый через Drain, поэтому сервер снова отслеживается (Add у trackers идемпотентен).
+func (m *trackedMembership) SetState(server string, state balancer.AdminState) error {
+	if err := m.Membership.SetState(server, state); err != nil {
+		return err
+	}
+	if state == balancer.StateEnabled {
+		for _, t := range m.trackers {
+			t.Add(server)
+		}
+	}
+	return nil
+}
+
+// healthCheckEnabled сообщает, нужны ли активные проверки: по умолчанию они включены
+// для всех стратегий, health_check.enabled: false их отключает.
+func healthCheckEnabled(cfg *config.Config) bool {
+	return cfg.HealthCheck.Enabled == nil || *cfg.HealthCheck.Enabled
+}
+
+// newHealthChecker создаёт активные проверки здоровья по конфигурации.
+// Интервал берётся из health_check.interval, затем из health_check_interval.
+func newHealthChecker(cfg *config.Config) (*healthcheck.Checker, error) {
+	hc := cfg.HealthCheck
+	statuses, err := healthcheck.ParseStatusRanges(hc.ExpectedStatuses)
+	if err != nil {
+		return nil, err
+	}
+	interval := hc.Interval
+	if interval <= 0 {
+		interval = cfg.HealthCheckInterval
+	}
+	return healthcheck.New(cfg.Servers, healthcheck.Config{
+		Type:          hc.Type,
+		ServerTypes:   hc.ServerTypes,
+		GRPCService:   hc.GRPCService,
+		TLSServerName: hc.TLSServerName,
+		TLSSkipVerify: hc.TLSSkipVerify,
+		Path:          hc.Path,
+		Method:        hc.Method,
+		Statuses:      statuses,
+		BodyContains:  hc.BodyContains,
+		BodyRegexp:    hc.BodyRegexp,
+		Host:          hc.Host,
+		Timeout:       hc.Timeout,
+		Interval:      interval,
+		Jitter:        hc.Jitter,
+		Rise:          hc.Rise,
+		Fall:          hc.Fall,
+	})
 }
 
 // rateLimitMiddleware реализует логику ограничения скорости для всех запросов.
@@ -133,30 +842,64 @@ func rateLimitMiddleware(
 	log logger.Logger,
 ) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Применение глобального ограничения по IP-адресу.
-		if !globalRL.Allow(r.RemoteAddr) {
-			log.Errorf("rate limit exceeded: %s", r.RemoteAddr)
-			http.Error(w, `{"code":429,"message":"rate limit exceeded"}`, http.StatusTooManyRequests)
+		// Применение глобального ограничения по IP-адресу клиента; за доверенным прокси
+		// это адрес исходного клиента, а не прокси (см. forwarded.Middleware).
+		log := logger.FromContext(r.Context(), log)
+		_, span := tracing.Start(r.Context(), "ratelimit", tracing.KindInternal)
+		defer span.End()
+		if !globalRL.Allow(forwarded.FromRequest(r).IP) {
+			span.SetAttr("ratelimit.limiter", "global")
+			span.SetAttr("ratelimit.allowed", false)
+			log.With("limiter", "global").Warnf("rate limit exceeded")
+			writeRateLimited(w, r, "rate limit exceeded")
 			return
 		}
 		// Применение per-client ограничения по API-Key.
 		if apiKey := r.Header.Get("X-API-Key"); apiKey != "" {
 			if !clientRL.Allow(apiKey) {
-				log.Errorf("rate limit exceeded: %s", r.RemoteAddr)
-				http.Error(w, `{"code":429,"message":"client rate limit exceeded"}`, http.StatusTooManyRequests)
+				span.SetAttr("ratelimit.limiter", "client")
+				span.SetAttr("ratelimit.allowed", false)
+				log.With("limiter", "client").Warnf("rate limit exceeded")
+				writeRateLimited(w, r, "client rate limit exceeded")
 				return
 			}
 		}
+		span.SetAttr("ratelimit.allowed", true)
+		span.End()
 		next.ServeHTTP(w, r)
 	})
 }
 
-// loggingMiddleware логирует входящие HTTP-запросы и время их обработки.
+// writeRateLimited отвечает 429 в формате {"code":429,"message":"...","request_id":"..."}.
+func writeRateLimited(w http.ResponseWriter, r *http.Request, msg string) {
+	body, _ := json.Marshal(struct {
+		Code      int    `json:"code"`
+		Message   string `json:"message"`
+		RequestID string `json:"request_id,omitempty"`
+	}{http.StatusTooManyRequests, msg, requestid.FromContext(r.Context())})
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusTooManyRequests)
+	_, _ = w.Write(append(body, '\n'))
+}
+
+// loggingMiddleware передаёт обработчикам журнал запроса с его атрибутами
+// (см. logger.FromContext) и пишет одну отладочную запись о завершении запроса.
 func loggingMiddleware(next http.Handler, log logger.Logger) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
-		log.Infof("incoming request: %s %s", r.Method, r.URL.String())
-		next.ServeHTTP(w, r)
-		log.Infof("completed %s %s in %v", r.Method, r.URL.String(), time.Since(start))
+		attrs := []interface{}{"method", r.Method, "path", r.URL.Path, "remote", r.RemoteAddr}
+		if id := requestid.FromContext(r.Context()); id != "" {
+			attrs = append(attrs, "request_id", id)
+		}
+		if apiKey := r.Header.Get("X-API-Key"); apiKey != "" {
+			// Сам ключ — секрет, в журнал попадает только его отпечаток
+			attrs = append(attrs, "client_key", logger.Fingerprint(apiKey))
+		}
+		reqLog := log.With(attrs...)
+
+		rec := &accesslog.ResponseRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r.WithContext(logger.NewContext(r.Context(), reqLog)))
+		reqLog.With("status", rec.Status(), "latency", time.Since(start)).Debugf("request completed")
 	})
 }
@@ -1,8 +1,20 @@
 package server
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/coffee-realist/balancer/internal/balancer"
+	"github.com/coffee-realist/balancer/internal/balancer/stats"
 	"github.com/coffee-realist/balancer/internal/config"
+	"github.com/coffee-realist/balancer/internal/forwarded"
+	"github.com/coffee-realist/balancer/internal/logger"
+	"github.com/coffee-realist/balancer/internal/proxy"
+	"github.com/coffee-realist/balancer/internal/requestid"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 	"time"
 )
@@ -43,3 +55,416 @@ func TestServerStartStop(t *testing.T) {
 		}
 	})
 }
+
+// TestServerStartStopAdminListener проверяет завершение работы по сигналу
+// при управляющем API на отдельном адресе.
+func TestServerStartStopAdminListener(t *testing.T) {
+	cfg := &config.Config{
+		ListenPort:  ":0",
+		AdminListen: ":0",
+		Servers:     []string{},
+	}
+	done := make(chan error, 1)
+
+	go func() {
+		done <- Start(cfg)
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+
+	p, _ := os.FindProcess(os.Getpid())
+	if err := p.Signal(os.Interrupt); err != nil {
+		t.Fatal("failed to send interrupt signal")
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("server shutdown with error: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("server did not shutdown in time")
+	}
+}
+
+// TestNewAdminAuth проверяет сборку аутентификации управляющего API из конфигурации.
+func TestNewAdminAuth(t *testing.T) {
+	authn, err := newAdminAuth(&config.Config{})
+	if err != nil || authn != nil {
+		t.Fatalf("expected no auth without config, got %v, %v", authn, err)
+	}
+
+	// mTLS возможен только на отдельном адресе с проверкой клиентских сертификатов.
+	_, err = newAdminAuth(&config.Config{AdminAuth: config.AdminAuthConfig{
+		ClientCertRoles: map[string]string{"dashboard": "read"},
+	}})
+	if err == nil {
+		t.Fatal("expected error for client_cert_roles without admin_tls")
+	}
+
+	_, err = newAdminAuth(&config.Config{
+		AdminListen: ":0",
+		AdminTLS:    config.AdminTLSConfig{ClientCAFile: "ca.pem"},
+		AdminAuth:   config.AdminAuthConfig{ClientCertRoles: map[string]string{"dashboard": "admin"}},
+	})
+	if err == nil {
+		t.Fatal("expected error for unknown role")
+	}
+}
+
+// TestLoggingMiddleware проверяет журнал запроса в контексте и итоговую запись с атрибутами.
+func TestLoggingMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	log, _, err := logger.NewFromConfig(logger.Config{Level: "debug", Format: logger.FormatJSON, Output: &buf})
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := requestid.Middleware(loggingMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logger.FromContext(r.Context(), logger.Nop()).Warnf("inside handler")
+		w.WriteHeader(http.StatusTeapot)
+	}), log))
+
+	req := httptest.NewRequest(http.MethodGet, "/foo", nil)
+	req.Header.Set("X-API-Key", "client-1")
+	req.Header.Set(requestid.Header, "req-1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 log records, got %d:\n%s", len(lines), buf.String())
+	}
+	var inner, done map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &inner); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &done); err != nil {
+		t.Fatal(err)
+	}
+	if inner["client_key"] != logger.Fingerprint("client-1") || inner["path"] != "/foo" || inner["request_id"] != "req-1" {
+		t.Errorf("handler record lacks request attributes: %v", inner)
+	}
+	if done["status"] != float64(http.StatusTeapot) || done["level"] != "DEBUG" || done["latency"] == nil || done["request_id"] != "req-1" {
+		t.Errorf("unexpected completion record: %v", done)
+	}
+	if strings.Contains(buf.String(), "client-1") {
+		t.Errorf("API key leaked to log:\n%s", buf.String())
+	}
+}
+
+// TestNewAccessLog проверяет выбор вывода журнала доступа и ошибки конфигурации.
+func TestNewAccessLog(t *testing.T) {
+	if l, err := newAccessLog(config.AccessLogConfig{}); err != nil || l != nil {
+		t.Fatalf("expected disabled access log, got %v, %v", l, err)
+	}
+
+	path := t.TempDir() + "/access.log"
+	l, err := newAccessLog(config.AccessLogConfig{Enabled: true, Format: "json", Output: "file", File: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, bad := range []config.AccessLogConfig{
+		{Enabled: true, Output: "file"},
+		{Enabled: true, Output: "kafka"},
+		{Enabled: true, Format: "template"},
+	} {
+		if _, err := newAccessLog(bad); err == nil {
+			t.Errorf("expected error for %+v", bad)
+		}
+	}
+}
+
+// TestNewTracer проверяет включение трассировки и обязательный адрес коллектора.
+func TestNewTracer(t *testing.T) {
+	if tr, err := newTracer(config.TracingConfig{}); err != nil || tr != nil {
+		t.Fatalf("expected disabled tracing, got %v, %v", tr, err)
+	}
+	if _, err := newTracer(config.TracingConfig{Enabled: true}); err == nil {
+		t.Error("expected error without otlp_endpoint")
+	}
+	tr, err := newTracer(config.TracingConfig{Enabled: true, OTLPEndpoint: "http://127.0.0.1:4318"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestNewRetryConfig проверяет значения по умолчанию, наследование в маршрутах и разбор условий.
+func TestNewRetryConfig(t *testing.T) {
+	rc, err := newRetryConfig(config.RetryConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rc.Default.MaxAttempts != 3 || !rc.Default.ConnectFailure || rc.Budget == nil {
+		t.Errorf("unexpected defaults: %+v", rc)
+	}
+
+	rc, err = newRetryConfig(config.RetryConfig{
+		RetryPolicyConfig: config.RetryPolicyConfig{MaxAttempts: 4, On: []string{"timeout", "503"}, Backoff: time.Millisecond},
+		Budget:            config.RetryBudgetConfig{Ratio: -1},
+		Routes: []config.RetryRouteConfig{
+			{PathPrefix: "/payments", RetryPolicyConfig: config.RetryPolicyConfig{MaxAttempts: 1}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	def, route := rc.Default, rc.Routes[0].Policy
+	if def.ConnectFailure || !def.Timeout || len(def.Statuses) != 1 || def.Statuses[0] != 503 || rc.Budget != nil {
+		t.Errorf("unexpected policy: %+v", rc)
+	}
+	if route.MaxAttempts != 1 || !route.Timeout || route.Backoff != time.Millisecond {
+		t.Errorf("route does not inherit unset fields: %+v", route)
+	}
+
+	for _, bad := range []config.RetryConfig{
+		{RetryPolicyConfig: config.RetryPolicyConfig{On: []string{"reset"}}},
+		{RetryPolicyConfig: config.RetryPolicyConfig{On: []string{"700"}}},
+		{Routes: []config.RetryRouteConfig{{}}},
+	} {
+		if _, err := newRetryConfig(bad); err == nil {
+			t.Errorf("expected error for %+v", bad)
+		}
+	}
+}
+
+// TestNewHedgeConfig проверяет выключенное хеджирование, наследование в маршрутах и проверку значений.
+func TestNewHedgeConfig(t *testing.T) {
+	hc, err := newHedgeConfig(config.HedgeConfig{HedgePolicyConfig: config.HedgePolicyConfig{Delay: time.Second}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hc.Default.Delay != 0 || hc.Budget != nil {
+		t.Errorf("hedging must stay off without enabled: %+v", hc)
+	}
+
+	hc, err = newHedgeConfig(config.HedgeConfig{
+		Enabled:           true,
+		HedgePolicyConfig: config.HedgePolicyConfig{Delay: 50 * time.Millisecond},
+		Routes: []config.HedgeRouteConfig{
+			{PathPrefix: "/replica", HedgePolicyConfig: config.HedgePolicyConfig{Percentile: 95, MinDelay: time.Millisecond}},
+			{PathPrefix: "/stream", Disabled: true},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hc.Default.Delay != 50*time.Millisecond || hc.Budget == nil {
+		t.Errorf("unexpected defaults: %+v", hc)
+	}
+	replica, stream := hc.Routes[0].Policy, hc.Routes[1].Policy
+	if replica.Percentile != 95 || replica.Delay != 50*time.Millisecond || replica.MinDelay != time.Millisecond {
+		t.Errorf("route does not inherit unset fields: %+v", replica)
+	}
+	if stream != (proxy.HedgePolicy{}) {
+		t.Errorf("disabled route must have empty policy: %+v", stream)
+	}
+
+	for _, bad := range []config.HedgeConfig{
+		{Enabled: true, HedgePolicyConfig: config.HedgePolicyConfig{Percentile: 100}},
+		{Enabled: true, HedgePolicyConfig: config.HedgePolicyConfig{Delay: -time.Second}},
+		{Enabled: true, Routes: []config.HedgeRouteConfig{{}}},
+	} {
+		if _, err := newHedgeConfig(bad); err == nil {
+			t.Errorf("expected error for %+v", bad)
+		}
+	}
+}
+
+// TestNewUpstreams проверяет наследование настроек пулами и проверку значений.
+func TestNewUpstreams(t *testing.T) {
+	def, pools, err := newUpstreams(config.UpstreamConfig{
+		TransportConfig: config.TransportConfig{DialTimeout: time.Second, ResponseHeaderTimeout: 5 * time.Second},
+		Pools: []config.UpstreamPoolConfig{{
+			Name:            "replicas",
+			Servers:         []string{"http://r1", "http://r2"},
+			TransportConfig: config.TransportConfig{ResponseHeaderTimeout: 500 * time.Millisecond, MaxConnsPerHost: 10},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if def.DialTimeout != time.Second || def.ResponseHeaderTimeout != 5*time.Second {
+		t.Errorf("unexpected default transport: %+v", def)
+	}
+	if len(pools) != 1 || len(pools[0].Servers) != 2 {
+		t.Fatalf("unexpected pools: %+v", pools)
+	}
+	pool := pools[0].Transport
+	if pool.DialTimeout != time.Second || pool.ResponseHeaderTimeout != 500*time.Millisecond || pool.MaxConnsPerHost != 10 {
+		t.Errorf("pool does not inherit unset fields: %+v", pool)
+	}
+
+	for _, bad := range []config.UpstreamConfig{
+		{TransportConfig: config.TransportConfig{DialTimeout: -time.Second}},
+		{Pools: []config.UpstreamPoolConfig{{Name: "empty"}}},
+		{Pools: []config.UpstreamPoolConfig{{Servers: []string{"http://a"}}, {Servers: []string{"http://a"}}}},
+	} {
+		if _, _, err := newUpstreams(bad); err == nil {
+			t.Errorf("expected error for %+v", bad)
+		}
+	}
+}
+
+// TestNewHTTPServer проверяет таймауты входящих соединений по умолчанию и из настроек.
+func TestNewHTTPServer(t *testing.T) {
+	srv := newHTTPServer(":0", http.NotFoundHandler(), config.ServerTimeoutsConfig{})
+	if srv.ReadHeaderTimeout != defaultReadHeaderTimeout || srv.IdleTimeout != defaultIdleTimeout {
+		t.Errorf("unexpected defaults: read header %v, idle %v", srv.ReadHeaderTimeout, srv.IdleTimeout)
+	}
+	srv = newHTTPServer(":0", http.NotFoundHandler(), config.ServerTimeoutsConfig{
+		ReadHeaderTimeout: time.Second, WriteTimeout: time.Minute,
+	})
+	if srv.ReadHeaderTimeout != time.Second || srv.WriteTimeout != time.Minute {
+		t.Errorf("timeouts not applied: %+v", srv)
+	}
+}
+
+// TestNewForwarding проверяет режимы по умолчанию и разбор доверенных прокси.
+func TestNewForwarding(t *testing.T) {
+	fc, trusted, err := newForwarding(config.ForwardingConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fc != proxy.DefaultForwarding || len(trusted) != 0 {
+		t.Errorf("unexpected defaults: %+v, %v", fc, trusted)
+	}
+
+	fc, trusted, err = newForwarding(config.ForwardingConfig{
+		XForwarded:     "replace",
+		Forwarded:      "append",
+		XRealIP:        true,
+		TrustedProxies: []string{"10.0.0.0/8", "127.0.0.1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := proxy.ForwardingConfig{XForwarded: proxy.HeaderReplace, Forwarded: proxy.HeaderAppend, XRealIP: true}
+	if fc != want || len(trusted) != 2 {
+		t.Errorf("unexpected config: %+v, %v", fc, trusted)
+	}
+
+	for _, bad := range []config.ForwardingConfig{
+		{XForwarded: "prepend"},
+		{Forwarded: "on"},
+		{TrustedProxies: []string{"10.0.0.0/40"}},
+	} {
+		if _, _, err := newForwarding(bad); err == nil {
+			t.Errorf("expected error for %+v", bad)
+		}
+	}
+}
+
+// trackerSet — список отслеживаемых серверов для тестов trackedMembership.
+type trackerSet map[string]bool
+
+func (s trackerSet) Add(server string)    { s[server] = true }
+func (s trackerSet) Remove(server string) { delete(s, server) }
+
+// TestTrackedMembership проверяет, что сервер, возвращённый в ротацию после Drain,
+// снова отслеживается.
+func TestTrackedMembership(t *testing.T) {
+	pool := balancer.NewPool([]string{"http://a"})
+	tracked := trackerSet{"http://a": true}
+	m := &trackedMembership{Membership: pool, trackers: []serverTracker{tracked}}
+
+	pool.Increase("http://a")
+	if err := m.Drain("http://a"); err != nil {
+		t.Fatal(err)
+	}
+	if tracked["http://a"] {
+		t.Error("drained server is still tracked")
+	}
+	if err := m.SetState("http://a", balancer.StateEnabled); err != nil {
+		t.Fatal(err)
+	}
+	pool.Decrease("http://a")
+	if _, ok := pool.Get("http://a"); !ok || !tracked["http://a"] {
+		t.Errorf("enabled server: in pool %v, tracked %v", ok, tracked["http://a"])
+	}
+
+	if err := m.SetState("http://a", balancer.StateDisabled); err != nil || !tracked["http://a"] {
+		t.Errorf("disable: err %v, tracked %v", err, tracked["http://a"])
+	}
+}
+
+// TestTrackedMembershipStats проверяет, что показатели удалённого сервера сбрасываются.
+func TestTrackedMembershipStats(t *testing.T) {
+	pool := balancer.NewPool([]string{"http://a", "http://b"})
+	collector := stats.NewCollector()
+	m := &trackedMembership{Membership: pool, stats: collector}
+	collector.Done("http://a", time.Millisecond, http.StatusOK, nil)
+	collector.Done("http://b", time.Millisecond, http.StatusOK, nil)
+
+	if err := m.Remove("http://a"); err != nil {
+		t.Fatal(err)
+	}
+	if got := collector.Get("http://a").Requests; got != 0 {
+		t.Errorf("removed server requests = %d, want 0", got)
+	}
+	// Выводимый сервер без начатых запросов удаляется сразу
+	if err := m.Drain("http://b"); err != nil {
+		t.Fatal(err)
+	}
+	if got := collector.Get("http://b").Requests; got != 0 {
+		t.Errorf("drained server requests = %d, want 0", got)
+	}
+}
+
+// keyRecorder — ограничитель, запоминающий ключи и пропускающий все запросы.
+type keyRecorder struct{ keys []string }
+
+func (k *keyRecorder) Allow(key string) bool { k.keys = append(k.keys, key); return true }
+func (k *keyRecorder) Stop()                 {}
+
+// TestRateLimitMiddlewareKey проверяет, что за доверенным прокси глобальное ограничение
+// считается по адресу исходного клиента.
+func TestRateLimitMiddlewareKey(t *testing.T) {
+	trusted, err := forwarded.ParseTrustedProxies([]string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	global := &keyRecorder{}
+	h := forwarded.Middleware(rateLimitMiddleware(http.NotFoundHandler(), global, &keyRecorder{}, logger.Nop()), trusted)
+
+	for _, remote := range []string{"10.0.0.1:5000", "10.0.0.1:5001", "203.0.113.7:5000"} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = remote
+		r.Header.Set("X-Forwarded-For", "198.51.100.4")
+		h.ServeHTTP(httptest.NewRecorder(), r)
+	}
+	want := []string{"198.51.100.4", "198.51.100.4", "203.0.113.7"}
+	if strings.Join(global.keys, " ") != strings.Join(want, " ") {
+		t.Errorf("keys = %v, want %v", global.keys, want)
+	}
+}
+
+// TestHealthCheckEnabled проверяет, что активные проверки по умолчанию включены
+// для всех стратегий и отключаются явно.
+func TestHealthCheckEnabled(t *testing.T) {
+	on, off := true, false
+	for _, c := range []struct {
+		algorithm string
+		enabled   *bool
+		want      bool
+	}{
+		{"p2c", nil, true},
+		{"adaptive", nil, true},
+		{"rr", nil, true},
+		{"lc", nil, true},
+		{"", nil, true},
+		{"rr", &on, true},
+		{"rr", &off, false},
+		{"maglev", &off, false},
+	} {
+		cfg := &config.Config{Algorithm: c.algorithm, HealthCheck: config.HealthCheckConfig{Enabled: c.enabled}}
+		if got := healthCheckEnabled(cfg); got != c.want {
+			t.Errorf("%q (enabled set: %v): got %v, want %v", c.algorithm, c.enabled != nil, got, c.want)
+		}
+	}
+}
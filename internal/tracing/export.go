@@ -0,0 +1,144 @@
+package tracing
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Exporter отправляет завершённые span во внешнюю систему.
+type Exporter interface {
+	Export(ctx context.Context, spans []SpanData) error
+}
+
+// InMemoryExporter хранит экспортированные span в памяти; используется в тестах.
+type InMemoryExporter struct {
+	mu    sync.Mutex
+	spans []SpanData
+}
+
+// Export добавляет span к сохранённым.
+func (e *InMemoryExporter) Export(_ context.Context, spans []SpanData) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+// Spans возвращает копию сохранённых span в порядке экспорта.
+func (e *InMemoryExporter) Spans() []SpanData {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]SpanData(nil), e.spans...)
+}
+
+// Reset удаляет сохранённые span.
+func (e *InMemoryExporter) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = nil
+}
+
+// Параметры пакетного экспорта.
+const (
+	queueSize     = 2048
+	maxBatchSize  = 512
+	batchInterval = time.Second
+	exportTimeout = 10 * time.Second
+)
+
+// batcher копит span в очереди и экспортирует их пачками из отдельной горутины,
+// чтобы запрос не ждал экспорта. При переполнении очереди span отбрасываются.
+type batcher struct {
+	exp     Exporter
+	queue   chan SpanData
+	dropped atomic.Uint64
+	errs    atomic.Uint64
+	mu      sync.RWMutex // Защищает закрытие очереди от параллельной записи
+	closed  bool
+	done    chan struct{}
+}
+
+// newBatcher создаёт batcher и запускает горутину экспорта.
+func newBatcher(exp Exporter) *batcher {
+	b := &batcher{exp: exp, queue: make(chan SpanData, queueSize), done: make(chan struct{})}
+	go b.run()
+	return b
+}
+
+// add ставит span в очередь без ожидания.
+func (b *batcher) add(d SpanData) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.closed {
+		b.dropped.Add(1)
+		return
+	}
+	select {
+	case b.queue <- d:
+	default:
+		b.dropped.Add(1)
+	}
+}
+
+// run собирает пачки по размеру или по таймеру и дописывает очередь после закрытия.
+func (b *batcher) run() {
+	defer close(b.done)
+	ticker := time.NewTicker(batchInterval)
+	defer ticker.Stop()
+
+	batch := make([]SpanData, 0, maxBatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+		if err := b.exp.Export(ctx, batch); err != nil {
+			b.errs.Add(uint64(len(batch)))
+		}
+		cancel()
+		batch = make([]SpanData, 0, maxBatchSize)
+	}
+	for {
+		select {
+		case d, ok := <-b.queue:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, d)
+			if len(batch) >= maxBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// shutdown закрывает очередь и ждёт экспорта оставшихся span не дольше ctx.
+func (b *batcher) shutdown(ctx context.Context) error {
+	b.mu.Lock()
+	if !b.closed {
+		b.closed = true
+		close(b.queue)
+	}
+	b.mu.Unlock()
+	select {
+	case <-b.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Dropped возвращает число span, отброшенных из-за переполнения очереди.
+func (t *Tracer) Dropped() uint64 {
+	return t.batch.dropped.Load()
+}
+
+// ExportErrors возвращает число span, которые не удалось экспортировать.
+func (t *Tracer) ExportErrors() uint64 {
+	return t.batch.errs.Load()
+}
@@ -0,0 +1,180 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OTLPConfig — параметры экспорта по OTLP/HTTP.
+type OTLPConfig struct {
+	Endpoint    string            // Адрес коллектора, например http://collector:4318; путь /v1/traces добавляется, если не указан
+	ServiceName string            // Атрибут ресурса service.name
+	Headers     map[string]string // Дополнительные заголовки, например для авторизации
+	Timeout     time.Duration     // Таймаут одного запроса экспорта
+}
+
+// otlpExporter отправляет span коллектору в JSON-кодировке OTLP/HTTP.
+type otlpExporter struct {
+	url     string
+	service string
+	headers map[string]string
+	client  *http.Client
+}
+
+// NewOTLPExporter создаёт экспортёр OTLP/HTTP.
+func NewOTLPExporter(cfg OTLPConfig) (Exporter, error) {
+	if cfg.Endpoint == "" {
+		return nil, fmt.Errorf("otlp endpoint is required")
+	}
+	url := strings.TrimRight(cfg.Endpoint, "/")
+	if !strings.HasSuffix(url, "/v1/traces") {
+		url += "/v1/traces"
+	}
+	if cfg.ServiceName == "" {
+		cfg.ServiceName = "balancer"
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = exportTimeout
+	}
+	return &otlpExporter{
+		url:     url,
+		service: cfg.ServiceName,
+		headers: cfg.Headers,
+		client:  &http.Client{Timeout: cfg.Timeout},
+	}, nil
+}
+
+// Export отправляет пачку span одним запросом.
+func (e *otlpExporter) Export(ctx context.Context, spans []SpanData) error {
+	body, err := json.Marshal(e.encode(spans))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("otlp export: unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Структуры JSON-кодировки ExportTraceServiceRequest.
+type (
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpKeyValue `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpSpan struct {
+		TraceID           string         `json:"traceId"`
+		SpanID            string         `json:"spanId"`
+		ParentSpanID      string         `json:"parentSpanId,omitempty"`
+		TraceState        string         `json:"traceState,omitempty"`
+		Name              string         `json:"name"`
+		Kind              SpanKind       `json:"kind"`
+		StartTimeUnixNano string         `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+		Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+		Status            otlpStatus     `json:"status"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code,omitempty"` // 2 — STATUS_CODE_ERROR
+		Message string `json:"message,omitempty"`
+	}
+	otlpKeyValue struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+	otlpValue struct {
+		StringValue *string  `json:"stringValue,omitempty"`
+		IntValue    *string  `json:"intValue,omitempty"` // int64 кодируется строкой
+		DoubleValue *float64 `json:"doubleValue,omitempty"`
+		BoolValue   *bool    `json:"boolValue,omitempty"`
+	}
+)
+
+// encode переводит span в формат OTLP.
+func (e *otlpExporter) encode(spans []SpanData) otlpRequest {
+	out := make([]otlpSpan, len(spans))
+	for i, d := range spans {
+		s := otlpSpan{
+			TraceID:           d.Context.TraceID.String(),
+			SpanID:            d.Context.SpanID.String(),
+			TraceState:        d.Context.TraceState,
+			Name:              d.Name,
+			Kind:              d.Kind,
+			StartTimeUnixNano: strconv.FormatInt(d.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(d.End.UnixNano(), 10),
+			Attributes:        encodeAttrs(d.Attrs),
+		}
+		if d.Parent.IsValid() {
+			s.ParentSpanID = d.Parent.String()
+		}
+		if d.Err != "" {
+			s.Status = otlpStatus{Code: 2, Message: d.Err}
+		}
+		out[i] = s
+	}
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: encodeAttrs([]Attr{{Key: "service.name", Value: e.service}})},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: "github.com/coffee-realist/balancer"}, Spans: out}},
+	}}}
+}
+
+// encodeAttrs кодирует атрибуты; значения неизвестных типов передаются строкой.
+func encodeAttrs(attrs []Attr) []otlpKeyValue {
+	out := make([]otlpKeyValue, len(attrs))
+	for i, a := range attrs {
+		var v otlpValue
+		switch x := a.Value.(type) {
+		case string:
+			v.StringValue = &x
+		case bool:
+			v.BoolValue = &x
+		case int:
+			s := strconv.Itoa(x)
+			v.IntValue = &s
+		case int64:
+			s := strconv.FormatInt(x, 10)
+			v.IntValue = &s
+		case float64:
+			v.DoubleValue = &x
+		default:
+			s := fmt.Sprint(x)
+			v.StringValue = &s
+		}
+		out[i] = otlpKeyValue{Key: a.Key, Value: v}
+	}
+	return out
+}
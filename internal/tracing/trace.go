@@ -0,0 +1,245 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// TraceID — идентификатор трассы W3C Trace Context.
+type TraceID [16]byte
+
+// SpanID — идентификатор span.
+type SpanID [8]byte
+
+// String возвращает ID в шестнадцатеричном виде.
+func (id TraceID) String() string { return hex.EncodeToString(id[:]) }
+
+// String возвращает ID в шестнадцатеричном виде.
+func (id SpanID) String() string { return hex.EncodeToString(id[:]) }
+
+// IsValid сообщает, что ID не нулевой.
+func (id TraceID) IsValid() bool { return id != TraceID{} }
+
+// IsValid сообщает, что ID не нулевой.
+func (id SpanID) IsValid() bool { return id != SpanID{} }
+
+// FlagSampled — флаг traceparent: трасса записывается.
+const FlagSampled byte = 0x01
+
+// SpanContext — часть span, передаваемая между сервисами.
+type SpanContext struct {
+	TraceID    TraceID
+	SpanID     SpanID
+	Flags      byte
+	TraceState string // Значение tracestate передаётся без изменений
+}
+
+// Sampled сообщает, записывается ли трасса.
+func (sc SpanContext) Sampled() bool {
+	return sc.Flags&FlagSampled != 0
+}
+
+// SpanKind — роль span, значения совпадают с OTLP.
+type SpanKind int
+
+const (
+	KindInternal SpanKind = 1 // Операция внутри балансировщика
+	KindServer   SpanKind = 2 // Обработка входящего запроса
+	KindClient   SpanKind = 3 // Запрос к бэкенду
+)
+
+// Attr — атрибут span. Значение: string, bool, int, int64 или float64.
+type Attr struct {
+	Key   string
+	Value any
+}
+
+// SpanData — завершённый span, передаваемый экспортёру.
+type SpanData struct {
+	Name       string
+	Kind       SpanKind
+	Context    SpanContext
+	Parent     SpanID // Нулевой у корневого span
+	Start, End time.Time
+	Attrs      []Attr
+	Err        string // Непустой, если операция завершилась ошибкой
+}
+
+// Span — выполняемая операция. Методы безопасны для nil: когда трассировка
+// выключена, Start возвращает nil, и инструментированный код работает без изменений.
+type Span struct {
+	tracer *Tracer
+	mu     sync.Mutex
+	data   SpanData
+	ended  bool
+}
+
+// Context возвращает SpanContext; для nil — нулевой.
+func (s *Span) Context() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.data.Context
+}
+
+// SetAttr добавляет атрибут.
+func (s *Span) SetAttr(key string, value any) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Attrs = append(s.data.Attrs, Attr{Key: key, Value: value})
+}
+
+// RecordError отмечает span как завершившийся ошибкой.
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Err = err.Error()
+}
+
+// End завершает span и передаёт его на экспорт, если трасса записывается.
+// Повторные вызовы ничего не делают.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	data := s.data
+	s.mu.Unlock()
+	if data.Context.Sampled() {
+		s.tracer.enqueue(data)
+	}
+}
+
+type (
+	ctxKey   struct{}
+	attrsKey struct{}
+)
+
+// ContextWithSpan возвращает контекст с текущим span.
+func ContextWithSpan(ctx context.Context, s *Span) context.Context {
+	return context.WithValue(ctx, ctxKey{}, s)
+}
+
+// SpanFromContext возвращает текущий span или nil.
+func SpanFromContext(ctx context.Context) *Span {
+	s, _ := ctx.Value(ctxKey{}).(*Span)
+	return s
+}
+
+// ContextWithAttrs возвращает контекст, span из которого получат атрибуты attrs
+// при создании (например, идентификатор запроса).
+func ContextWithAttrs(ctx context.Context, attrs ...Attr) context.Context {
+	prev := attrsFromContext(ctx)
+	return context.WithValue(ctx, attrsKey{}, append(prev[:len(prev):len(prev)], attrs...))
+}
+
+// attrsFromContext возвращает атрибуты, заданные через ContextWithAttrs.
+func attrsFromContext(ctx context.Context) []Attr {
+	attrs, _ := ctx.Value(attrsKey{}).([]Attr)
+	return attrs
+}
+
+// Start начинает дочерний span текущего span из ctx. Если в ctx нет span
+// (трассировка выключена), возвращает ctx без изменений и nil.
+func Start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	parent := SpanFromContext(ctx)
+	if parent == nil {
+		return ctx, nil
+	}
+	pc := parent.Context()
+	s := parent.tracer.newSpan(ctx, name, kind, SpanContext{
+		TraceID:    pc.TraceID,
+		SpanID:     newSpanID(),
+		Flags:      pc.Flags,
+		TraceState: pc.TraceState,
+	}, pc.SpanID)
+	return ContextWithSpan(ctx, s), s
+}
+
+// Tracer создаёт корневые span входящих запросов и передаёт завершённые span экспортёру.
+type Tracer struct {
+	batch *batcher
+}
+
+// NewTracer создаёт Tracer, экспортирующий span пачками через exp.
+func NewTracer(exp Exporter) *Tracer {
+	return &Tracer{batch: newBatcher(exp)}
+}
+
+// StartRemote начинает span, продолжающий трассу remote, либо новую трассу,
+// если remote нулевой.
+func (t *Tracer) StartRemote(ctx context.Context, name string, kind SpanKind, remote SpanContext) (context.Context, *Span) {
+	sc := SpanContext{SpanID: newSpanID(), Flags: FlagSampled}
+	var parent SpanID
+	if remote.TraceID.IsValid() {
+		sc.TraceID, sc.Flags, sc.TraceState = remote.TraceID, remote.Flags, remote.TraceState
+		parent = remote.SpanID
+	} else {
+		sc.TraceID = newTraceID()
+	}
+	s := t.newSpan(ctx, name, kind, sc, parent)
+	return ContextWithSpan(ctx, s), s
+}
+
+// Shutdown экспортирует накопленные span и останавливает экспорт.
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	return t.batch.shutdown(ctx)
+}
+
+// newSpan создаёт начатый span с атрибутами из ctx.
+func (t *Tracer) newSpan(ctx context.Context, name string, kind SpanKind, sc SpanContext, parent SpanID) *Span {
+	return &Span{tracer: t, data: SpanData{
+		Name:    name,
+		Kind:    kind,
+		Context: sc,
+		Parent:  parent,
+		Start:   time.Now(),
+		Attrs:   append([]Attr(nil), attrsFromContext(ctx)...),
+	}}
+}
+
+// enqueue ставит завершённый span в очередь экспорта.
+func (t *Tracer) enqueue(d SpanData) {
+	t.batch.add(d)
+}
+
+// newTraceID генерирует случайный ненулевой TraceID.
+func newTraceID() TraceID {
+	var id TraceID
+	for !id.IsValid() {
+		mustRead(id[:])
+	}
+	return id
+}
+
+// newSpanID генерирует случайный ненулевой SpanID.
+func newSpanID() SpanID {
+	var id SpanID
+	for !id.IsValid() {
+		mustRead(id[:])
+	}
+	return id
+}
+
+// mustRead заполняет b случайными байтами; crypto/rand не возвращает ошибок на поддерживаемых платформах.
+func mustRead(b []byte) {
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("tracing: random source failed: %v", err))
+	}
+}
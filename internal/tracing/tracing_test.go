@@ -0,0 +1,138 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseTraceparent проверяет разбор и формирование заголовка traceparent.
+func TestParseTraceparent(t *testing.T) {
+	const h = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	sc, ok := ParseTraceparent(h)
+	require.True(t, ok)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID.String())
+	assert.Equal(t, "00f067aa0ba902b7", sc.SpanID.String())
+	assert.True(t, sc.Sampled())
+	assert.Equal(t, h, sc.Traceparent())
+
+	for _, bad := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+	} {
+		_, ok := ParseTraceparent(bad)
+		assert.False(t, ok, bad)
+	}
+	_, ok = ParseTraceparent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra")
+	assert.True(t, ok, "future versions may append fields")
+}
+
+// TestMiddleware проверяет продолжение трассы клиента, начало новой и передачу контекста дальше.
+func TestMiddleware(t *testing.T) {
+	exp := &InMemoryExporter{}
+	tracer := NewTracer(exp)
+	var outgoing http.Header
+	handler := tracer.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, child := Start(r.Context(), "child", KindInternal)
+		child.RecordError(errors.New("boom"))
+		child.End()
+		outgoing = http.Header{}
+		Inject(r.Context(), outgoing)
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/a", nil)
+	req.Header.Set(HeaderTraceparent, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	req.Header.Set(HeaderTracestate, "vendor=1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/b", nil))
+	require.NoError(t, tracer.Shutdown(context.Background()))
+
+	spans := exp.Spans()
+	require.Len(t, spans, 4)
+	child, server := spans[0], spans[1]
+	assert.Equal(t, "child", child.Name)
+	assert.Equal(t, "boom", child.Err)
+	assert.Equal(t, server.Context.SpanID, child.Parent)
+	assert.Equal(t, KindServer, server.Kind)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.Context.TraceID.String())
+	assert.Equal(t, "00f067aa0ba902b7", server.Parent.String())
+	assert.Contains(t, server.Attrs, Attr{Key: "http.response.status_code", Value: http.StatusTeapot})
+
+	fresh := spans[3]
+	assert.True(t, fresh.Context.TraceID.IsValid())
+	assert.NotEqual(t, server.Context.TraceID, fresh.Context.TraceID)
+	assert.False(t, fresh.Parent.IsValid(), "new trace has no parent")
+	sc, ok := Extract(outgoing)
+	require.True(t, ok)
+	assert.Equal(t, fresh.Context.TraceID, sc.TraceID)
+	assert.Empty(t, outgoing.Get(HeaderTracestate))
+}
+
+// TestUnsampled проверяет, что трасса без флага sampled продолжается, но не экспортируется.
+func TestUnsampled(t *testing.T) {
+	exp := &InMemoryExporter{}
+	tracer := NewTracer(exp)
+	ctx, span := tracer.StartRemote(context.Background(), "op", KindServer, SpanContext{TraceID: newTraceID(), SpanID: newSpanID()})
+	h := http.Header{}
+	Inject(ctx, h)
+	span.End()
+	require.NoError(t, tracer.Shutdown(context.Background()))
+	assert.Empty(t, exp.Spans())
+	assert.Regexp(t, "-00$", h.Get(HeaderTraceparent))
+
+	// Без span в контексте инструментированный код ничего не делает.
+	_, none := Start(context.Background(), "noop", KindInternal)
+	none.SetAttr("k", "v")
+	none.End()
+	assert.Nil(t, none)
+}
+
+// TestOTLPExporter проверяет JSON-кодировку запроса экспорта.
+func TestOTLPExporter(t *testing.T) {
+	var body map[string]any
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/traces", r.URL.Path)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.Equal(t, "secret", r.Header.Get("Authorization"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+	}))
+	defer collector.Close()
+
+	exp, err := NewOTLPExporter(OTLPConfig{Endpoint: collector.URL, ServiceName: "lb", Headers: map[string]string{"Authorization": "secret"}})
+	require.NoError(t, err)
+	tracer := NewTracer(exp)
+	_, root := tracer.StartRemote(context.Background(), "GET", KindServer, SpanContext{})
+	root.SetAttr("http.response.status_code", 502)
+	root.SetAttr("ok", false)
+	root.RecordError(errors.New("bad gateway"))
+	root.End()
+	require.NoError(t, tracer.Shutdown(context.Background()))
+	assert.Zero(t, tracer.ExportErrors())
+
+	rs := body["resourceSpans"].([]any)[0].(map[string]any)
+	resAttr := rs["resource"].(map[string]any)["attributes"].([]any)[0].(map[string]any)
+	assert.Equal(t, "lb", resAttr["value"].(map[string]any)["stringValue"])
+	span := rs["scopeSpans"].([]any)[0].(map[string]any)["spans"].([]any)[0].(map[string]any)
+	assert.Equal(t, root.Context().TraceID.String(), span["traceId"])
+	assert.Equal(t, float64(KindServer), span["kind"])
+	assert.NotContains(t, span, "parentSpanId")
+	assert.Equal(t, map[string]any{"code": float64(2), "message": "bad gateway"}, span["status"])
+	attrs := span["attributes"].([]any)
+	assert.Equal(t, "502", attrs[0].(map[string]any)["value"].(map[string]any)["intValue"])
+	assert.Equal(t, false, attrs[1].(map[string]any)["value"].(map[string]any)["boolValue"])
+
+	_, err = NewOTLPExporter(OTLPConfig{})
+	assert.Error(t, err)
+}